@@ -8,11 +8,50 @@ import (
 	`encoding/binary`
 	`os`
 	`sort`
+	`sync`
 )
 
+// Book move selection policies.
+const (
+	BookTopTwo = iota // Random pick between two best moves.
+	BookBest          // Always the best move.
+	BookWeighted      // Random pick weighted by move scores.
+)
+
+// Book policy names as they appear in NewEngine() arguments and UCI options.
+var bookPolicies = map[string]int{ `toptwo`: BookTopTwo, `best`: BookBest, `weighted`: BookWeighted }
+
+// Learning from unfinished games: the game is considered won (or lost) if the
+// search score stays above (or below) the winning score for the given number
+// of moves in a row, and drawn if the score stays at zero.
+const (
+	learnWinCount  = 4
+	learnWinScore  = onePawn * 3
+	learnDrawCount = 8
+)
+
+// Serializes access to book files since the engines might share the same book
+// and update it when learning.
+var bookLock sync.Mutex
+
+// Opening book settings kept by the engine.
+type bookOptions struct {
+	policy      int 	// Move selection policy.
+	minWeight   int 	// Minimum move weight in percents of the best move weight.
+	depth       int 	// Last move number to play from the book, 0 for no limit.
+	misses      int 	// Stop probing after this many consecutive misses, 0 to keep probing.
+	learn       bool 	// Update learn values of the played moves with game results.
+}
+
 type Book struct {
-	fileName string
-	entries  int64
+	bookOptions
+	fileName    string 	// Polyglot book file name.
+	entries     int64 	// Number of entries in the book.
+	missed      int 	// Number of consecutive misses.
+	fullmove    int 	// Move number of the last probed position.
+	played      []Entry 	// Moves played from the book, Learn holds the color.
+	scores      []int 	// Search scores of the moves played past the book, from White's point of view.
+	foreseen    string 	// Game result foreseen by the search, if any.
 }
 
 // Opening book record: the fields are exported for binary.Read().
@@ -35,26 +74,139 @@ func NewBook(bookFile string) (*Book, error) {
 	return book, nil
 }
 
+// Returns the book of the game. The book gets reopened if the engine's book
+// settings have been changed.
+func (game *Game) openBook() *Book {
+	engine := game.engine
+	if game.book == nil || game.book.fileName != engine.bookFile || game.book.bookOptions != engine.book {
+		game.book = nil
+		if book, err := NewBook(engine.bookFile); err == nil {
+			book.bookOptions = engine.book
+			game.book = book
+		}
+	}
+	return game.book
+}
+
+// Updates learn values of the moves played from the book with the game result.
+// If the game is unfinished, ex. UCI GUI has started new game without sending
+// the final position, the result foreseen by the search is used instead.
+func (game *Game) learn(result string) {
+	if game.book != nil && game.book.learn {
+		game.book.update(game.book.outcome(result))
+	}
+}
+
+// Keeps track of the search results after the book moves have run out so that
+// we could learn from the game even if it has not been played out.
+func (b *Book) track(p *Position, status, score int) {
+	switch status {
+	case WhiteWon, WhiteWinning:
+		b.foreseen = `1-0`
+	case BlackWon, BlackWinning:
+		b.foreseen = `0-1`
+	case Stalemate, Insufficient, Repetition, FiftyMoves:
+		b.foreseen = `1/2-1/2`
+	default:
+		if p.color == Black {
+			score = -score
+		}
+		b.scores = append(b.scores, score)
+	}
+}
+
+// Returns the result of the game: the actual one if the game is over, the one
+// foreseen by the search, or the one suggested by the trend of search scores.
+func (b *Book) outcome(result string) string {
+	if result != `*` {
+		return result
+	} else if b.foreseen != `` {
+		return b.foreseen
+	}
+
+	trend := func(count int, within func(int) bool) bool {
+		if len(b.scores) < count {
+			return false
+		}
+		for _, score := range b.scores[len(b.scores) - count:] {
+			if !within(score) {
+				return false
+			}
+		}
+		return true
+	}
+
+	if trend(learnWinCount, func(score int) bool { return score >= learnWinScore }) {
+		return `1-0`
+	} else if trend(learnWinCount, func(score int) bool { return score <= -learnWinScore }) {
+		return `0-1`
+	} else if trend(learnDrawCount, func(score int) bool { return score == 0 }) {
+		return `1/2-1/2`
+	}
+	return `*`
+}
+
 func (b *Book) pickMove(position *Position) Move {
-	entries := b.lookup(position)
-	switch length := len(entries); length {
-	case 0:
-		// TODO: set the "useless book" flag after a few misses.
+	// Start over if the move number went back, i.e. it's a new game.
+	if int(position.fullmove) < b.fullmove {
+		b.missed, b.played, b.scores, b.foreseen = 0, nil, nil, ``
+	}
+	b.fullmove = int(position.fullmove)
+
+	// Stop probing past the book depth or once the book has become useless.
+	if (b.depth > 0 && b.fullmove > b.depth) || (b.misses > 0 && b.missed >= b.misses) {
 		return 0
-	case 1:
-		// The only move available.
-		return b.move(position, entries[0])
-	default:
-		// Sort book entries by score and pick among two best moves.
-		sort.Sort(byBookScore{entries})
-		best := min(2, len(entries))
-		return b.move(position, entries[Random(best)])
 	}
+
+	entries := b.candidates(position)
+	if len(entries) == 0 {
+		b.missed++
+		return 0
+	}
+	b.missed = 0
+
+	entry := entries[0]
+	switch b.policy {
+	case BookTopTwo:
+		entry = entries[Random(min(2, len(entries)))]
+	case BookWeighted:
+		total := 0
+		for _, entry := range entries {
+			total += entry.weight()
+		}
+		for pick := Random(total); pick >= entries[0].weight(); entries = entries[1:] {
+			pick -= entries[0].weight()
+		}
+		entry = entries[0]
+	}
+
+	if b.learn {
+		b.played = append(b.played, Entry{Key: entry.Key, Move: entry.Move, Learn: uint32(position.color)})
+	}
+	return b.move(position, entry)
+}
+
+// Returns book entries for the position sorted by weight, best moves first.
+// Moves with zero weight or weighing less than minimum percentage of the best
+// move's weight are left out.
+func (b *Book) candidates(position *Position) []Entry {
+	entries := b.lookup(position)
+	sort.Sort(byBookWeight{entries})
+
+	for i, entry := range entries {
+		if weight := entry.weight(); weight == 0 || weight * 100 < entries[0].weight() * b.minWeight {
+			return entries[:i]
+		}
+	}
+	return entries
 }
 
 func (b *Book) lookup(position *Position) (entries []Entry) {
 	var entry Entry
 
+	bookLock.Lock()
+	defer bookLock.Unlock()
+
 	file, err := os.Open(b.fileName)
 	if err != nil {
 		return
@@ -63,9 +215,25 @@ func (b *Book) lookup(position *Position) (entries []Entry) {
 
 	key, _ := position.polyglot()
 
-	// Since book entries are ordered by polyglot key we can use binary
-	// search to find *first* book entry that matches the position.
-	first, current, last := int64(-1), int64(0), b.entries
+	// Read all book entries for the given position. Stop at the end of
+	// file since the last entry read stays in place.
+	file.Seek(b.find(file, key)*16, 0)
+	for {
+		if err := binary.Read(file, binary.BigEndian, &entry); err != nil || key != entry.Key {
+			break
+		} else {
+			entries = append(entries, entry)
+		}
+	}
+	return
+}
+
+// Since book entries are ordered by polyglot key we can use binary search to
+// find *first* book entry that matches the key. Returns entry index.
+func (b *Book) find(file *os.File, key uint64) int64 {
+	var entry Entry
+
+	first, current, last := int64(0), int64(0), b.entries
 	for first < last {
 		current = (first + last) / 2
 		file.Seek(current*16, 0)
@@ -76,18 +244,56 @@ func (b *Book) lookup(position *Position) (entries []Entry) {
 			first = current + 1
 		}
 	}
+	return first
+}
 
-	// Read all book entries for the given position.
-	file.Seek(first*16, 0)
-	for {
-		binary.Read(file, binary.BigEndian, &entry)
-		if key != entry.Key {
-			break
-		} else {
-			entries = append(entries, entry)
+// Writes game result to the learn values of the moves played from the book.
+// Learn value keeps the number of games in upper 16 bits, and the number of
+// half points scored (2 per win and 1 per draw) in lower 16 bits.
+func (b *Book) update(result string) error {
+	played := b.played
+	if b.played, b.scores, b.foreseen = nil, nil, ``; result == `*` || len(played) == 0 {
+		return nil
+	}
+
+	bookLock.Lock()
+	defer bookLock.Unlock()
+
+	file, err := os.OpenFile(b.fileName, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, move := range played {
+		points := uint32(1)
+		if result != `1/2-1/2` {
+			points = 0
+			if result == []string{ `1-0`, `0-1` }[move.Learn] {
+				points = 2
+			}
+		}
+
+		var entry Entry
+		for index := b.find(file, move.Key); index < b.entries; index++ {
+			file.Seek(index*16, 0)
+			if err := binary.Read(file, binary.BigEndian, &entry); err != nil || entry.Key != move.Key {
+				break
+			}
+			if entry.Move == move.Move {
+				if games := entry.Learn >> 16; games < 0x7FFF {
+					entry.Learn = (games + 1) << 16 | (entry.Learn & 0xFFFF + points)
+					file.Seek(index*16, 0)
+					if err := binary.Write(file, binary.BigEndian, entry); err != nil {
+						return err
+					}
+				}
+				break
+			}
 		}
 	}
-	return
+
+	return nil
 }
 
 func (b *Book) move(p *Position, entry Entry) Move {
@@ -113,7 +319,7 @@ func (b *Book) move(p *Position, entry Entry) Move {
 
 	move := NewMove(p, from, to)
 	if promo := entry.promoted(); promo != 0 {
-		move.promote(promo)
+		move = move.promote(promo)
 	}
 	return move
 }
@@ -141,10 +347,20 @@ func (e *Entry) promoted() int {
 	return piece*2 + 2
 }
 
-type byBookScore struct {
+// Returns move weight: the score adjusted by learned results. The score gets
+// multiplied by (half points + 1) / (games + 1) which is one for drawing moves
+// and the moves that have not been learned yet, and ranges from almost zero for
+// losing moves to almost two for winning ones. The weight is doubled to reduce
+// rounding errors.
+func (e *Entry) weight() int {
+	games, points := int(e.Learn >> 16), int(e.Learn & 0xFFFF)
+	return int(e.Score) * (points + 1) * 2 / (games + 1)
+}
+
+type byBookWeight struct {
 	list []Entry
 }
 
-func (her byBookScore) Len() int           { return len(her.list) }
-func (her byBookScore) Swap(i, j int)      { her.list[i], her.list[j] = her.list[j], her.list[i] }
-func (her byBookScore) Less(i, j int) bool { return her.list[i].Score > her.list[j].Score }
+func (her byBookWeight) Len() int           { return len(her.list) }
+func (her byBookWeight) Swap(i, j int)      { her.list[i], her.list[j] = her.list[j], her.list[i] }
+func (her byBookWeight) Less(i, j int) bool { return her.list[i].weight() > her.list[j].weight() }
@@ -0,0 +1,199 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`encoding/binary`
+	`io`
+	`sort`
+	`strconv`
+	`strings`
+)
+
+// Builds Polyglot opening book from PGN games. Moves from the first plies of
+// each game are aggregated by Polyglot key of the position they were played
+// in, along with the number of wins, draws, and losses they have led to.
+type BookBuilder struct {
+	plies       int 		// Number of plies to keep from each game.
+	minElo      int 		// Minimum rating of the player whose moves are included.
+	minCount    int 		// Minimum number of games the move must have been played in.
+	results     map[string]bool 	// Game results to include.
+	games       int 		// Number of games added to the book.
+	moves       map[uint64]map[uint16]*bookStats // Move statistics by position key and Polyglot move.
+}
+
+// Move statistics from the point of view of the side that made the move.
+type bookStats struct {
+	wins        int
+	draws       int
+	losses      int
+}
+
+// Creates book builder with the given settings, ex. "plies", 16, "minelo",
+// 2400, "results", "1-0,0-1". By default the book keeps first 24 plies of
+// all finished games.
+func NewBookBuilder(args ...interface{}) *BookBuilder {
+	bb := &BookBuilder{plies: 24, minCount: 1, moves: make(map[uint64]map[uint16]*bookStats)}
+	bb.results = map[string]bool{ `1-0`: true, `0-1`: true, `1/2-1/2`: true }
+	for i := 0; i < len(args); i += 2 {
+		switch value := args[i+1]; args[i] {
+		case `plies`:
+			bb.plies = value.(int)
+		case `minelo`:
+			bb.minElo = value.(int)
+		case `mincount`:
+			bb.minCount = max(1, value.(int))
+		case `results`:
+			bb.results = map[string]bool{}
+			for _, result := range strings.Split(value.(string), `,`) {
+				bb.results[strings.TrimSpace(result)] = true
+			}
+		}
+	}
+
+	return bb
+}
+
+// Adds the moves of the game to the book. Unfinished games, Chess960 games,
+// and games with results that have not been requested are skipped. Returns
+// true if the game has been added.
+func (bb *BookBuilder) Add(pgn *Pgn) bool {
+	if pgn.Result == `*` || !bb.results[pgn.Result] || pgn.chess960() {
+		return false
+	}
+
+	game, ply := NewGame(), 0
+	pgn.Walk(game, func(p *Position, move Move) bool {
+		if ply++; ply > bb.plies {
+			return false
+		}
+		if bb.minElo > 0 {
+			elo, _ := strconv.Atoi(pgn.Tag([]string{ `WhiteElo`, `BlackElo` }[p.color]))
+			if elo < bb.minElo {
+				return true
+			}
+		}
+
+		key, _ := p.polyglot()
+		if bb.moves[key] == nil {
+			bb.moves[key] = make(map[uint16]*bookStats)
+		}
+		entry := polyglotMove(move)
+		stats := bb.moves[key][entry]
+		if stats == nil {
+			stats = &bookStats{}
+			bb.moves[key][entry] = stats
+		}
+		switch pgn.Result {
+		case `1/2-1/2`:
+			stats.draws++
+		case []string{ `1-0`, `0-1` }[p.color]:
+			stats.wins++
+		default:
+			stats.losses++
+		}
+		return true
+	})
+	bb.games++
+
+	return true
+}
+
+// Reads PGN games and adds them to the book. Returns the number of games
+// added.
+func (bb *BookBuilder) Read(r io.Reader) (games int, err error) {
+	reader := NewPgnReader(r)
+	for {
+		pgn, err := reader.Read()
+		if err == io.EOF {
+			return games, nil
+		} else if err != nil {
+			return games, err
+		}
+		if bb.Add(pgn) {
+			games++
+		}
+	}
+}
+
+// Returns the number of games added to the book.
+func (bb *BookBuilder) Games() int {
+	return bb.games
+}
+
+// Returns book entries sorted by position key and then by score, best moves
+// first. Move score is 2 points per win and 1 point per draw; moves that have
+// never won or drawn are left out. Scores are scaled down proportionally if
+// they exceed 16-bit limit.
+func (bb *BookBuilder) entries() (entries []Entry) {
+	highest := 0
+	for key, moves := range bb.moves {
+		for move, stats := range moves {
+			score := 2 * stats.wins + stats.draws
+			if score > 0 && stats.wins + stats.draws + stats.losses >= bb.minCount {
+				entries = append(entries, Entry{Key: key, Move: move})
+				highest = max(highest, score)
+			}
+		}
+	}
+
+	for i := range entries {
+		stats := bb.moves[entries[i].Key][entries[i].Move]
+		score := 2 * stats.wins + stats.draws
+		if highest > 0xFFFF {
+			score = max(1, score * 0xFFFF / highest)
+		}
+		entries[i].Score = uint16(score)
+	}
+
+	sort.Sort(byBookKey{entries})
+	return
+}
+
+// Writes the book in Polyglot format: 16-byte big-endian records sorted by
+// position key. Returns the number of entries written.
+func (bb *BookBuilder) Save(w io.Writer) (int, error) {
+	entries := bb.entries()
+	if err := binary.Write(w, binary.BigEndian, entries); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+// Encodes the move the way Polyglot does: bits 0-5 hold the target square, bits
+// 6-11 hold the source square, and bits 12-14 hold promotion piece. Castles are
+// represented as king captures own rook, ex. E1-H1.
+func polyglotMove(move Move) uint16 {
+	from, to := move.from(), move.to()
+	if move.isCastle() {
+		if to > from {
+			to = square(row(to), 7)
+		} else {
+			to = square(row(to), 0)
+		}
+	}
+
+	entry := uint16(row(from) << 9 | col(from) << 6 | row(to) << 3 | col(to))
+	if promo := move.promo(); promo != 0 {
+		entry |= uint16((promo.kind() - 2) / 2) << 12
+	}
+	return entry
+}
+
+type byBookKey struct {
+	list []Entry
+}
+
+func (her byBookKey) Len() int      { return len(her.list) }
+func (her byBookKey) Swap(i, j int) { her.list[i], her.list[j] = her.list[j], her.list[i] }
+func (her byBookKey) Less(i, j int) bool {
+	if her.list[i].Key != her.list[j].Key {
+		return her.list[i].Key < her.list[j].Key
+	}
+	if her.list[i].Score != her.list[j].Score {
+		return her.list[i].Score > her.list[j].Score
+	}
+	return her.list[i].Move < her.list[j].Move
+}
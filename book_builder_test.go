@@ -0,0 +1,172 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `io/ioutil`; `os`; `strings`; `testing`)
+
+const pgnOpenings = `[White "First"]
+[Black "Second"]
+[WhiteElo "2500"]
+[BlackElo "2300"]
+[Result "1-0"]
+
+1.e4 e5 2.Nf3 Nc6 3.Bb5 a6 1-0
+
+[White "Second"]
+[Black "First"]
+[WhiteElo "2300"]
+[BlackElo "2500"]
+[Result "0-1"]
+
+1.e4 c5 2.Nf3 d6 0-1
+
+[White "First"]
+[Black "Second"]
+[Result "1/2-1/2"]
+
+1.d4 d5 2.c4 e6 1/2-1/2
+
+[White "Second"]
+[Black "First"]
+[Result "*"]
+
+1.e4 e5 *
+`
+
+func bookBuilder(args ...interface{}) *BookBuilder {
+	bb := NewBookBuilder(args...)
+	bb.Read(strings.NewReader(pgnOpenings))
+	return bb
+}
+
+func bookStatsOf(bb *BookBuilder, p *Position, from, to int) bookStats {
+	key, _ := p.polyglot()
+	if stats := bb.moves[key][polyglotMove(NewMove(p, from, to))]; stats != nil {
+		return *stats
+	}
+	return bookStats{}
+}
+
+// Polyglot move encoding is reversed by Book.move() for all moves including
+// castles, promotions, and en-passant captures.
+func TestBookBuilder000(t *testing.T) {
+	book := &Book{}
+	for _, fen := range []string{
+		`r3k2r/1P6/8/8/8/8/8/R3K2R w KQkq - 0 1`,
+		`r3k2r/8/8/8/8/8/1p6/R3K2R b KQkq - 0 1`,
+		`4k3/8/8/3pP3/8/8/8/4K3 w - d6 0 1`,
+	} {
+		p := NewGame(fen).start()
+		for _, move := range NewGen(p, 0).generateAllMoves().validOnly().allMoves() {
+			expect.Eq(t, book.move(p, Entry{Move: polyglotMove(move)}), move)
+		}
+	}
+	p := NewGame().start()
+	expect.Eq(t, polyglotMove(NewMove(p, E2, E4)), polyglotEntry(E2, E4).Move)
+}
+
+func TestBookBuilder010(t *testing.T) {
+	bb := bookBuilder()
+	expect.Eq(t, bb.Games(), 3)
+
+	p := NewGame().start()
+	expect.Eq(t, bookStatsOf(bb, p, E2, E4), bookStats{wins: 1, losses: 1})
+	expect.Eq(t, bookStatsOf(bb, p, D2, D4), bookStats{draws: 1})
+
+	p = p.makeMove(NewMove(p, E2, E4))
+	expect.Eq(t, bookStatsOf(bb, p, E7, E5), bookStats{losses: 1})
+	expect.Eq(t, bookStatsOf(bb, p, C7, C5), bookStats{wins: 1})
+}
+
+// Filtering by result, rating, and number of plies.
+func TestBookBuilder020(t *testing.T) {
+	bb := bookBuilder(`results`, `1-0, 0-1`)
+	expect.Eq(t, bb.Games(), 2)
+	expect.Eq(t, bookStatsOf(bb, NewGame().start(), D2, D4), bookStats{})
+
+	bb = bookBuilder(`minelo`, 2400)
+	p := NewGame().start()
+	expect.Eq(t, bookStatsOf(bb, p, E2, E4), bookStats{wins: 1})
+	p = p.makeMove(NewMove(p, E2, E4))
+	expect.Eq(t, bookStatsOf(bb, p, E7, E5), bookStats{})
+	expect.Eq(t, bookStatsOf(bb, p, C7, C5), bookStats{wins: 1})
+
+	bb = bookBuilder(`plies`, 1)
+	expect.Eq(t, len(bb.moves), 1)
+	expect.Eq(t, len(bb.entries()), 2)
+}
+
+// Entries are sorted by key and score; moves without wins or draws and moves
+// played less than minimum number of times are left out.
+func TestBookBuilder030(t *testing.T) {
+	entries := bookBuilder().entries()
+	for i := 1; i < len(entries); i++ {
+		expect.True(t, entries[i-1].Key < entries[i].Key ||
+			(entries[i-1].Key == entries[i].Key && entries[i-1].Score >= entries[i].Score))
+	}
+	expect.Eq(t, len(entries), 9)
+
+	entries = bookBuilder(`mincount`, 2).entries()
+	expect.Eq(t, len(entries), 1) // 1.e4 only.
+}
+
+func TestBookBuilder040(t *testing.T) {
+	bb := NewBookBuilder()
+	bb.moves[1] = map[uint16]*bookStats{ 1: &bookStats{wins: 40000}, 2: &bookStats{draws: 1} }
+	bb.moves[2] = map[uint16]*bookStats{ 3: &bookStats{wins: 10000}, 4: &bookStats{losses: 1} }
+	entries := bb.entries()
+	expect.Eq(t, len(entries), 3)
+	expect.Eq(t, entries[0], Entry{Key: 1, Move: 1, Score: 0xFFFF})
+	expect.Eq(t, entries[1], Entry{Key: 1, Move: 2, Score: 1})
+	expect.Eq(t, entries[2], Entry{Key: 2, Move: 3, Score: 0x3FFF})
+}
+
+// Saved book is read back by Book.
+func TestBookBuilder050(t *testing.T) {
+	file, _ := ioutil.TempFile(``, `donna`)
+	defer os.Remove(file.Name())
+
+	bb := bookBuilder()
+	entries, err := bb.Save(file)
+	file.Close()
+	expect.Eq(t, err, nil)
+	expect.Eq(t, entries, 9)
+
+	book, err := NewBook(file.Name())
+	expect.Eq(t, err, nil)
+	expect.Eq(t, book.entries, int64(9))
+
+	p := NewGame().start()
+	found := book.lookup(p)
+	expect.Eq(t, len(found), 2)
+	expect.Eq(t, book.move(p, found[0]), NewMove(p, E2, E4))
+	expect.Eq(t, found[0].Score, uint16(2))
+	expect.Eq(t, book.move(p, found[1]), NewMove(p, D2, D4))
+	expect.Eq(t, found[1].Score, uint16(1))
+
+	p = p.makeMove(NewMove(p, E2, E4))
+	found = book.lookup(p)
+	expect.Eq(t, len(found), 1)
+	expect.Eq(t, book.move(p, found[0]), NewMove(p, C7, C5))
+	expect.Eq(t, book.pickMove(p), NewMove(p, C7, C5))
+
+	p = p.makeMove(NewMove(p, A7, A6))
+	expect.Eq(t, len(book.lookup(p)), 0)
+
+	// All the moves that have won or drawn are found in the book.
+	games, _ := ReadPgn(strings.NewReader(pgnOpenings))
+	for _, pgn := range games {
+		pgn.Walk(NewGame(), func(p *Position, move Move) bool {
+			if stats := bookStatsOf(bb, p, move.from(), move.to()); stats.wins + stats.draws > 0 {
+				found := false
+				for _, entry := range book.lookup(p) {
+					found = found || book.move(p, entry) == move
+				}
+				expect.True(t, found)
+			}
+			return true
+		})
+	}
+}
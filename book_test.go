@@ -4,7 +4,7 @@
 
 package donna
 
-import(`github.com/michaeldv/donna/expect`; `testing`)
+import(`github.com/michaeldv/donna/expect`; `encoding/binary`; `io/ioutil`; `os`; `sort`; `testing`)
 
 func openBook() (*Book, *Position) {
 	return &Book{}, NewGame().start()
@@ -160,3 +160,214 @@ func TestBook110(t *testing.T) { // 1. d4 d5
 	expect.Eq(t, p.enpassant, uint8(0))
 	expect.Eq(t, p.castles, uint8(0x0F))
 }
+
+// Creates temporary book file with the given entries.
+func bookFile(entries ...Entry) string {
+	sort.Sort(byBookKey{entries})
+	file, _ := ioutil.TempFile(``, `donna`)
+	binary.Write(file, binary.BigEndian, entries)
+	file.Close()
+	return file.Name()
+}
+
+func bookEntry(p *Position, from, to int, score uint16, learn uint32) Entry {
+	key, _ := p.polyglot()
+	return Entry{Key: key, Move: polyglotMove(NewMove(p, from, to)), Score: score, Learn: learn}
+}
+
+func startingBook() (*Book, *Position) {
+	p := NewGame().start()
+	fileName := bookFile(
+		bookEntry(p, E2, E4, 100, 0),
+		bookEntry(p, D2, D4, 80, 0),
+		bookEntry(p, C2, C4, 10, 0),
+		bookEntry(p, G1, F3, 0, 0),
+	)
+	book, _ := NewBook(fileName)
+	return book, p
+}
+
+// Candidate moves are sorted by weight; zero weight and light moves are left out.
+func TestBook200(t *testing.T) {
+	book, p := startingBook()
+	defer os.Remove(book.fileName)
+
+	entries := book.candidates(p)
+	expect.Eq(t, len(entries), 3)
+	expect.Eq(t, book.move(p, entries[0]), NewMove(p, E2, E4))
+	expect.Eq(t, book.move(p, entries[1]), NewMove(p, D2, D4))
+	expect.Eq(t, book.move(p, entries[2]), NewMove(p, C2, C4))
+
+	book.minWeight = 20
+	expect.Eq(t, len(book.candidates(p)), 2)
+}
+
+// Learned results adjust move weights.
+func TestBook210(t *testing.T) {
+	expect.Eq(t, (&Entry{Score: 80}).weight(), 160)
+	expect.Eq(t, (&Entry{Score: 80, Learn: 1 << 16 | 2}).weight(), 240)
+	expect.Eq(t, (&Entry{Score: 80, Learn: 2 << 16 | 2}).weight(), 160)
+	expect.Eq(t, (&Entry{Score: 80, Learn: 3 << 16}).weight(), 40)
+
+	p := NewGame().start()
+	fileName := bookFile(bookEntry(p, E2, E4, 100, 3 << 16), bookEntry(p, D2, D4, 80, 1 << 16 | 2))
+	defer os.Remove(fileName)
+	book, _ := NewBook(fileName)
+	book.policy = BookBest
+	expect.Eq(t, book.pickMove(p), NewMove(p, D2, D4))
+}
+
+// Move selection policies.
+func TestBook220(t *testing.T) {
+	book, p := startingBook()
+	defer os.Remove(book.fileName)
+
+	book.policy = BookBest
+	expect.Eq(t, book.pickMove(p), NewMove(p, E2, E4))
+
+	book.policy = BookTopTwo
+	move := book.pickMove(p)
+	expect.True(t, move == NewMove(p, E2, E4) || move == NewMove(p, D2, D4))
+
+	book.policy = BookWeighted
+	move = book.pickMove(p)
+	expect.True(t, move == NewMove(p, E2, E4) || move == NewMove(p, D2, D4) || move == NewMove(p, C2, C4))
+
+	book.minWeight = 100
+	expect.Eq(t, book.pickMove(p), NewMove(p, E2, E4))
+}
+
+// Book depth and consecutive misses.
+func TestBook230(t *testing.T) {
+	book, p := startingBook()
+	defer os.Remove(book.fileName)
+
+	book.policy, book.depth, book.misses = BookBest, 1, 2
+	expect.Eq(t, book.pickMove(p), NewMove(p, E2, E4))
+
+	p = p.makeMove(NewMove(p, E2, E4))
+	expect.Eq(t, book.pickMove(p), Move(0))
+	expect.Eq(t, book.missed, 1)
+
+	p = p.makeMove(NewMove(p, E7, E5))
+	expect.Eq(t, book.pickMove(p), Move(0)) // Past the book depth.
+	expect.Eq(t, book.missed, 1)
+
+	book.depth = 0
+	p = p.makeMove(NewMove(p, G1, F3))
+	expect.Eq(t, book.pickMove(p), Move(0))
+	expect.Eq(t, book.missed, 2)
+
+	// The book is useless now even for the position it has.
+	p = NewGame().start()
+	p.fullmove = 2
+	expect.Eq(t, book.pickMove(p), Move(0))
+
+	// New game starts over.
+	p.fullmove = 1
+	expect.Eq(t, book.pickMove(p), NewMove(p, E2, E4))
+	expect.Eq(t, book.missed, 0)
+}
+
+// Learning from game results.
+func TestBook240(t *testing.T) {
+	book, p := startingBook()
+	defer os.Remove(book.fileName)
+
+	book.policy, book.learn = BookBest, true
+	book.pickMove(p)
+	p = p.makeMove(NewMove(p, E2, E4))
+	book.pickMove(p) // Miss.
+	expect.Eq(t, book.update(`1-0`), nil)
+	expect.Eq(t, len(book.played), 0)
+
+	p = p.undoLastMove()
+	entries := book.lookup(p)
+	expect.Eq(t, len(entries), 4)
+	expect.Eq(t, entries[0].Learn, uint32(1 << 16 | 2))
+	expect.Eq(t, entries[1].Learn, uint32(0))
+
+	book.pickMove(p)
+	book.update(`1/2-1/2`)
+	book.pickMove(p)
+	book.update(`0-1`)
+	book.pickMove(p)
+	book.update(`*`)
+	expect.Eq(t, book.lookup(p)[0].Learn, uint32(3 << 16 | 3))
+}
+
+// Engine plays book moves and learns through the game.
+func TestBook250(t *testing.T) {
+	_, p := startingBook()
+	fileName := bookFile(bookEntry(p, E2, E4, 100, 0), bookEntry(p, D2, D4, 80, 0))
+	defer os.Remove(fileName)
+
+	engine := NewEngine(`quiet`, true, `bookfile`, fileName, `bookpolicy`, `Best`, `booklearn`, true)
+	game := engine.NewGame()
+	p = game.start()
+	expect.Eq(t, game.Think(), NewMove(p, E2, E4))
+	game.learn(`0-1`)
+	game.learn(`0-1`) // Nothing to learn since the move has been learned already.
+
+	book, _ := NewBook(fileName)
+	entries := book.lookup(p)
+	expect.Eq(t, entries[0].Learn, uint32(1 << 16))
+	expect.Eq(t, game.Think(), NewMove(p, D2, D4))
+}
+
+// Learning from unfinished games.
+func TestBook260(t *testing.T) {
+	book, p := startingBook()
+	defer os.Remove(book.fileName)
+
+	expect.Eq(t, book.outcome(`0-1`), `0-1`)
+	expect.Eq(t, book.outcome(`*`), `*`)
+
+	for i := 0; i < learnWinCount; i++ {
+		book.track(p, InProgress, learnWinScore)
+	}
+	expect.Eq(t, book.outcome(`*`), `1-0`)
+	book.track(p.makeMove(NewMove(p, E2, E4)), InProgress, learnWinScore)
+	expect.Eq(t, book.outcome(`*`), `*`)
+
+	book.track(p, Repetition, 0)
+	expect.Eq(t, book.outcome(`*`), `1/2-1/2`)
+	expect.Eq(t, book.outcome(`1-0`), `1-0`)
+}
+
+// Engine learns from the game when its own search finds the game is over.
+func TestBook270(t *testing.T) {
+	p := NewGame(`Kg1,Ra1,f2,g2,h2`, `Kg8,f7,g7,h7`).start()
+	fileName := bookFile(bookEntry(p, A1, B1, 100, 0))
+	defer os.Remove(fileName)
+
+	engine := NewEngine(`quiet`, true, `bookfile`, fileName, `booklearn`, true, `depth`, 4)
+	game := engine.NewGame(`Kg1,Ra1,f2,g2,h2`, `Kg8,f7,g7,h7`)
+	p = game.start()
+	move := game.Think()
+	expect.Eq(t, move, NewMove(p, A1, B1))
+	p = p.makeMove(move)
+	p = p.makeMove(NewMove(p, G8, H8))
+	move = game.Think()
+	expect.Eq(t, move, NewMove(p, B1, B8))
+	game.learn(`*`)
+
+	book, _ := NewBook(fileName)
+	expect.Eq(t, book.lookup(NewGame(`Kg1,Ra1,f2,g2,h2`, `Kg8,f7,g7,h7`).start())[0].Learn, uint32(1 << 16 | 2))
+}
+
+// Book is not used when the search is restricted to some moves.
+func TestBook280(t *testing.T) {
+	p := NewGame().start()
+	fileName := bookFile(bookEntry(p, E2, E4, 100, 0))
+	defer os.Remove(fileName)
+
+	engine := NewEngine(`quiet`, true, `bookfile`, fileName, `depth`, 2)
+	game := engine.NewGame()
+	p = game.start()
+	engine.options.searchMoves = []Move{ NewMove(p, D2, D4) }
+	expect.Eq(t, game.Think(), NewMove(p, D2, D4))
+
+	engine.options.searchMoves = nil
+	expect.Eq(t, game.Think(), NewMove(p, E2, E4))
+}
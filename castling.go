@@ -0,0 +1,220 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+// Castle setup of the game. In standard chess the kings start off E1 and E8,
+// and the rooks off A1, H1, A8, and H8. In Chess960 (Fischer Random) these
+// squares depend on the starting position. Either way the king ends up on G1
+// or C1 (G8 or C8 for Black), and the rook next to it on F1 or D1.
+type Castling struct {
+	chess960    bool 	// True when playing Chess960.
+	king        [2]int 	// King's home square for both colors.
+	rook        [2][2]int 	// Home squares of castling rooks: [color][0] kingside, [color][1] queenside.
+	rights      [64]uint8 	// Castle rights that are left after moving from or to the square.
+}
+
+// Standard chess castle setup.
+func NewCastling(chess960 bool) Castling {
+	return Castling{
+		chess960: chess960,
+		king:     [2]int{ E1, E8 },
+		rook:     [2][2]int{ { H1, A1 }, { H8, A8 } },
+		rights:   castleRights,
+	}
+}
+
+// Sets the home squares of the king and castling rook, and updates castle
+// rights masks accordingly.
+func (c *Castling) set(color uint8, side int, king, rook int) *Castling {
+	c.king[color], c.rook[color][side] = king, rook
+
+	for square := A1; square <= H8; square++ {
+		c.rights[square] = 0x0F
+	}
+	for color := uint8(White); color <= uint8(Black); color++ {
+		c.rights[c.king[color]] &= ^(castleKingside[color] | castleQueenside[color])
+		c.rights[c.rook[color][0]] &= ^castleKingside[color]
+		c.rights[c.rook[color][1]] &= ^castleQueenside[color]
+	}
+
+	return c
+}
+
+// Returns castle rights mask for the given side: 0 for kingside and 1 for
+// queenside.
+func castleSide(color uint8, side int) uint8 {
+	if side == 0 {
+		return castleKingside[color]
+	}
+	return castleQueenside[color]
+}
+
+// Returns the side of the castle move: 0 for kingside and 1 for queenside.
+func (m Move) castleSide() int {
+	if col(m.to()) == 6 { // G1 or G8.
+		return 0
+	}
+	return 1
+}
+
+// Returns the square the king is moving to when castling, and the square the
+// rook is moving to.
+func castleTargets(color uint8, side int) (king, rook int) {
+	if side == 0 {
+		return G1 + 56 * int(color), F1 + 56 * int(color)
+	}
+	return C1 + 56 * int(color), D1 + 56 * int(color)
+}
+
+// Returns the bitmask of squares on the same rank between the two squares,
+// both squares included.
+func rankSpan(from, to int) (mask Bitmask) {
+	for square := min(from, to); square <= max(from, to); square++ {
+		mask |= bit[square]
+	}
+	return
+}
+
+// Returns the outermost rook of the given color on the back rank, on the given
+// side of the king. Used to resolve KQkq castle rights in Chess960 positions.
+func (p *Position) outermostRook(color uint8, side int) int {
+	king := int(p.king[color])
+	if side == 0 {
+		for square := H1 + 56 * int(color); square > king; square-- {
+			if p.pieces[square] == rook(color) {
+				return square
+			}
+		}
+	} else {
+		for square := A1 + 56 * int(color); square < king; square++ {
+			if p.pieces[square] == rook(color) {
+				return square
+			}
+		}
+	}
+	return -1
+}
+
+// Sets castle rights in Chess960 position as encoded by Shredder-FEN (rook
+// files, ex. "HAha") or X-FEN (KQkq for the outermost rooks, or rook files).
+// Rights that do not match the position are kept as is so that validation
+// reports them: the king stays at its standard home square if it's not on the
+// back rank, and so does the rook if there is no rook on that side.
+func (p *Position) castles960(rights string) *Position {
+	castling := &p.worker.game.castling
+	for _, char := range rights {
+		color, side, home := uint8(White), 0, -1
+		if char >= 'a' && char <= 'z' {
+			color = Black
+		}
+		king := int(p.king[color])
+		if row(king) != int(color) * 7 {
+			king = castling.king[color]
+		}
+
+		switch char {
+		case 'K', 'k':
+			if home = p.outermostRook(color, 0); home < 0 {
+				home = square(int(color) * 7, 7)
+			}
+		case 'Q', 'q':
+			if side, home = 1, p.outermostRook(color, 1); home < 0 {
+				home = square(int(color) * 7, 0)
+			}
+		default:
+			if char >= 'A' && char <= 'H' {
+				home = square(0, int(char - 'A'))
+			} else {
+				home = square(7, int(char - 'a'))
+			}
+			if home < king {
+				side = 1
+			}
+		}
+
+		castling.set(color, side, king, home)
+		p.castles |= castleSide(color, side)
+	}
+
+	return p
+}
+
+// Encodes castle rights of Chess960 position as X-FEN: the outermost rooks are
+// represented as KQkq, and the rest by their files.
+func (p *Position) fen960() (rights string) {
+	castling := &p.worker.game.castling
+	for color := uint8(White); color <= uint8(Black); color++ {
+		for side := 0; side <= 1; side++ {
+			if p.castles & castleSide(color, side) != 0 {
+				square := castling.rook[color][side]
+				char := byte('K')
+				if side == 1 {
+					char = 'Q'
+				}
+				if square != p.outermostRook(color, side) {
+					char = byte(col(square)) + 'A'
+				}
+				if color == Black {
+					char += 32
+				}
+				rights += string(char)
+			}
+		}
+	}
+	return
+}
+
+// Chess960 castle rules: all the squares between the king and its destination,
+// and between the rook and its destination must be empty except for the king
+// and the castling rook themselves. None of the squares the king travels over
+// can be attacked; the castling rook is taken off the board when checking that
+// since it might be shielding the king's destination.
+func (p *Position) canCastle960(color uint8) (kingside, queenside bool) {
+	castling := &p.worker.game.castling
+	can := [2]bool{}
+
+	for side := 0; side <= 1; side++ {
+		if p.castles & castleSide(color, side) == 0 {
+			continue
+		}
+
+		king, rook := castling.king[color], castling.rook[color][side]
+		kingTo, rookTo := castleTargets(color, side)
+		board := p.board & ^bit[king] & ^bit[rook]
+		if (rankSpan(king, kingTo) | rankSpan(rook, rookTo)) & board != 0 {
+			continue
+		}
+
+		can[side] = true
+		board = p.board & ^bit[rook]
+		for path := rankSpan(king, kingTo); path != 0; {
+			if p.attackers(color ^ 1, path.pop(), board) != 0 {
+				can[side] = false
+				break
+			}
+		}
+	}
+
+	return can[0], can[1]
+}
+
+// Moves the king and the rook when castling. In Chess960 the king or the rook
+// might stay put, or land on the square the other one is moving from, so we
+// fix up the pieces once both have moved.
+func (p *Position) castle(move Move) *Position {
+	color, from, to, side := move.color(), move.from(), move.to(), move.castleSide()
+	rookFrom := p.worker.game.castling.rook[color][side]
+	_, rookTo := castleTargets(color, side)
+
+	if from != to {
+		p.movePiece(king(color), from, to)
+	}
+	if rookFrom != rookTo {
+		p.movePiece(rook(color), rookFrom, rookTo)
+	}
+	p.pieces[to], p.pieces[rookTo] = king(color), rook(color)
+
+	return p
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `testing`)
+
+func chess960(fen string) *Position {
+	return NewEngine(`chess960`, true).NewGame(fen).start()
+}
+
+// Shredder-FEN and X-FEN castle rights.
+func TestCastling000(t *testing.T) {
+	p := chess960(`1r3k1r/8/8/8/8/8/8/1R3K1R w HBhb - 0 1`)
+	expect.Eq(t, p.castles, uint8(0x0F))
+	expect.Eq(t, p.worker.game.castling.rook, [2][2]int{ { H1, B1 }, { H8, B8 } })
+	expect.Eq(t, p.fen(), `1r3k1r/8/8/8/8/8/8/1R3K1R w KQkq - 0 1`)
+}
+
+func TestCastling010(t *testing.T) {
+	p := chess960(`rr3k2/8/8/8/8/8/8/RR3K2 w Bb - 0 1`)
+	expect.Eq(t, p.worker.game.castling.rook, [2][2]int{ { H1, B1 }, { H8, B8 } })
+	expect.Eq(t, p.fen(), `rr3k2/8/8/8/8/8/8/RR3K2 w Bb - 0 1`)
+}
+
+func TestCastling020(t *testing.T) {
+	p := chess960(`rr3k2/8/8/8/8/8/8/RR3K2 w Qq - 0 1`)
+	expect.Eq(t, p.worker.game.castling.rook, [2][2]int{ { H1, A1 }, { H8, A8 } })
+	expect.Eq(t, p.fen(), `rr3k2/8/8/8/8/8/8/RR3K2 w Qq - 0 1`)
+}
+
+// Rook files don't imply Chess960 if the engine is not set to play it.
+func TestCastling030(t *testing.T) {
+	p := NewGame(`r3k2r/8/8/8/8/8/8/R3K2R w HAha - 0 1`).start()
+	expect.False(t, p.worker.game.castling.chess960)
+	expect.Eq(t, p.castles, uint8(0x0F))
+	expect.Eq(t, p.fen(), `r3k2r/8/8/8/8/8/8/R3K2R w KQkq - 0 1`)
+
+	_, err := NewGame(`1r3k1r/8/8/8/8/8/8/1R3K1R w HBhb - 0 1`).setup()
+	expect.Eq(t, err.(*PositionError).Code, InvalidCastles)
+}
+
+// Standard chess position remains standard.
+func TestCastling040(t *testing.T) {
+	p := NewGame().start()
+	expect.False(t, p.worker.game.castling.chess960)
+	expect.Eq(t, p.fen(), `rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1`)
+}
+
+// Castles as king takes rook.
+func TestCastling100(t *testing.T) {
+	p := chess960(`1r3k1r/8/8/8/8/8/8/1R3K1R w HBhb - 0 1`)
+	move := NewMoveFromNotation(p, `f1h1`)
+	expect.True(t, move.isCastle())
+	expect.Eq(t, move, `0-0`)
+	expect.Eq(t, move.uci(p.worker.game), `f1h1`)
+
+	p = p.makeMove(move)
+	expect.Eq(t, p.pieces[G1], Piece(King))
+	expect.Eq(t, p.pieces[F1], Piece(Rook))
+	expect.Eq(t, p.pieces[H1], Piece(0))
+	expect.Eq(t, p.castles, uint8(0x0C))
+	expect.Eq(t, p.fen(), `1r3k1r/8/8/8/8/8/8/1R3RK1 b kq - 1 1`)
+}
+
+func TestCastling110(t *testing.T) {
+	p := chess960(`1r3k1r/8/8/8/8/8/8/1R3K1R b HBhb - 0 1`)
+	move := NewMoveFromNotation(p, `f8b8`)
+	expect.Eq(t, move, `0-0-0`)
+	expect.Eq(t, move.uci(p.worker.game), `f8b8`)
+	expect.Eq(t, move.san(p), `O-O-O`)
+
+	p = p.makeMove(move)
+	expect.Eq(t, p.pieces[C8], Piece(BlackKing))
+	expect.Eq(t, p.pieces[D8], Piece(BlackRook))
+	expect.Eq(t, p.pieces[B8], Piece(0))
+	expect.Eq(t, p.pieces[F8], Piece(0))
+}
+
+// King and rook swap places.
+func TestCastling120(t *testing.T) {
+	p := chess960(`5kr1/8/8/8/8/8/8/5KR1 w Gg - 0 1`)
+	move := NewMoveFromNotation(p, `f1g1`)
+	expect.True(t, move.isCastle())
+	expect.Eq(t, move.uci(p.worker.game), `f1g1`)
+
+	p = p.makeMove(move)
+	expect.Eq(t, p.pieces[G1], Piece(King))
+	expect.Eq(t, p.pieces[F1], Piece(Rook))
+	expect.Eq(t, p.outposts[White], bit[F1] | bit[G1])
+	expect.Eq(t, int(p.king[White]), G1)
+}
+
+// King stays put, and the move is still a castle.
+func TestCastling130(t *testing.T) {
+	p := chess960(`6kr/8/8/8/8/8/8/6KR w Hh - 0 1`)
+	move := NewMoveFromSan(p, `O-O`)
+	expect.Eq(t, move.uci(p.worker.game), `g1h1`)
+
+	p = p.makeMove(move)
+	expect.Eq(t, p.pieces[G1], Piece(King))
+	expect.Eq(t, p.pieces[F1], Piece(Rook))
+	expect.Eq(t, p.pieces[H1], Piece(0))
+}
+
+// Castle through attacked square is not allowed.
+func TestCastling140(t *testing.T) {
+	p := chess960(`1r3k1r/8/8/8/8/3r4/8/1R3K1R w HB - 0 1`)
+	kingside, queenside := p.canCastle(White)
+	expect.True(t, kingside)
+	expect.False(t, queenside)
+}
+
+// Castling rook shields the king's destination square.
+func TestCastling150(t *testing.T) {
+	p := chess960(`2r3k1/8/8/8/8/8/8/1RK5 w B - 0 1`)
+	_, queenside := p.canCastle(White)
+	expect.False(t, queenside)
+}
+
+// Chess960 perft.
+func TestCastling200(t *testing.T) {
+	p := chess960(`bqnb1rkr/pp3ppp/3ppn2/2p5/5P2/P2P4/NPP1P1PP/BQ1BNRKR w HFhf - 2 9`)
+	expect.Eq(t, p.Perft(1), int64(21))
+	expect.Eq(t, p.Perft(2), int64(528))
+	expect.Eq(t, p.Perft(3), int64(12189))
+}
+
+func TestCastling210(t *testing.T) {
+	p := chess960(`2nnrbkr/p1qppppp/8/1ppb4/6PP/3PP3/PPP2P2/BQNNRBKR w HEhe - 1 9`)
+	expect.Eq(t, p.Perft(1), int64(21))
+	expect.Eq(t, p.Perft(2), int64(807))
+	expect.Eq(t, p.Perft(3), int64(18002))
+}
+
+func TestCastling220(t *testing.T) {
+	p := chess960(`b1q1rrkb/pppppppp/3nn3/8/P7/1PPP4/4PPPP/BQNNRKRB w GE - 1 9`)
+	expect.Eq(t, p.Perft(1), int64(20))
+	expect.Eq(t, p.Perft(2), int64(479))
+	expect.Eq(t, p.Perft(3), int64(10471))
+}
+
+func TestCastling230(t *testing.T) {
+	p := chess960(`qbbnnrkr/2pp2pp/p7/1p2pp2/8/P3PP2/1PPP1KPP/QBBNNR1R w hf - 0 9`)
+	expect.Eq(t, p.Perft(1), int64(22))
+	expect.Eq(t, p.Perft(2), int64(593))
+	expect.Eq(t, p.Perft(3), int64(13440))
+}
+
+func TestCastling240(t *testing.T) {
+	p := chess960(`1nbbnrkr/p1p1ppp1/3p4/1p3P1p/3Pq2P/8/PPP1P1P1/QNBBNRKR w HFhf - 0 9`)
+	expect.Eq(t, p.Perft(1), int64(28))
+	expect.Eq(t, p.Perft(2), int64(1120))
+	expect.Eq(t, p.Perft(3), int64(31058))
+	expect.Eq(t, p.Perft(4), int64(1171749))
+}
@@ -5,12 +5,49 @@
 package main
 
 import (
+	`flag`
+	`fmt`
 	`github.com/michaeldv/donna`
 	`os`
 	`runtime`
+	`strconv`
+	`strings`
+	`time`
 )
 
 func main() {
+	interactive := flag.Bool(`i`, false, `interactive mode (REPL)`)
+	suite := flag.String(`epd`, ``, `run EPD test suite from the `+"`file`")
+	depth := flag.Int(`depth`, 0, `search depth per EPD position or self-play move (6 by default)`)
+	moveTime := flag.Int(`movetime`, 5000, `search time per EPD position in milliseconds`)
+	report := flag.String(`report`, ``, `save EPD suite results to .json or .csv `+"`file`")
+	opponent := flag.String(`match`, ``, `play match against UCI engine `+"`command`"+`, or "donna" for self-play`)
+	games := flag.Int(`games`, 0, `number of match or self-play games (100 by default), or maximum number of SPRT games (no limit by default)`)
+	sprt := flag.String(`sprt`, ``, `run SPRT match with the given bounds: elo0,elo1[,alpha,beta]`)
+	tc := flag.String(`tc`, `40/60+1`, `match time control: moves/seconds+increment, movetime=ms, depth=n, or nodes=n`)
+	openings := flag.String(`openings`, ``, `play match openings from EPD `+"`file`")
+	pgn := flag.String(`pgn`, ``, `append match games to PGN `+"`file`")
+	weights := flag.String(`weights`, ``, `evaluation weights for the match, ex. Mobility=120,KingSafety=90`)
+	rate := flag.String(`rate`, ``, `compute Elo difference from PGN `+"`file`")
+	player := flag.String(`player`, `Donna`, `player name to compute Elo for`)
+	tune := flag.String(`tune`, ``, `tune evaluation parameters using quiet positions labelled with game results from `+"`file`")
+	selected := flag.String(`select`, ``, `tune only the parameters whose names match regular expression, ex. ^mobility`)
+	iterations := flag.Int(`iterations`, 0, `maximum number of tuning iterations (no limit by default)`)
+	output := flag.String(`output`, ``, `save tuned evaluation parameters (params.txt by default) or opening book (book.bin by default) to `+"`file`")
+	params := flag.String(`params`, os.Getenv(`DONNA_PARAMS`), `load evaluation parameters from `+"`file`")
+	network := flag.String(`network`, os.Getenv(`DONNA_NETWORK`), `evaluate positions with neural network loaded from `+"`file`")
+	gensfen := flag.String(`gensfen`, ``, `generate training positions by self-play and save them to `+"`file`")
+	nodes := flag.Int(`nodes`, 0, `search nodes per self-play move`)
+	threads := flag.Int(`threads`, 1, `number of self-play games to play in parallel`)
+	seed := flag.Int64(`seed`, 0, `random seed for self-play openings (current time by default)`)
+	randomPlies := flag.Int(`randomplies`, 8, `number of random moves to start each self-play game with`)
+	makeBook := flag.String(`makebook`, ``, `build Polyglot opening book from PGN `+"`file`")
+	plies := flag.Int(`plies`, 24, `number of plies to keep from each opening book game`)
+	minElo := flag.Int(`minelo`, 0, `minimum player rating to add the moves to opening book`)
+	minCount := flag.Int(`mincount`, 1, `minimum number of games the move must have been played in to add it to opening book`)
+	results := flag.String(`results`, `1-0,0-1,1/2-1/2`, `game results to add to opening book`)
+	flag.Parse()
+
 	// Default engine settings are: 128MB transposition table, 5s per move.
 	engine := donna.NewEngine(
 		`fancy`, runtime.GOOS == `darwin`,
@@ -18,11 +55,322 @@ func main() {
 		`movetime`, 5000,
 		`logfile`, os.Getenv(`DONNA_LOG`),
 		`bookfile`, os.Getenv(`DONNA_BOOK`),
+		`syzygypath`, os.Getenv(`DONNA_SYZYGY`),
+		`paramsfile`, *params,
+		`evalfile`, *network,
+		`usenetwork`, *network != ``,
 	)
 
-	if len(os.Args) > 1 && os.Args[1] == `-i` {
+	if *suite != `` {
+		os.Exit(runSuite(engine, *suite, *depth, *moveTime, *report))
+	} else if *opponent != `` {
+		os.Exit(runMatch(*opponent, *games, *tc, *openings, *pgn, *weights, *params, *sprt))
+	} else if *rate != `` {
+		os.Exit(runRate(*rate, *player))
+	} else if *tune != `` {
+		os.Exit(runTuner(engine, *tune, *selected, *iterations, *output))
+	} else if *makeBook != `` {
+		os.Exit(runBookBuilder(*makeBook, *output, *plies, *minElo, *minCount, *results))
+	} else if *gensfen != `` {
+		os.Exit(runSelfPlay(*gensfen, *games, *depth, *nodes, *threads, *seed, *randomPlies, *network))
+	} else if *interactive {
 		engine.Repl()
 	} else {
 		engine.Uci()
 	}
 }
+
+// Runs EPD test suite and returns exit status: zero if all the positions have
+// been solved, one otherwise.
+func runSuite(engine *donna.Engine, fileName string, depth, moveTime int, report string) int {
+	file, err := os.Open(fileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer file.Close()
+
+	epds, err := donna.ReadEpd(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if depth > 0 {
+		moveTime = 0
+	}
+
+	solved := 0
+	results := engine.Suite(epds, depth, moveTime, func(result *donna.EpdResult) {
+		status := `-`
+		if result.Solved {
+			status, solved = `+`, solved + 1
+		}
+		fmt.Printf("%s %-16s %-8s depth %2d time %6d nodes %10d %s\n", status, result.Id, result.Move, result.Depth, result.Time, result.Nodes, result.Error)
+	})
+	fmt.Printf("Solved %d out of %d\n", solved, len(results))
+
+	if report != `` {
+		if err := donna.WriteEpdReport(report, results); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	if solved < len(results) {
+		return 1
+	}
+	return 0
+}
+
+// Plays the match between Donna and the opponent, and returns exit status:
+// zero if the match has been played, two on errors. Donna is configured with
+// the given evaluation weights, ex. "Mobility=120,KingSafety=90", and
+// evaluation parameters file, if any, while self-play opponent is using the
+// default ones. In SPRT mode the exit status
+// is zero if H0 has been rejected, i.e. Donna is stronger, and one otherwise.
+func runMatch(opponent string, games int, tc, openings, pgn, weights, params, sprt string) int {
+	timeControl, err := donna.NewTimeControl(tc)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	var test *donna.Sprt
+	if sprt != `` {
+		bounds := []float64{ 0.0, 0.0, 0.05, 0.05 }
+		for i, value := range strings.Split(sprt, `,`) {
+			if i >= len(bounds) {
+				break
+			}
+			if bounds[i], err = strconv.ParseFloat(value, 64); err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid SPRT bounds %s\n", sprt)
+				return 2
+			}
+		}
+		test = donna.NewSprt(bounds[0], bounds[1], bounds[2], bounds[3])
+	} else if games == 0 {
+		games = 100
+	}
+
+	args := []interface{}{ `cache`, 16, `ownbook`, false }
+	for _, weight := range strings.Split(weights, `,`) {
+		if pair := strings.SplitN(weight, `=`, 2); len(pair) == 2 {
+			n, err := strconv.Atoi(pair[1])
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Invalid weight %s\n", weight)
+				return 2
+			}
+			args = append(args, strings.ToLower(pair[0]), n)
+		}
+	}
+	if params != `` { // The baseline keeps default evaluation parameters.
+		args = append(args, `paramsfile`, params)
+	}
+	first := donna.NewEnginePlayer(`Donna`, donna.NewEngine(args...))
+
+	var second donna.Player = donna.NewEnginePlayer(`Donna (baseline)`, donna.NewEngine(`cache`, 16, `ownbook`, false))
+	if opponent != `donna` {
+		fields := strings.Fields(opponent)
+		if second, err = donna.NewUciPlayer(map[string]string{ `Hash`: `16` }, fields[0], fields[1:]...); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+	}
+	defer second.Close()
+
+	var fens []string
+	if openings != `` {
+		file, err := os.Open(openings)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		epds, err := donna.ReadEpd(file)
+		file.Close()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 2
+		}
+		for _, epd := range epds {
+			fens = append(fens, epd.Fen)
+		}
+	}
+
+	match := donna.NewMatch(first, second, `games`, games, `tc`, timeControl, `openings`, fens, `pgnfile`, pgn)
+	progress := func(round int, game *donna.Pgn, score donna.MatchScore) {
+		fmt.Printf("%3d) %s - %s %s (%s)\n", round, game.Tag(`White`), game.Tag(`Black`), game.Result, game.Tag(`Termination`))
+		fmt.Printf("     %s\n", score)
+		if test != nil && round % 2 == 0 {
+			fmt.Printf("     %s\n", test)
+		}
+	}
+
+	var score donna.MatchScore
+	if test != nil {
+		score, err = match.Sprt(test, progress)
+	} else {
+		score, err = match.Play(progress)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Printf("%s vs. %s: %s\n", first.Name(), second.Name(), score)
+
+	if test != nil {
+		fmt.Printf("%s\n", test)
+		if test.Status() != donna.SprtRejectH0 {
+			return 1
+		}
+	}
+	return 0
+}
+
+// Computes Elo difference between the player and its opponents based on PGN
+// games. Returns exit status: zero on success, two on errors.
+func runRate(fileName, player string) int {
+	file, err := os.Open(fileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer file.Close()
+
+	games, err := donna.ReadPgn(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	score := donna.PgnScore(games, player)
+	if score.Games() == 0 {
+		fmt.Fprintf(os.Stderr, "No games played by %s\n", player)
+		return 2
+	}
+	fmt.Printf("%s: %s\n", player, score)
+
+	return 0
+}
+
+// Runs Texel tuner and saves tuned parameters after each iteration. Returns
+// exit status: zero on success, two on errors.
+func runTuner(engine *donna.Engine, fileName, selected string, iterations int, output string) int {
+	if output == `` {
+		output = `params.txt`
+	}
+	file, err := os.Open(fileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer file.Close()
+
+	tuner, err := engine.NewTuner(file)
+	if err == nil && selected != `` {
+		err = tuner.Select(selected)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	k := tuner.FitK()
+	fmt.Printf("Positions %d, K %.4f, error %.6f\n", tuner.Size(), k, tuner.Error())
+	_, tuneErr := tuner.Tune(iterations, func(iteration int, mse float64) {
+		fmt.Printf("Iteration %d, error %.6f\n", iteration, mse)
+		if err = save(tuner, output); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	})
+	if tuneErr != nil {
+		fmt.Fprintln(os.Stderr, tuneErr)
+		return 2
+	}
+	if err != nil {
+		return 2
+	}
+	fmt.Printf("Tuned parameters saved to %s\n", output)
+
+	return 0
+}
+
+func save(tuner *donna.Tuner, fileName string) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	if err = tuner.Save(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// Plays self-play games and appends quiet positions labelled with search
+// scores and game results to the file. Returns exit status: zero on success,
+// two on errors.
+func runSelfPlay(fileName string, games, depth, nodes, threads int, seed int64, randomPlies int, network string) int {
+	file, err := os.OpenFile(fileName, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer file.Close()
+
+	if games == 0 {
+		games = 100
+	}
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	fmt.Printf("Seed %d\n", seed)
+
+	selfPlay := donna.NewSelfPlay(`games`, games, `depth`, depth, `nodes`, nodes, `threads`, threads,
+		`cache`, 16, `seed`, seed, `randomplies`, randomPlies, `evalfile`, network)
+	positions, err := selfPlay.Run(file, func(played, positions int) {
+		fmt.Printf("Games %d, positions %d\n", played, positions)
+	})
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Printf("Saved %d positions to %s\n", positions, fileName)
+
+	return 0
+}
+
+// Builds Polyglot opening book from PGN games. Returns exit status: zero on
+// success, two on errors.
+func runBookBuilder(fileName, output string, plies, minElo, minCount int, results string) int {
+	file, err := os.Open(fileName)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	defer file.Close()
+
+	builder := donna.NewBookBuilder(`plies`, plies, `minelo`, minElo, `mincount`, minCount, `results`, results)
+	games, err := builder.Read(file)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if output == `` {
+		output = `book.bin`
+	}
+	book, err := os.Create(output)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	entries, err := builder.Save(book)
+	if err == nil {
+		err = book.Close()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	fmt.Printf("Saved %d entries from %d games to %s\n", entries, games, output)
+
+	return 0
+}
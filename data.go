@@ -12,6 +12,9 @@ const Version = `1.0` // Dec 14, 2014
 const (
 	MaxPly = 64
 	MaxDepth = 32
+	MaxThreads = 64
+	MaxMultiPV = 64
+	MaxCache = 4096 // Megabytes.
 	Checkmate = 0x7FFF // = math.MaxInt16 = 32,767
 )
 
@@ -86,6 +89,7 @@ var castleRights = [64]uint8{
 }
 
 var reMove = regexp.MustCompile(`([KQRBNEC]?)([a-h])([1-8])`)
+var reSan = regexp.MustCompile(`^([KQRBN])?([a-h])?([1-8])?[-x]?([a-h][1-8])(=?[QRBNqrbn])?$`)
 
 var maskRank = [8]Bitmask{ // 0 to 8
 	0x00000000000000FF, 0x000000000000FF00, 0x0000000000FF0000, 0x00000000FF000000,
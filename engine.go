@@ -4,49 +4,91 @@
 
 package donna
 
-import (`fmt`; `os`; `time`)
+import (`fmt`; `os`; `strings`; `sync`; `sync/atomic`; `time`)
 
-const Ping = 125 // Check time 8 times a second.
+const Ping = 125 // Check for "stop" 8 times a second.
 
+// Clock keeps track of search time. Halt and ponderhit flags are set by the UCI
+// goroutine while the search is running so they are accessed atomically; the
+// rest is owned by the search.
 type Clock struct {
-	halt        bool     // Stop search immediately when set to true.
+	halt        int32    // Stop search immediately when set.
+	ponderhit   int32    // The GUI has sent "ponderhit" while pondering.
+	ticking     bool     // True when the search is limited by time.
 	optimal     int64    // Minimum time slot for the move.
 	softStop    int64    // Target soft time limit to make a move.
 	hardStop    int64    // Immediate stop time limit.
 	extra       float32  // Extra time factor based on search volatility.
 	start       time.Time
-	ticker      *time.Ticker
+}
+
+// Stops the search.
+func (c *Clock) stop() *Clock {
+	atomic.StoreInt32(&c.halt, 1)
+	return c
+}
+
+// Returns true if the search has been stopped.
+func (c *Clock) stopped() bool {
+	return atomic.LoadInt32(&c.halt) != 0
+}
+
+// Clears halt and ponderhit flags before starting new search.
+func (c *Clock) reset() *Clock {
+	atomic.StoreInt32(&c.halt, 0)
+	atomic.StoreInt32(&c.ponderhit, 0)
+	return c
 }
 
 type Options struct {
-	ponder      bool     // (-) Pondering mode.
-	infinite    bool     // (-) Search until the "stop" command.
+	ponder      bool     // Pondering mode: search until "ponderhit" or "stop".
+	infinite    bool     // Search until the "stop" command.
 	maxDepth    int      // Search X plies only.
-	maxNodes    int      // (-) Search X nodes only.
+	maxNodes    int      // Search X nodes only.
 	moveTime    int64    // Search exactly X milliseconds per move.
 	movesToGo   int64    // Number of moves to make till time control.
 	timeLeft    int64    // Time left for all remaining moves.
 	timeInc     int64    // Time increment after the move is made.
+	searchMoves []Move   // Search these root moves only.
 }
 
 type Engine struct {
 	log         bool     // Enable logging.
 	uci	    bool     // Use UCI protocol.
-	trace       bool     // Trace evaluation scores.
 	fancy       bool     // Represent pieces as UTF-8 characters.
+	quiet       bool     // Do not print search progress and best move.
+	chess960    bool     // Play Chess960 (Fischer Random) chess.
 	status      uint8    // Engine status.
+	ownBook     bool     // Use opening book if available.
 	logFile     string   // Log file name.
 	bookFile    string   // Polyglot opening book file name.
-	cacheSize   float64  // Default cache size.
+	book        bookOptions // Opening book move selection settings.
+	paramsFile  string   // Evaluation parameters file name.
+	params      *Params  // Evaluation parameters, compiled-in defaults unless loaded from paramsFile.
+	paramsLock  sync.Mutex // Guards parameter changes and the search count below.
+	paramsReady *sync.Cond // Signals the end of parameters change.
+	searches    int      // Number of the engine's games being searched.
+	changing    bool     // Evaluation parameters are being changed.
+	pawnToken   int      // Pawn cache expiration token, changes along with evaluation parameters and weights.
+	evalFile    string   // Evaluation network weights file name.
+	useNetwork  bool     // Evaluate with the network rather than classical evaluation.
+	network     *Network // Evaluation network loaded from evalFile.
+	evaluator   Evaluator // Evaluation backend.
+	cacheSize   float64  // Default cache size in megabytes, no cache if zero.
+	threads     int      // Number of search threads.
+	multiPV     int      // Number of best lines to search and report.
+	syzygyPath  string   // Directories with Syzygy endgame tables.
+	syzygyDepth int      // Minimum depth to probe the tables at.
+	tablebase   *Tablebase // Syzygy tables found in syzygyPath.
+	weights     []Score  // Evaluation weights.
 	clock       Clock
 	options     Options
 }
 
-// Use single statically allocated variable.
-var engine Engine
-
 func NewEngine(args ...interface{}) *Engine {
-	engine = Engine{}
+	engine := &Engine{ownBook: true, syzygyDepth: 1, params: &defaultParams}
+	engine.paramsReady = sync.NewCond(&engine.paramsLock)
+	engine.weights = append([]Score{}, weights...)
 	for i := 0; i < len(args); i += 2 {
 		switch value := args[i+1]; args[i] {
 		case `log`:
@@ -55,14 +97,51 @@ func NewEngine(args ...interface{}) *Engine {
 			engine.logFile = value.(string)
 		case `bookfile`:
 			engine.bookFile = value.(string)
+		case `ownbook`:
+			engine.ownBook = value.(bool)
+		case `bookpolicy`:
+			if policy, ok := bookPolicies[strings.ToLower(value.(string))]; ok {
+				engine.book.policy = policy
+			}
+		case `bookminweight`:
+			engine.book.minWeight = max(0, min(value.(int), 100))
+		case `bookdepth`:
+			engine.book.depth = max(0, value.(int))
+		case `bookmisses`:
+			engine.book.misses = max(0, value.(int))
+		case `booklearn`:
+			engine.book.learn = value.(bool)
+		case `evalfile`:
+			if err := engine.loadNetwork(value.(string)); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
+		case `usenetwork`:
+			engine.useNetwork = value.(bool)
+		case `paramsfile`:
+			if err := engine.LoadParams(value.(string)); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+			}
 		case `uci`:
 			engine.uci = value.(bool)
-		case `trace`:
-			engine.trace = value.(bool)
 		case `fancy`:
 			engine.fancy = value.(bool)
+		case `quiet`:
+			engine.quiet = value.(bool)
+		case `chess960`:
+			engine.chess960 = value.(bool)
+		case `threads`:
+			engine.threads = value.(int)
+		case `multipv`:
+			engine.multiPV = value.(int)
+		case `syzygypath`:
+			engine.syzygyPath = value.(string)
+			engine.tablebase = NewTablebase(engine.syzygyPath)
+		case `syzygydepth`:
+			engine.syzygyDepth = max(1, value.(int))
 		case `depth`:
 			engine.options.maxDepth = value.(int)
+		case `nodes`:
+			engine.options.maxNodes = value.(int)
 		case `movetime`:
 			engine.options.moveTime = int64(value.(int))
 		case `cache`:
@@ -72,10 +151,42 @@ func NewEngine(args ...interface{}) *Engine {
 			case int:
 				engine.cacheSize = float64(value.(int))
 			}
+		default: // Evaluation weights by their UCI option names, ex. `mobility`, 120.
+			for j, name := range uciWeights {
+				if args[i] == strings.ToLower(name) {
+					n := max(0, min(value.(int), 200))
+					engine.weights[j] = Score{n, n}
+				}
+			}
+		}
+	}
+
+	return engine.pickEvaluator()
+}
+
+// Loads evaluation network from the file. Empty file name unloads the network.
+func (e *Engine) loadNetwork(fileName string) error {
+	e.evalFile, e.network = fileName, nil
+	if fileName != `` {
+		network, err := LoadNetwork(fileName)
+		if err != nil {
+			e.evalFile = ``
+			return err
 		}
+		e.network = network
 	}
+	return nil
+}
 
-	return &engine
+// Sets evaluation backend: the network if it's enabled and loaded, classical
+// evaluation otherwise.
+func (e *Engine) pickEvaluator() *Engine {
+	if e.useNetwork && e.network != nil {
+		e.evaluator = e.network
+	} else {
+		e.evaluator = Classical{}
+	}
+	return e
 }
 
 // Dumps the string to standard output.
@@ -118,6 +229,10 @@ func (e *Engine) fixedDepth() bool {
 	return e.options.maxDepth > 0
 }
 
+func (e *Engine) fixedNodes() bool {
+	return e.options.maxNodes > 0
+}
+
 func (e *Engine) fixedTime() bool {
 	return e.options.moveTime > 0
 }
@@ -150,79 +265,73 @@ func (e *Engine) factor(depth int, volatility float32) *Engine {
 	return e
 }
 
-// Starts the clock setting ticker callback function. The callback function is
-// different for fixed and variable time controls.
+// Starts the clock. Time controls are checked by the main search worker and
+// only when the search is limited by time.
 func (e *Engine) startClock() *Engine {
-	e.clock.halt = false
-
-	if e.options.moveTime == 0 && e.options.timeLeft == 0 {
-		return e
-	}
-
+	e.clock.ticking = (e.options.moveTime != 0 || e.options.timeLeft != 0)
 	e.clock.start = time.Now()
-	e.clock.ticker = time.NewTicker(time.Millisecond * Ping)
-
-	if e.fixedTime() {
-		return e.fixedTimeTicker()
-	}
 
-	return e.varyingTimeTicker()
+	return e
 }
 
-// Stop the clock so that the ticker callback function is longer invoked.
+// Stops the clock so that time controls are no longer checked.
 func (e *Engine) stopClock() *Engine {
-	if e.clock.ticker != nil {
-		e.clock.ticker.Stop()
-		e.clock.ticker = nil
-	}
+	e.clock.ticking = false
 	return e
 }
 
-// Ticker callback for fixed time control (ex. 5s per move). Search gets terminated
-// when we've got the move and the elapsed time approaches time-per-move limit.
-func (e *Engine) fixedTimeTicker() *Engine {
-	go func() {
-		if e.clock.ticker == nil {
-			return // Nothing to do if the clock has been stopped.
+// Returns true while pondering. When the GUI sends "ponderhit" the search
+// switches to regular time controls set by the "go ponder" command.
+func (e *Engine) pondering() bool {
+	if e.options.ponder && atomic.LoadInt32(&e.clock.ponderhit) != 0 {
+		e.options.ponder = false
+		if !e.fixedDepth() {
+			e.startClock()
 		}
-		for now := range e.clock.ticker.C {
-			if len(game.rootpv) == 0 {
-				continue // Haven't found the move yet.
-			}
-			if e.elapsed(now) >= e.options.moveTime - Ping {
-				e.clock.halt = true
-				return
-			}
-		}
-	}()
+	}
+	return e.options.ponder
+}
 
+// Opponent has played expected move. Unlike other engine methods it's called
+// from the UCI goroutine while the search is running.
+func (e *Engine) ponderHit() *Engine {
+	atomic.StoreInt32(&e.clock.ponderhit, 1)
 	return e
 }
 
-// Ticker callback for the variable time control (ex. 40 moves in 5 minutes). Search
-// termination depends on multiple factors with hard stop being the ultimate limit.
-func (e *Engine) varyingTimeTicker() *Engine {
-	go func() {
-		if e.clock.ticker == nil {
-			return // Nothing to do if the clock has been stopped.
-		}
-		for now := range e.clock.ticker.C {
-			if len(game.rootpv) == 0 {
-				continue // Haven't found the move yet.
-			}
-			elapsed := e.elapsed(now)
-			if (game.deepening && game.improving && elapsed > e.remaining() * 4 / 5) || elapsed > e.clock.hardStop {
-				e.debug("# Halt: Flags %v Elapsed %s Remaining %s Hard stop %s\n",
-					game.deepening && game.improving, ms(elapsed), ms(e.remaining() * 4 / 5), ms(e.clock.hardStop))
-				e.clock.halt = true
-				return
-			}
-		}
-	}()
-
+// Keeps pondering or infinite search from returning best move until the GUI
+// sends either "ponderhit" or "stop" command.
+func (e *Engine) waitForStop() *Engine {
+	for (e.pondering() || e.options.infinite) && !e.clock.stopped() {
+		time.Sleep(time.Millisecond * Ping)
+	}
 	return e
 }
 
+// Returns true if the main worker has got the move and its time is up. With
+// fixed time control (ex. 5s per move) it's when elapsed time approaches time
+// per move limit. With variable time control (ex. 40 moves in 5 minutes) search
+// termination depends on multiple factors with hard stop being the ultimate
+// limit.
+func (e *Engine) timeIsUp(main *Worker) bool {
+	if !e.clock.ticking || len(main.rootpv) == 0 {
+		return false // Haven't found the move yet.
+	}
+
+	elapsed := e.elapsed(time.Now())
+	if e.fixedTime() {
+		return elapsed >= e.options.moveTime - Ping
+	}
+
+	if (main.deepening && main.improving && elapsed > e.remaining() * 4 / 5) || elapsed > e.clock.hardStop {
+		e.debug("# Halt: Flags %v Elapsed %s Remaining %s Hard stop %s\n",
+			main.deepening && main.improving, ms(elapsed), ms(e.remaining() * 4 / 5), ms(e.clock.hardStop))
+		return true
+	}
+
+	return false
+}
+
 // Sets fixed search limits such as maximum depth or time to make a move.
 func (e *Engine) fixedLimit(options Options) *Engine {
 	e.options = options
@@ -235,7 +344,6 @@ func (e *Engine) varyingLimits(options Options) *Engine {
 	// Note if it's a new time control before saving the options.
 	timeControl := options.movesToGo > e.options.movesToGo
 	e.options = options
-	e.options.ponder = false
 	e.options.infinite = false
 	e.options.maxDepth = 0
 	e.options.maxNodes = 0
@@ -297,3 +405,28 @@ func (e *Engine) varyingLimits(options Options) *Engine {
 
 	return e
 }
+
+// Logging wrapper around fmt.Printf() that could be turned on as needed. Typical
+// usage is e.Log(); defer e.Log() in tests.
+func (e *Engine) Log(args ...interface{}) {
+	switch len(args) {
+	case 0:
+		// Calling Log() with no arguments flips the logging setting.
+		e.log = !e.log
+		e.fancy = !e.fancy
+	case 1:
+		switch args[0].(type) {
+		case bool:
+			e.log = args[0].(bool)
+			e.fancy = args[0].(bool)
+		default:
+			if e.log {
+				fmt.Println(args...)
+			}
+		}
+	default:
+		if e.log {
+			fmt.Printf(args[0].(string), args[1:]...)
+		}
+	}
+}
@@ -5,8 +5,10 @@
 package donna
 
 import(
+	`bufio`
 	`fmt`
 	`io/ioutil`
+	`os`
 	`regexp`
 	`strconv`
 	`strings`
@@ -20,9 +22,9 @@ const (
 	escNone  = "\033[0m"
 )
 
-func (e *Engine) replBestMove(move Move) *Engine {
-	fmt.Printf(escTeal + "Donna's move: %s", move)
-	if game.nodes == 0 {
+func (e *Engine) replBestMove(game *Game, move Move) *Engine {
+	fmt.Printf(escTeal + "Donna's move: %s", move.formatSan(game.position(), e.fancy))
+	if nodes, _ := game.nodeCount(); nodes == 0 {
 		fmt.Printf(" (book)")
 	}
 	fmt.Println(escNone + "\n")
@@ -30,8 +32,9 @@ func (e *Engine) replBestMove(move Move) *Engine {
 	return e
 }
 
-func (e *Engine) replPrincipal(depth, score, status int, duration int64) {
-	fmt.Printf(`%2d %s %10d %10d %9d   `, depth, ms(duration), game.nodes, game.qnodes, nps(duration))
+func (e *Engine) replPrincipal(game *Game, depth, score, status int, duration int64) {
+	nodes, qnodes := game.nodeCount()
+	fmt.Printf(`%2d %s %10d %10d %9d   `, depth, ms(duration), nodes, qnodes, nps(nodes + qnodes, duration))
 	switch status {
 	case WhiteWon:
 		fmt.Println(`1-0 White Checkmates`)
@@ -44,31 +47,93 @@ func (e *Engine) replPrincipal(depth, score, status int, duration int64) {
 	case FiftyMoves:
 		fmt.Println(`1/2 Fifty Moves`)
 	case WhiteWinning, BlackWinning: // Show moves till checkmate.
-		fmt.Printf("%4dX   %v Checkmate\n", (Checkmate - abs(score)) / 2, game.rootpv)
+		fmt.Printf("%4dX   %s Checkmate\n", (Checkmate - abs(score)) / 2, e.moves(game, game.workers[0].rootpv))
 	default:
-		fmt.Printf("%5.2f   %v\n", float32(score) / float32(onePawn), game.rootpv)
+		fmt.Printf("%5.2f   %s\n", float32(score) / float32(onePawn), e.moves(game, game.workers[0].rootpv))
+	}
+
+	// In MultiPV mode list the rest of the variations ranked by their score.
+	if variations := game.workers[0].variations; len(variations) > 1 {
+		for i, variation := range variations[1:] {
+			score := variation.score
+			if game.position().color == Black {
+				score = -score
+			}
+			rank := fmt.Sprintf(`#%d`, i + 2)
+			if abs(score) >= Checkmate - MaxPly {
+				fmt.Printf("%44s   %4dX   %s\n", rank, (Checkmate - abs(score)) / 2, e.moves(game, variation.moves))
+			} else {
+				fmt.Printf("%44s   %5.2f   %s\n", rank, float32(score) / float32(onePawn), e.moves(game, variation.moves))
+			}
+		}
 	}
 }
 
+// Formats the variation in standard algebraic notation honoring engine's fancy
+// setting. The moves get replayed starting from the game's root position.
+func (e *Engine) moves(game *Game, moves []Move) string {
+	position := game.position()
+	list := make([]string, 0, len(moves))
+	for _, move := range moves {
+		list = append(list, move.formatSan(position, e.fancy))
+		position = position.makeMove(move)
+	}
+	for _ = range moves {
+		position = position.undoLastMove()
+	}
+	return `[` + strings.Join(list, ` `) + `]`
+}
+
 func (e *Engine) Repl() *Engine {
 	var game *Game
 	var position *Position
+	var pgn *Pgn
 
 	setup := func() {
 		if game == nil || position == nil {
-			game = NewGame()
+			game = e.NewGame()
 			position = game.start()
+			pgn = (&Pgn{}).SetTag(`Date`, pgnDate())
 			fmt.Printf("%s\n", position)
 		}
 	}
 
 	think := func() {
 		if move := game.Think(); move != 0 {
+			pgn.add(position, move)
 			position = position.makeMove(move)
 			fmt.Printf("%s\n", position)
 		}
 	}
 
+	save := func(fileName string) {
+		setup()
+		pgn.Result = position.result()
+		if err := ioutil.WriteFile(fileName, []byte(pgn.String()), 0644); err != nil {
+			fmt.Printf("Could not save the game to '%s'\n", fileName)
+		} else {
+			fmt.Printf("Saved the game to '%s'\n", fileName)
+		}
+	}
+
+	load := func(fileName string) {
+		file, err := os.Open(fileName)
+		if err != nil {
+			fmt.Printf("Could not open PGN file '%s'\n", fileName)
+			return
+		}
+		defer file.Close()
+
+		loaded, err := NewPgnReader(file).Read()
+		if err != nil {
+			fmt.Printf("Could not load the game from '%s': %v\n", fileName, err)
+			return
+		}
+		game, pgn = e.NewGame(), loaded
+		position = pgn.Walk(game, nil)
+		fmt.Printf("%s\n", position)
+	}
+
 	benchmark := func(fileName string) {
 		maxDepth, moveTime := e.options.maxDepth, e.options.moveTime
 		e.options.maxDepth, e.options.moveTime = 0, 10000
@@ -82,27 +147,41 @@ func (e *Engine) Repl() *Engine {
 		content, err := ioutil.ReadFile(fileName)
 		if err == nil {
 			total, solved := 0, 0
-			re := regexp.MustCompile(`[\+\?!]`)
+			re := regexp.MustCompile(`[\+#\?!]`)
 
-			NextLine:
 			for _, line := range strings.Split(string(content), "\n") {
 				if len(line) > 0 && line[0] != '#' {
 					total++
-					game := NewGame(line)
-					position := game.start()
-
-					best := strings.Split(line, ` # `)[1] // TODO: add support for "am" (avoid move).
-					fmt.Printf(escTeal + "%d) %s for %s" + escNone + "\n%s\n", total, best, C(position.color), position)
-					move := game.Think()
-
-					for _, nextBest := range strings.Split(best, ` `) {
-						if move.str() == re.ReplaceAllLiteralString(nextBest, ``) {
-							solved++
-							fmt.Printf(escGreen + "%d) Solved (%d/%d %2.1f%%)\n\n\n" + escNone, total, solved, total - solved, float32(solved) * 100.0 / float32(total))
-							continue NextLine
+					game := e.NewGame(strings.Split(line, ` # `)[0])
+					position, err := game.setup()
+					if err != nil {
+						fmt.Printf(escRed + "%d) %v\n\n\n" + escNone, total, err)
+						continue
+					}
+
+					// Expected moves are either the list of best moves, or EPD
+					// style operations, ex. "bm Qg6" or "am Qxb2".
+					expected := strings.Split(line, ` # `)[1]
+					epd := &Epd{Best: strings.Fields(re.ReplaceAllLiteralString(expected, ``))}
+					if len(epd.Best) > 0 && (epd.Best[0] == `bm` || epd.Best[0] == `am`) {
+						epd.Best = nil
+						for _, operation := range epdOperations(re.ReplaceAllLiteralString(expected, ``)) {
+							if operation[0] == `bm` {
+								epd.Best = append(epd.Best, operation[1:]...)
+							} else if operation[0] == `am` {
+								epd.Avoid = append(epd.Avoid, operation[1:]...)
+							}
 						}
 					}
-					fmt.Printf(escRed + "%d) Not solved (%d/%d %2.1f%%)\n\n\n" + escNone, total, solved, total - solved, float32(solved) * 100.0 / float32(total))
+					best, avoid := epd.moves(position)
+
+					fmt.Printf(escTeal + "%d) %s for %s" + escNone + "\n%s\n", total, expected, C(position.color), position)
+					if move := game.Think(); solves(move, best, avoid) {
+						solved++
+						fmt.Printf(escGreen + "%d) Solved (%d/%d %2.1f%%)\n\n\n" + escNone, total, solved, total - solved, float32(solved) * 100.0 / float32(total))
+					} else {
+						fmt.Printf(escRed + "%d) Not solved (%d/%d %2.1f%%)\n\n\n" + escNone, total, solved, total - solved, float32(solved) * 100.0 / float32(total))
+					}
 				}
 			}
 		} else {
@@ -110,26 +189,64 @@ func (e *Engine) Repl() *Engine {
 		}
 	}
 
-	perft := func(parameter string) {
-		if parameter == `` {
-			parameter = `5`
+	// Runs perft test or perft divide for the given depth (5 by default)
+	// starting from the given FEN or the initial position.
+	perft := func(parameter string, divide bool) {
+		depth, fields := 5, strings.SplitN(parameter, ` `, 2)
+		if fields[0] != `` {
+			n, err := strconv.Atoi(fields[0])
+			if err != nil || n < 1 {
+				fmt.Printf("Invalid perft depth '%s'\n", fields[0])
+				return
+			}
+			depth = n
+		}
+
+		game := e.NewGame()
+		if len(fields) > 1 {
+			game = e.NewGame(strings.TrimSpace(fields[1]))
+		}
+		position, err := game.setup()
+		if err != nil {
+			fmt.Printf(escRed + "%v\n" + escNone, err)
+			return
 		}
-		if depth, err := strconv.Atoi(parameter); err == nil {
-			position := NewGame().start()
-			start := time.Now()
-			total := position.Perft(depth)
-			finish := since(start)
-			fmt.Printf("  Depth: %d\n", depth)
-			fmt.Printf("  Nodes: %d\n", total)
-			fmt.Printf("Elapsed: %s\n", ms(finish))
-			fmt.Printf("Nodes/s: %dK\n", total / finish)
+
+		var total int64
+		start := time.Now()
+		if divide {
+			moves, counts := position.Divide(depth)
+			for i, move := range moves {
+				fmt.Printf("  %s: %d\n", move.uci(game), counts[i])
+				total += counts[i]
+			}
+			fmt.Printf("  Moves: %d\n", len(moves))
+		} else {
+			total = position.Perft(depth)
 		}
+		finish := since(start)
+		fmt.Printf("  Depth: %d\n", depth)
+		fmt.Printf("  Nodes: %d\n", total)
+		fmt.Printf("Elapsed: %s\n", ms(finish))
+		fmt.Printf("Nodes/s: %dK\n", total / max64(1, finish))
 	}
 
 	fmt.Printf("Donna v%s Copyright (c) 2014 by Michael Dvorkin. All Rights Reserved.\nType ? for help.\n\n", Version)
-	for command, parameter := ``, ``; ; command, parameter = ``, `` {
+	reader := bufio.NewReader(os.Stdin)
+	for {
 		fmt.Print(`donna> `)
-		fmt.Scanln(&command, &parameter)
+		line, err := reader.ReadString('\n')
+		if err != nil && line == `` {
+			return e
+		}
+
+		// The first word is the command and the rest of the line is its
+		// parameter, ex. "perft 4 8/8/8/8/8/8/8/K1k5 w - - 0 1".
+		fields := strings.SplitN(strings.TrimSpace(line), ` `, 2)
+		command, parameter := fields[0], ``
+		if len(fields) > 1 {
+			parameter = strings.TrimSpace(fields[1])
+		}
 
 		switch command {
 		case ``:
@@ -139,41 +256,73 @@ func (e *Engine) Repl() *Engine {
 			return e
 		case `go`:
 			setup()
+			e.options.searchMoves = nil
+			for _, notation := range strings.Split(parameter, `,`) {
+				if move, _ := NewMoveFromString(position, notation); move != 0 {
+					e.options.searchMoves = append(e.options.searchMoves, move)
+				}
+			}
 			think()
+			e.options.searchMoves = nil
+		case `load`:
+			load(parameter)
 		case `help`, `?`:
 			fmt.Println("The commands are:\n\n" +
 				"  bench <file>   Run benchmarks\n" +
+				"  divide [depth] [fen]\n" +
+				"                 Run perft divide showing node counts per move\n" +
 				"  exit           Exit the program\n" +
-				"  go             Take side and make a move\n" +
+				"  go [moves]     Take side and make a move, optionally considering\n" +
+				"                 given moves only, ex. go e4,d4\n" +
 				"  help           Display this help\n" +
+				"  load <file>    Load the game from PGN file\n" +
+				"  multipv [n]    Show or set number of best lines to search\n" +
 				"  new            Start new game\n" +
-				"  perft [depth]  Run perft test\n" +
+				"  perft [depth] [fen]\n" +
+				"                 Run perft test from given or initial position\n" +
+				"  save <file>    Save the game to PGN file\n" +
 				"  score          Show evaluation summary\n" +
 				"  undo           Undo last move\n\n" +
-				"To make a move use algebraic notation, for example e2e4, Ng1f3, or e7e8Q\n")
+				"To make a move use algebraic notation, for example e4, Nf3, O-O, e8=Q,\n" +
+				"or long algebraic notation, for example e2e4, Ng1f3, or e7e8Q\n")
+		case `multipv`:
+			if n, err := strconv.Atoi(parameter); err == nil {
+				e.multiPV = max(1, min(n, MaxMultiPV))
+			}
+			fmt.Printf("MultiPV is set to %d\n", max(1, e.multiPV))
 		case `new`:
 			game, position = nil, nil
 			setup()
 		case `perft`:
-			perft(parameter)
+			perft(parameter, false)
+		case `divide`:
+			perft(parameter, true)
+		case `save`:
+			save(parameter)
 		case `score`:
 			setup()
 			_, metrics := position.EvaluateWithTrace()
 			Summary(metrics)
 		case `undo`:
 			if position != nil {
+				if len(pgn.Moves) > 0 {
+					pgn.Moves = pgn.Moves[:len(pgn.Moves) - 1]
+				}
 				position = position.undoLastMove()
 				fmt.Printf("%s\n", position)
 			}
 		default:
 			setup()
 			if move, validMoves := NewMoveFromString(position, command); move != 0 {
+				pgn.add(position, move)
 				position = position.makeMove(move)
 				think()
 			} else { // Invalid move or non-evasion on check.
-				fancy := e.fancy; e.fancy = false
-				fmt.Printf("%s appears to be an invalid move; valid moves are %v\n", command, validMoves)
-				e.fancy = fancy
+				list := make([]string, 0, len(validMoves))
+				for _, move := range validMoves {
+					list = append(list, move.san(position))
+				}
+				fmt.Printf("%s appears to be an invalid move; valid moves are [%s]\n", command, strings.Join(list, ` `))
 			}
 		}
 	}
@@ -11,6 +11,7 @@ import (
 	`os`
 	`strconv`
 	`strings`
+	`sync`
 )
 
 func (e *Engine) uciScore(depth, score, alpha, beta int) *Engine {
@@ -31,19 +32,44 @@ func (e *Engine) uciScore(depth, score, alpha, beta int) *Engine {
 		str += " lowerbound"
 	}
 
-	return engine.reply(str + "\n")
+	return e.reply(str + "\n")
 }
 
-func (e *Engine) uciMove(move Move, moveno, depth int) *Engine {
-	return engine.reply("info depth %d currmove %s currmovenumber %d\n", depth, move.notation(), moveno)
+func (e *Engine) uciMove(game *Game, move Move, moveno, depth int) *Engine {
+	return e.reply("info depth %d currmove %s currmovenumber %d\n", depth, move.uci(game), moveno)
 }
 
-func (e *Engine) uciBestMove(move Move, duration int64) *Engine {
-	return engine.reply("info nodes %d time %d\nbestmove %s\n", game.nodes + game.qnodes, duration, move.notation())
+// Reports best move along with the expected reply to ponder on, if any.
+func (e *Engine) uciBestMove(game *Game, move Move, duration int64) *Engine {
+	nodes, qnodes := game.nodeCount()
+	if rootpv := game.workers[0].rootpv; len(rootpv) > 1 && rootpv[0] == move {
+		return e.reply("info nodes %d time %d\nbestmove %s ponder %s\n", nodes + qnodes, duration, move.uci(game), rootpv[1].uci(game))
+	}
+	return e.reply("info nodes %d time %d\nbestmove %s\n", nodes + qnodes, duration, move.uci(game))
 }
 
-func (e *Engine) uciPrincipal(depth, score int, duration int64) *Engine {
-	str := fmt.Sprintf("info depth %d score", depth)
+// Reports principal variation or, in MultiPV mode, all the variations ranked
+// by their score.
+func (e *Engine) uciPrincipal(game *Game, depth, score int, duration int64) *Engine {
+	main := game.workers[0]
+	if len(main.variations) < 2 {
+		return e.uciVariation(game, 0, depth, score, main.rootpv, duration)
+	}
+
+	for i, variation := range main.variations {
+		e.uciVariation(game, i + 1, depth, variation.score, variation.moves, duration)
+	}
+	return e
+}
+
+// Reports single variation, multipv being its rank or zero if it's the only
+// variation.
+func (e *Engine) uciVariation(game *Game, multipv, depth, score int, moves []Move, duration int64) *Engine {
+	str := `info`
+	if multipv > 0 {
+		str += fmt.Sprintf(" multipv %d", multipv)
+	}
+	str += fmt.Sprintf(" depth %d score", depth)
 
 	if abs(score) < Checkmate - MaxPly {
 		str += fmt.Sprintf(" cp %d", score * 100 / onePawn)
@@ -54,42 +80,194 @@ func (e *Engine) uciPrincipal(depth, score int, duration int64) *Engine {
 		}
 		str += fmt.Sprintf(" mate %d", mate / 2)
 	}
-	str += fmt.Sprintf(" nodes %d nps %d time %d pv", game.nodes + game.qnodes, nps(duration), duration)
+	nodes, qnodes := game.nodeCount()
+	str += fmt.Sprintf(" nodes %d nps %d time %d pv", nodes + qnodes, nps(nodes + qnodes, duration), duration)
 
-	for i := 0; i < len(game.rootpv); i++ {
-		str += " " + game.rootpv[i].notation()
+	for _, move := range moves {
+		str += " " + move.uci(game)
 	}
 
-	return engine.reply(str + "\n")
+	return e.reply(str + "\n")
+}
+
+// Names of UCI options for evaluation weights matching their index.
+var uciWeights = []string{ `Mobility`, `PawnStructure`, `PassedPawns`, `KingSafety`, `EnemyKingSafety` }
+
+// UCI represents empty string option value as "<empty>".
+func uciString(value string) string {
+	if value == `` {
+		return `<empty>`
+	}
+	return value
+}
+
+// Converts UCI string option value into plain string.
+func uciValue(value string) string {
+	if value == `<empty>` {
+		return ``
+	}
+	return value
 }
 
 // Brain-damaged universal chess interface (UCI) protocol as described at
 // http://wbec-ridderkerk.nl/html/UCIProtocol.html
+//
+// The search runs on its own goroutine so that we keep reading commands while
+// the engine is thinking. Commands that change the game or engine settings
+// stop the search first.
 func (e *Engine) Uci() *Engine {
 	var game *Game
 	var position *Position
+	var searching sync.WaitGroup
 
 	e.uci = true
 
+	// Stops the search, if any, and waits till it's over.
+	finish := func() {
+		e.clock.stop()
+		searching.Wait()
+	}
+
 	// "uci" command handler.
 	doUci := func(args []string) {
 		e.reply("Donna v%s Copyright (c) 2014 by Michael Dvorkin. All Rights Reserved.\n", Version)
 		e.reply("id name Donna %s\n", Version)
 		e.reply("id author Michael Dvorkin\n")
-		// e.reply("option name Hash type spin default %d min 1 max 1024\n", 64)
-		// e.reply("option name Mobility type spin default %d min 0 max 100\n", weights[0].midgame)
-		// e.reply("option name PawnStructure type spin default %d min 0 max 100\n", weights[1].midgame)
-		// e.reply("option name PassedPawns type spin default %d min 0 max 100\n", weights[2].midgame)
-		// e.reply("option name KingSafety type spin default %d min 0 max 100\n", weights[3].midgame)
-		// e.reply("option name EnemyKingSafety type spin default %d min 0 max 100\n", weights[4].midgame)
+		e.reply("option name Hash type spin default %d min 0 max %d\n", int(e.cacheSize), MaxCache)
+		e.reply("option name Clear Hash type button\n")
+		e.reply("option name Threads type spin default %d min 1 max %d\n", max(1, e.threads), MaxThreads)
+		e.reply("option name MultiPV type spin default %d min 1 max %d\n", max(1, e.multiPV), MaxMultiPV)
+		e.reply("option name Ponder type check default false\n")
+		e.reply("option name OwnBook type check default %v\n", e.ownBook)
+		e.reply("option name BookFile type string default %s\n", uciString(e.bookFile))
+		e.reply("option name BookPolicy type combo default %s var TopTwo var Best var Weighted\n", []string{ `TopTwo`, `Best`, `Weighted` }[e.book.policy])
+		e.reply("option name BookMinWeight type spin default %d min 0 max 100\n", e.book.minWeight)
+		e.reply("option name BookDepth type spin default %d min 0 max 100\n", e.book.depth)
+		e.reply("option name BookMisses type spin default %d min 0 max 100\n", e.book.misses)
+		e.reply("option name BookLearn type check default %v\n", e.book.learn)
+		e.reply("option name LogFile type string default %s\n", uciString(e.logFile))
+		e.reply("option name ParamsFile type string default %s\n", uciString(e.paramsFile))
+		e.reply("option name EvalFile type string default %s\n", uciString(e.evalFile))
+		e.reply("option name UseNetwork type check default %v\n", e.useNetwork)
+		e.reply("option name SyzygyPath type string default %s\n", uciString(e.syzygyPath))
+		e.reply("option name SyzygyProbeDepth type spin default %d min 1 max 100\n", e.syzygyDepth)
+		e.reply("option name UCI_Chess960 type check default %v\n", e.chess960)
+		for i, name := range uciWeights {
+			e.reply("option name %s type spin default %d min 0 max 200\n", name, e.weights[i].midgame)
+		}
 		e.reply("uciok\n")
 	}
 
 	// "ucinewgame" command handler.
 	doUciNewGame := func(args []string) {
+		finish()
+		if game != nil && position != nil { // Learn from the previous game.
+			game.learn(position.result())
+		}
 		game, position = nil, nil
 	}
 
+	// "setoption name <id> [value <x>]" command handler. Both option name and
+	// its value might contain spaces, ex. "setoption name Clear Hash".
+	doSetOption := func(args []string) {
+		if len(args) < 2 || args[0] != `name` {
+			return
+		}
+		finish()
+
+		name, value := ``, ``
+		for i, token := range args[1:] {
+			if token == `value` {
+				name = strings.Join(args[1:i+1], ` `)
+				value = strings.Join(args[i+2:], ` `)
+				break
+			}
+		}
+		if name == `` {
+			name = strings.Join(args[1:], ` `)
+		}
+
+		switch name = strings.ToLower(name); name {
+		case `hash`:
+			if n, err := strconv.Atoi(value); err == nil {
+				e.cacheSize = float64(max(0, min(n, MaxCache)))
+				if game != nil {
+					game.cache = NewCache(e.cacheSize)
+				}
+			}
+		case `clear hash`:
+			if game != nil {
+				game.cache.clear()
+			}
+		case `threads`:
+			if n, err := strconv.Atoi(value); err == nil {
+				e.threads = max(1, min(n, MaxThreads))
+			}
+		case `multipv`:
+			if n, err := strconv.Atoi(value); err == nil {
+				e.multiPV = max(1, min(n, MaxMultiPV))
+			}
+		case `ponder`:
+			// Nothing to do: the GUI decides when to ponder.
+		case `ownbook`:
+			e.ownBook = (value == `true`)
+		case `bookfile`:
+			e.bookFile = uciValue(value)
+		case `bookpolicy`:
+			if policy, ok := bookPolicies[strings.ToLower(value)]; ok {
+				e.book.policy = policy
+			}
+		case `bookminweight`:
+			if n, err := strconv.Atoi(value); err == nil {
+				e.book.minWeight = max(0, min(n, 100))
+			}
+		case `bookdepth`:
+			if n, err := strconv.Atoi(value); err == nil {
+				e.book.depth = max(0, min(n, 100))
+			}
+		case `bookmisses`:
+			if n, err := strconv.Atoi(value); err == nil {
+				e.book.misses = max(0, min(n, 100))
+			}
+		case `booklearn`:
+			e.book.learn = (value == `true`)
+		case `logfile`:
+			e.logFile = uciValue(value)
+		case `evalfile`, `usenetwork`:
+			if name == `usenetwork` {
+				e.useNetwork = (value == `true`)
+			} else if err := e.loadNetwork(uciValue(value)); err != nil {
+				e.reply("info string %s\n", err.Error())
+			}
+			if e.pickEvaluator(); position != nil { // Accumulators depend on the network.
+				position.setupAccumulator()
+			}
+		case `paramsfile`:
+			if err := e.LoadParams(uciValue(value)); err != nil {
+				e.reply("info string %s\n", err.Error())
+			}
+		case `syzygypath`:
+			e.syzygyPath = uciValue(value)
+			e.tablebase = NewTablebase(e.syzygyPath)
+		case `syzygyprobedepth`:
+			if n, err := strconv.Atoi(value); err == nil {
+				e.syzygyDepth = max(1, min(n, 100))
+			}
+		case `uci_chess960`:
+			e.chess960 = (value == `true`)
+		default:
+			for i, weight := range uciWeights {
+				if name == strings.ToLower(weight) {
+					if n, err := strconv.Atoi(value); err == nil {
+						n = max(0, min(n, 200))
+						e.weights[i] = Score{n, n}
+						e.pawnToken++ // Cached pawn scores have old weights applied.
+					}
+				}
+			}
+		}
+	}
+
 	// "isready" command handler.
 	doIsReady := func(args []string) {
 		e.reply("readyok\n")
@@ -97,9 +275,14 @@ func (e *Engine) Uci() *Engine {
 
 	// "position [startpos | fen ] [ moves ... ]" command handler.
 	doPosition := func(args []string) {
+		if len(args) == 0 {
+			return
+		}
+		finish()
+
 		// Make sure we've started the game since "ucinewgame" is optional.
 		if game == nil || position == nil {
-			game = NewGame()
+			game = e.NewGame()
 		}
 
 		switch args[0] {
@@ -116,30 +299,50 @@ func (e *Engine) Uci() *Engine {
 				fen = append(fen, token)
 			}
 			game.initial = strings.Join(fen, ` `)
-			position = game.start()
+			var err error
+			if position, err = game.setup(); err != nil {
+				e.reply("info string %s\n", err.Error())
+			}
 		default:
 			return
 		}
 
 		if position != nil && len(args) > 0 && args[0] == `moves` {
-			for _, move := range args[1:] {
+			for _, notation := range args[1:] {
 				args = args[1:] // Shift the move.
-				position = position.makeMove(NewMoveFromNotation(position, move))
+
+				// Note that the game tree keeps growing so we can't
+				// use move generator of the current ply.
+				move := Move(0)
+				if isNotation(notation) {
+					move = NewMoveFromNotation(position, notation)
+				}
+				if move == Move(0) || !NewGen(position, MaxPly).generateAllMoves().validOnly().amongValid(move) {
+					e.reply("info string invalid move %s\n", notation)
+					break
+				}
+				position = position.makeMove(move)
 			}
 		}
 	}
 
-	// "go [[wtime winc | btime binc ] movestogo] | depth | nodes | movetime"
+	// "go [[wtime winc | btime binc ] movestogo] | depth | nodes | movetime |
+	// searchmoves ..."
 	doGo := func(args []string) {
-		think := true
-		options := e.options
+		if position == nil {
+			return
+		}
+		finish()
+
+		think, ponder := true, false
+		options, searchMoves := e.options, []Move{}
 
 		for i, token := range args {
 			// Boolen "infinite" and "ponder" commands have no arguments.
 			if token == `infinite` {
 				options = Options{infinite: true}
 			} else if token == `ponder` {
-				options = Options{ponder: true}
+				ponder = true
 			} else if token == `test` { // <-- Custom token for use in tests.
 				think = false
 			} else if len(args) > i+1 {
@@ -184,9 +387,24 @@ func (e *Engine) Uci() *Engine {
 					if n, err := strconv.Atoi(args[i+1]); err == nil {
 						options.movesToGo = int64(n)
 					}
+				case `searchmoves`: // Moves follow till the next token.
+					for _, notation := range args[i+1:] {
+						if !isNotation(notation) {
+							break
+						}
+						// Illegal moves are ignored, and if none of the moves
+						// is legal the search falls back to all the moves.
+						move := NewMoveFromNotation(position, notation)
+						if move != Move(0) && NewGen(position, MaxPly).generateAllMoves().validOnly().amongValid(move) {
+							searchMoves = append(searchMoves, move)
+						} else {
+							e.reply("info string invalid move %s\n", notation)
+						}
+					}
 				}
 			}
 		}
+		options.ponder, options.searchMoves = ponder, searchMoves
 		if options.timeLeft != 0 || options.timeInc != 0 || options.movesToGo != 0 {
 			e.varyingLimits(options)
 		} else {
@@ -194,39 +412,63 @@ func (e *Engine) Uci() *Engine {
 		}
 
 		// Start "thinking" and come up with best move unless when running
-		// tests where we verify argument parsing only.
+		// tests where we verify argument parsing only. The clock gets reset
+		// before the search goroutine starts so that "stop" that follows
+		// right away is not lost.
 		if think {
-			game.Think()
+			e.clock.reset()
+			searching.Add(1)
+			go func() {
+				defer searching.Done()
+				game.think()
+			}()
 		}
 	}
 
+	// Opponent has played expected move: keep searching but now with time
+	// controls set by the "go ponder" command.
+	doPonderHit := func(args []string) {
+		e.ponderHit()
+	}
+
 	// Stop calculating as soon as possible.
 	doStop := func(args []string) {
-		e.clock.halt = true
+		e.clock.stop()
 	}
 
 	var commands = map[string]func([]string){
 		`isready`:    doIsReady,
 		`uci`:        doUci,
 		`ucinewgame`: doUciNewGame,
+		`setoption`:  doSetOption,
 		`position`:   doPosition,
 		`go`:         doGo,
+		`ponderhit`:  doPonderHit,
 		`stop`:       doStop,
 	}
 
 	bio := bufio.NewReader(os.Stdin)
 	for {
 		command, err := bio.ReadString('\n')
-		if err != io.EOF && len(command) > 0 {
+		if len(command) > 0 {
 			e.debug("> " + command)
-			args := strings.Split(strings.Trim(command, " \t\r\n"), ` `)
-			if args[0] == `quit` {
+			args := strings.Fields(command)
+			if len(args) > 0 && args[0] == `quit` {
 				break
 			}
-			if handler, ok := commands[args[0]]; ok {
-				handler(args[1:])
+			if len(args) > 0 {
+				if handler, ok := commands[args[0]]; ok {
+					handler(args[1:])
+				}
 			}
 		}
+		if err == io.EOF {
+			break // Treat closed input as "quit".
+		}
 	}
+
+	// Make sure the search is over before we quit.
+	finish()
+
 	return e
 }
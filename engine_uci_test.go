@@ -8,6 +8,7 @@ import (
 	`github.com/michaeldv/donna/expect`
 	`io/ioutil`
 	`os`
+	`path/filepath`
 	`syscall`
 	`testing`
 )
@@ -39,9 +40,10 @@ func mockStdin(input string) (string, error) {
 	return mock, nil
 }
 
-// Resores os.Stdin and removes input mock file.
+// Removes input mock file. Note that os.Stdin stays as is: if we replaced it
+// with new *os.File the old one would get garbage collected eventually, and
+// its finalizer would close fd=0 from under the next test.
 func unmockStdin(mock string) {
-	os.Stdin = os.NewFile(uintptr(syscall.Stdin), `/dev/stdin`)
 	if mock != `` {
 		os.Remove(mock)
 	}
@@ -54,7 +56,6 @@ func TestUci000(t *testing.T) {
 		t.Errorf(err.Error())
 	} else {
 		defer unmockStdin(mock)
-		defer NewEngine()
 
 		engine := NewEngine().Uci()
 		expect.Eq(t, engine.options.moveTime, int64(12345))
@@ -70,7 +71,6 @@ func TestUci010(t *testing.T) {
 		t.Errorf(err.Error())
 	} else {
 		defer unmockStdin(mock)
-		defer NewEngine()
 
 		engine := NewEngine().Uci()
 		expect.Eq(t, engine.options.timeLeft, int64(12345))
@@ -87,7 +87,6 @@ func TestUci020(t *testing.T) {
 		t.Errorf(err.Error())
 	} else {
 		defer unmockStdin(mock)
-		defer NewEngine()
 
 		engine := NewEngine().Uci()
 		expect.Eq(t, engine.options.timeLeft, int64(98765))
@@ -96,3 +95,299 @@ func TestUci020(t *testing.T) {
 		expect.Eq(t, engine.options.movesToGo, int64(42))
 	}
 }
+
+func TestUci030(t *testing.T) {
+	mock, err := mockStdin("setoption name Threads value 4\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.threads, 4)
+	}
+}
+
+func TestUci040(t *testing.T) {
+	mock, err := mockStdin("setoption name Hash value 32\nsetoption name Clear Hash\nsetoption name OwnBook value false\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine(`cache`, 64, `bookfile`, `book.bin`).Uci()
+		expect.Eq(t, engine.cacheSize, 32.0)
+		expect.Eq(t, engine.ownBook, false)
+		expect.Eq(t, engine.bookFile, `book.bin`)
+	}
+}
+
+func TestUci050(t *testing.T) {
+	mock, err := mockStdin("setoption name BookFile value /tmp/my book.bin\nsetoption name LogFile value <empty>\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		logFile := filepath.Join(os.TempDir(), `donna.log`)
+		defer os.Remove(logFile)
+
+		engine := NewEngine(`logfile`, logFile).Uci()
+		expect.Eq(t, engine.bookFile, `/tmp/my book.bin`)
+		expect.Eq(t, engine.logFile, ``)
+	}
+}
+
+func TestUci060(t *testing.T) {
+	mock, err := mockStdin("setoption name Mobility value 50\nsetoption name EnemyKingSafety value 120\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.weights[0], Score{50, 50})
+		expect.Eq(t, engine.weights[4], Score{120, 120})
+		expect.Eq(t, weights[0], Score{100, 100}) // Defaults stay intact.
+	}
+}
+
+func TestUci070(t *testing.T) {
+	mock, err := mockStdin("position startpos\ngo test ponder wtime 12345 btime 98765\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.True(t, engine.options.ponder)
+		expect.Eq(t, engine.options.timeLeft, int64(12345))
+	}
+}
+
+func TestUci080(t *testing.T) {
+	mock, err := mockStdin("position startpos\ngo ponder movetime 100\nponderhit\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.False(t, engine.options.ponder)
+		expect.Eq(t, engine.options.moveTime, int64(100))
+	}
+}
+
+func TestUci090(t *testing.T) {
+	mock, err := mockStdin("position startpos\ngo test nodes 12345\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.options.maxNodes, 12345)
+		expect.False(t, engine.options.infinite)
+	}
+}
+
+func TestUci100(t *testing.T) {
+	mock, err := mockStdin("position startpos\ngo infinite\nstop\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.True(t, engine.options.infinite)
+		expect.True(t, engine.clock.stopped())
+	}
+}
+
+func TestUci110(t *testing.T) {
+	mock, err := mockStdin("position startpos\ngo depth 40\nisready\nstop\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.options.maxDepth, 40)
+		expect.True(t, engine.clock.stopped())
+	}
+}
+
+func TestUci120(t *testing.T) {
+	mock, err := mockStdin("setoption name MultiPV value 3\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.multiPV, 3)
+	}
+}
+
+func TestUci130(t *testing.T) {
+	mock, err := mockStdin("position startpos\ngo test searchmoves e2e4 g1f3 depth 5\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.options.searchMoves, `[e2-e4 Ng1-f3]`)
+		expect.Eq(t, engine.options.maxDepth, 5)
+	}
+}
+
+func TestUci140(t *testing.T) {
+	mock, err := mockStdin("setoption name UCI_Chess960 value true\nposition fen 1r3k1r/8/8/8/8/8/8/1R3K1R w HBhb - 0 1 moves f1h1\ngo test depth 1\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.True(t, engine.chess960)
+	}
+}
+
+// Invalid position gets reported and ignored.
+func TestUci150(t *testing.T) {
+	mock, err := mockStdin("position fen 4k3/8/8/8/8/8/8/8 w - - 0 1 moves e8e7\ngo test depth 5\nquit\n")
+
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.options.maxDepth, 0)
+	}
+}
+
+func TestUci160(t *testing.T) {
+	file, _ := ioutil.TempFile(``, `donna`)
+	defer os.Remove(file.Name())
+	file.WriteString("valuePawn.endgame = 150\n")
+	file.Close()
+
+	mock, err := mockStdin("setoption name ParamsFile value " + file.Name() + "\nquit\n")
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.paramsFile, file.Name())
+		expect.Eq(t, engine.params.valuePawn, Score{100, 150})
+		expect.Eq(t, NewEngine().params.valuePawn, Score{100, 129})
+	}
+}
+
+func TestUci170(t *testing.T) {
+	mock, err := mockStdin("position startpos\nsetoption name EvalFile value testdata/material.nnue\nsetoption name UseNetwork value true\nquit\n")
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.evalFile, `testdata/material.nnue`)
+		expect.Eq(t, engine.evaluator, engine.network)
+	}
+}
+
+func TestUci180(t *testing.T) {
+	mock, err := mockStdin("setoption name BookPolicy value Weighted\nsetoption name BookMinWeight value 10\nsetoption name BookDepth value 8\nsetoption name BookMisses value 3\nsetoption name BookLearn value true\nquit\n")
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.book, bookOptions{policy: BookWeighted, minWeight: 10, depth: 8, misses: 3, learn: true})
+	}
+}
+
+// Moves after the invalid one are ignored.
+func TestUci190(t *testing.T) {
+	mock, err := mockStdin("position startpos moves e2e4 zz99 e7e5\ngo test wtime 12345 btime 98765\nquit\n")
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.options.timeLeft, int64(98765))
+	}
+}
+
+func TestUci200(t *testing.T) {
+	mock, err := mockStdin("position startpos moves e2e5\ngo test wtime 12345 btime 98765\nquit\n")
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.options.timeLeft, int64(12345))
+	}
+}
+
+// Illegal search moves are ignored.
+func TestUci210(t *testing.T) {
+	mock, err := mockStdin("position startpos\ngo test searchmoves e2e5 e2e4 a1a8 depth 5\nquit\n")
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, engine.options.searchMoves, `[e2-e4]`)
+		expect.Eq(t, engine.options.maxDepth, 5)
+	}
+}
+
+// Search falls back to all the moves if none of the search moves is legal.
+func TestUci220(t *testing.T) {
+	mock, err := mockStdin("position startpos\ngo test searchmoves e7e5 h1h3 depth 5\nquit\n")
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine().Uci()
+		expect.Eq(t, len(engine.options.searchMoves), 0)
+		expect.Eq(t, engine.options.maxDepth, 5)
+	}
+}
+
+// Zero hash size turns the cache off, and tables are never probed below depth 1.
+func TestUci230(t *testing.T) {
+	mock, err := mockStdin("setoption name Hash value 0\nsetoption name SyzygyProbeDepth value 0\nquit\n")
+	if err != nil {
+		t.Error(err)
+	} else {
+		defer unmockStdin(mock)
+
+		engine := NewEngine(`cache`, 16).Uci()
+		expect.Eq(t, engine.cacheSize, 0.0)
+		expect.Eq(t, engine.syzygyDepth, 1)
+		expect.Eq(t, NewEngine(`syzygydepth`, 0).syzygyDepth, 1)
+	}
+}
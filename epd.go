@@ -0,0 +1,273 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`encoding/csv`
+	`encoding/json`
+	`fmt`
+	`io`
+	`os`
+	`path/filepath`
+	`strconv`
+	`strings`
+	`time`
+)
+
+// Extended position description (EPD) record: four FEN fields followed by
+// opcodes, ex. `2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";`
+type Epd struct {
+	Fen         string 	// Position in FEN notation.
+	Best        []string 	// Best moves (bm) in standard algebraic notation.
+	Avoid       []string 	// Moves to avoid (am) in standard algebraic notation.
+	Id          string 	// Position identifier (id).
+	Comment     string 	// Comment (c0).
+}
+
+// Result of searching single EPD position. If the position is solved the
+// depth and time (in milliseconds) are those of the iteration that found the
+// solution and stuck with it; otherwise they are the totals.
+type EpdResult struct {
+	Id          string 	`json:"id"`
+	Fen         string 	`json:"fen"`
+	Best        []string 	`json:"bm,omitempty"`
+	Avoid       []string 	`json:"am,omitempty"`
+	Move        string 	`json:"move"`
+	Solved      bool 	`json:"solved"`
+	Depth       int 	`json:"depth"`
+	Time        int64 	`json:"time"`
+	Nodes       int 	`json:"nodes"`
+	Score       int 	`json:"score"`
+	Error       string 	`json:"error,omitempty"`
+}
+
+// Parses single EPD line. Half-move clock (hmvc) and full move number (fmvn)
+// opcodes, if any, are folded into the FEN.
+func NewEpd(line string) (*Epd, error) {
+	fields := strings.Fields(line)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf(`epd: expected at least 4 fields in "%s"`, line)
+	}
+
+	// Some EPD files are FENs in disguise, ex. opening books: take half-move
+	// clock and full move number if they follow the four FEN fields.
+	epd, halfmove, fullmove, operations := &Epd{}, `0`, `1`, fields[4:]
+	if len(operations) >= 2 && reFenNumber.MatchString(operations[0]) && reFenNumber.MatchString(operations[1]) {
+		halfmove, fullmove, operations = operations[0], operations[1], operations[2:]
+	}
+	for _, operation := range epdOperations(strings.Join(operations, ` `)) {
+		opcode, operands := operation[0], operation[1:]
+		switch opcode {
+		case `bm`:
+			epd.Best = append(epd.Best, operands...)
+		case `am`:
+			epd.Avoid = append(epd.Avoid, operands...)
+		case `id`:
+			epd.Id = strings.Join(operands, ` `)
+		case `c0`:
+			epd.Comment = strings.Join(operands, ` `)
+		case `hmvc`:
+			if len(operands) > 0 {
+				halfmove = operands[0]
+			}
+		case `fmvn`:
+			if len(operands) > 0 {
+				fullmove = operands[0]
+			}
+		}
+	}
+	epd.Fen = strings.Join(append(fields[:4:4], halfmove, fullmove), ` `)
+
+	if err := checkFEN(epd.Fen); err != nil {
+		return nil, err
+	}
+	return epd, nil
+}
+
+// Reads EPD records skipping empty lines and lines starting with #.
+func ReadEpd(r io.Reader) (epds []*Epd, err error) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == `` || line[0] == '#' {
+			continue
+		}
+		epd, err := NewEpd(line)
+		if err != nil {
+			return epds, err
+		}
+		epds = append(epds, epd)
+	}
+	return epds, scanner.Err()
+}
+
+// Splits EPD operations into opcodes and their operands. Operations are
+// separated by semicolons; quoted operands might contain spaces and
+// semicolons.
+func epdOperations(str string) (operations [][]string) {
+	var tokens []string
+	var token []byte
+	quoted := false
+
+	flush := func() {
+		if len(token) > 0 {
+			tokens, token = append(tokens, string(token)), token[:0]
+		}
+	}
+
+	for i := 0; i < len(str); i++ {
+		switch char := str[i]; {
+		case char == '"':
+			if quoted {
+				tokens, token = append(tokens, string(token)), token[:0]
+			}
+			quoted = !quoted
+		case quoted:
+			token = append(token, char)
+		case char == ' ' || char == '\t':
+			flush()
+		case char == ';':
+			flush()
+			if len(tokens) > 0 {
+				operations, tokens = append(operations, tokens), nil
+			}
+		default:
+			token = append(token, char)
+		}
+	}
+	flush()
+	if len(tokens) > 0 {
+		operations = append(operations, tokens)
+	}
+
+	return
+}
+
+// Converts best moves and moves to avoid into actual moves in the given
+// position. Moves could be in standard or long algebraic notation; invalid
+// ones are ignored.
+func (epd *Epd) moves(p *Position) (best, avoid []Move) {
+	parse := func(list []string) (moves []Move) {
+		for _, notation := range list {
+			if move, _ := NewMoveFromString(p, notation); move != Move(0) {
+				moves = append(moves, move)
+			}
+		}
+		return
+	}
+	return parse(epd.Best), parse(epd.Avoid)
+}
+
+// Returns true if the move is one of the best moves and none of the moves to
+// avoid.
+func solves(move Move, best, avoid []Move) bool {
+	if move == Move(0) || len(best) + len(avoid) == 0 {
+		return false
+	}
+	found := len(best) == 0
+	for _, m := range best {
+		found = found || m == move
+	}
+	for _, m := range avoid {
+		found = found && m != move
+	}
+	return found
+}
+
+// Searches EPD positions one by one for the given time (in milliseconds) or to
+// the given depth, and returns the results. Optional callback gets invoked
+// right after each position is done.
+func (e *Engine) Suite(epds []*Epd, depth, moveTime int, callback func(*EpdResult)) (results []*EpdResult) {
+	options, ownBook, quiet := e.options, e.ownBook, e.quiet
+	defer func() {
+		e.options, e.ownBook, e.quiet = options, ownBook, quiet
+	}()
+	e.options, e.ownBook, e.quiet = Options{maxDepth: depth, moveTime: int64(moveTime)}, false, true
+
+	for _, epd := range epds {
+		result := &EpdResult{Id: epd.Id, Fen: epd.Fen, Best: epd.Best, Avoid: epd.Avoid}
+
+		game := e.NewGame(epd.Fen)
+		position, err := game.setup()
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			// Note that the callback is invoked in the middle of the search
+			// so we can't generate moves there.
+			best, avoid := epd.moves(position)
+			game.progress = func(depth, score int, move Move, duration int64) {
+				if !solves(move, best, avoid) {
+					result.Solved, result.Depth = false, depth
+				} else if !result.Solved {
+					result.Solved, result.Depth, result.Time = true, depth, duration
+				}
+				result.Score = score * 100 / onePawn
+			}
+
+			start := time.Now()
+			move := game.Think()
+			nodes, qnodes := game.nodeCount()
+			result.Nodes = nodes + qnodes
+			if !result.Solved {
+				result.Time = since(start)
+			}
+			if move != Move(0) {
+				result.Move = move.san(position)
+			}
+		}
+
+		results = append(results, result)
+		if callback != nil {
+			callback(result)
+		}
+	}
+
+	return
+}
+
+// Writes EPD suite results as JSON or CSV depending on the file extension.
+func WriteEpdReport(fileName string, results []*EpdResult) error {
+	file, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(fileName)) == `.csv` {
+		return writeEpdCsv(file, results)
+	}
+	return writeEpdJson(file, results)
+}
+
+func writeEpdJson(w io.Writer, results []*EpdResult) error {
+	data, err := json.MarshalIndent(results, ``, `  `)
+	if err == nil {
+		_, err = w.Write(append(data, '\n'))
+	}
+	return err
+}
+
+func writeEpdCsv(w io.Writer, results []*EpdResult) error {
+	writer := csv.NewWriter(w)
+	writer.Write([]string{ `id`, `fen`, `bm`, `am`, `move`, `solved`, `depth`, `time`, `nodes`, `score`, `error` })
+	for _, result := range results {
+		writer.Write([]string{
+			result.Id,
+			result.Fen,
+			strings.Join(result.Best, ` `),
+			strings.Join(result.Avoid, ` `),
+			result.Move,
+			strconv.FormatBool(result.Solved),
+			strconv.Itoa(result.Depth),
+			strconv.FormatInt(result.Time, 10),
+			strconv.Itoa(result.Nodes),
+			strconv.Itoa(result.Score),
+			result.Error,
+		})
+	}
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `bytes`; `strings`; `testing`)
+
+func TestEpd000(t *testing.T) {
+	epd, err := NewEpd(`2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - bm Qg6; id "WAC.001";`)
+	expect.True(t, err == nil)
+	expect.Eq(t, epd.Fen, `2rr3k/pp3pp1/1nnqbN1p/3pN3/2pP4/2P3Q1/PPB4P/R4RK1 w - - 0 1`)
+	expect.Eq(t, epd.Best, []string{ `Qg6` })
+	expect.Eq(t, len(epd.Avoid), 0)
+	expect.Eq(t, epd.Id, `WAC.001`)
+}
+
+func TestEpd010(t *testing.T) {
+	epd, err := NewEpd(`r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - am Nxc6 Be2; bm Qd2 Nb5; c0 "Quoted; operand"; hmvc 3; fmvn 7; id "test 1";`)
+	expect.True(t, err == nil)
+	expect.Eq(t, epd.Fen, `r1b1k2r/ppppnppp/2n2q2/2b5/3NP3/2P1B3/PP3PPP/RN1QKB1R w KQkq - 3 7`)
+	expect.Eq(t, epd.Best, []string{ `Qd2`, `Nb5` })
+	expect.Eq(t, epd.Avoid, []string{ `Nxc6`, `Be2` })
+	expect.Eq(t, epd.Comment, `Quoted; operand`)
+	expect.Eq(t, epd.Id, `test 1`)
+}
+
+// Full FEN with half-move clock and full move number.
+func TestEpd011(t *testing.T) {
+	epd, err := NewEpd(`rnbqkb1r/1p3ppp/p2ppn2/8/3NP3/2N1BP2/PPP3PP/R2QKB1R b KQkq - 0 7`)
+	expect.True(t, err == nil)
+	expect.Eq(t, epd.Fen, `rnbqkb1r/1p3ppp/p2ppn2/8/3NP3/2N1BP2/PPP3PP/R2QKB1R b KQkq - 0 7`)
+
+	epd, _ = NewEpd(`6k1/5ppp/8/8/8/8/8/R5K1 w - - 4 30 bm Ra8#; id "mate";`)
+	expect.Eq(t, epd.Fen, `6k1/5ppp/8/8/8/8/8/R5K1 w - - 4 30`)
+	expect.Eq(t, epd.Best, []string{ `Ra8#` })
+	expect.Eq(t, epd.Id, `mate`)
+}
+
+func TestEpd020(t *testing.T) {
+	_, err := NewEpd(`2rr3k/pp3pp1 w - -`)
+	expect.Ne(t, err, nil)
+	_, err = NewEpd(`bm Qg6;`)
+	expect.Ne(t, err, nil)
+}
+
+func TestEpd030(t *testing.T) {
+	epds, err := ReadEpd(strings.NewReader("# Comment.\n\n6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Ra8#; id \"1\";\n6k1/5ppp/8/8/8/8/8/R5K1 w - - am Ra7; id \"2\";\n"))
+	expect.True(t, err == nil)
+	expect.Eq(t, len(epds), 2)
+	expect.Eq(t, epds[1].Id, `2`)
+}
+
+// Best moves and moves to avoid.
+func TestEpd100(t *testing.T) {
+	epd, _ := NewEpd(`6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Ra8# a1a7;`)
+	p := NewGame(epd.Fen).start()
+	best, avoid := epd.moves(p)
+	expect.Eq(t, best, []Move{ NewMove(p, A1, A8), NewMove(p, A1, A7) })
+	expect.True(t, solves(NewMove(p, A1, A8), best, avoid))
+	expect.False(t, solves(NewMove(p, A1, A2), best, avoid))
+}
+
+func TestEpd110(t *testing.T) {
+	epd, _ := NewEpd(`6k1/5ppp/8/8/8/8/8/R5K1 w - - am Ra7;`)
+	p := NewGame(epd.Fen).start()
+	best, avoid := epd.moves(p)
+	expect.False(t, solves(NewMove(p, A1, A7), best, avoid))
+	expect.True(t, solves(NewMove(p, A1, A8), best, avoid))
+}
+
+// Running the suite.
+func TestEpd200(t *testing.T) {
+	epds, _ := ReadEpd(strings.NewReader("6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Ra8#; id \"mate\";\n6k1/5ppp/8/8/8/8/8/R5K1 w - - bm Kf1; id \"miss\";\n6k1/5ppp/8/8/8/8/8/R5KP w - - bm h2; id \"invalid\";\n"))
+	engine := NewEngine()
+	results := engine.Suite(epds, 2, 0, nil)
+	expect.Eq(t, len(results), 3)
+
+	expect.True(t, results[0].Solved)
+	expect.Eq(t, results[0].Move, `Ra8#`)
+	expect.Eq(t, results[0].Depth, 1)
+	expect.True(t, results[0].Nodes > 0)
+
+	expect.False(t, results[1].Solved)
+	expect.Eq(t, results[1].Move, `Ra8#`)
+
+	expect.False(t, results[2].Solved)
+	expect.Eq(t, results[2].Error, `position: pawn on h1`)
+
+	expect.False(t, engine.quiet)
+	expect.Eq(t, engine.options.maxDepth, 0)
+}
+
+// Report formats.
+func TestEpd300(t *testing.T) {
+	results := []*EpdResult{ &EpdResult{Id: `1`, Fen: `8/8/8/8/8/8/8/K6k w - - 0 1`, Best: []string{ `Kb2`, `Kb1` }, Move: `Kb2`, Solved: true, Depth: 3, Time: 10, Nodes: 42} }
+
+	var buffer bytes.Buffer
+	writeEpdCsv(&buffer, results)
+	expect.Eq(t, buffer.String(), "id,fen,bm,am,move,solved,depth,time,nodes,score,error\n1,8/8/8/8/8/8/8/K6k w - - 0 1,Kb2 Kb1,,Kb2,true,3,10,42,0,\n")
+
+	buffer.Reset()
+	writeEpdJson(&buffer, results)
+	expect.True(t, strings.Contains(buffer.String(), `"bm": [`))
+	expect.True(t, strings.Contains(buffer.String(), `"solved": true`))
+	expect.False(t, strings.Contains(buffer.String(), `"am"`))
+}
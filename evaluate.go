@@ -34,7 +34,6 @@ type Total struct {
 
 type Function func(*Evaluation) int
 type MaterialEntry struct {
-	score     Score 	// Score adjustment for the given material.
 	endgame   Function 	// Function to analyze an endgame position.
 	phase     int 		// Game phase based on available material.
 	turf      int 		// Home turf score for the game opening.
@@ -48,57 +47,73 @@ type Evaluation struct {
 	pawns     *PawnEntry 	 // Pointer to the pawn cache entry.
 	material  *MaterialEntry // Pointer to the matrial base entry.
 	position  *Position 	 // Pointer to the position we're evaluating.
+	params    *Params 	 // Evaluation parameters set by the engine.
+	weights   []Score 	 // Weight percentages set by the engine.
 	metrics   Metrics 	 // Evaluation metrics when tracking is on.
 }
 
-// Use single statically allocated variable to avoid garbage collection overhead.
-var eval Evaluation
+// Pluggable evaluation backend that returns the score of the position from the
+// side to move point of view.
+type Evaluator interface {
+	Evaluate(p *Position) int
+}
+
+// Classical hand-crafted evaluation.
+type Classical struct{}
 
-// Main position evaluation method that returns single blended score.
+// Main position evaluation method that returns the score computed by engine's
+// evaluation backend.
 func (p *Position) Evaluate() int {
-	return eval.init(p).run()
+	return p.worker.game.engine.evaluator.Evaluate(p)
+}
+
+// Returns single blended score. Each search worker has its own statically
+// allocated evaluation to avoid garbage collection overhead.
+func (Classical) Evaluate(p *Position) int {
+	return p.worker.eval.init(p).run()
 }
 
 // Auxiliary evaluation method that captures individual evaluation metrics. This
 // is useful when we want to see evaluation summary.
 func (p *Position) EvaluateWithTrace() (int, Metrics) {
+	eval := &p.worker.eval
 	eval.init(p)
 	eval.metrics = make(Metrics)
 
-	engine.trace = true
 	defer func() {
 		var tempo Total
 		var final Score
 
 		if p.color == White {
-			tempo.white.add(rightToMove)
+			tempo.white.add(eval.params.rightToMove)
 			final.add(eval.score)
 		} else {
-			tempo.black.add(rightToMove)
+			tempo.black.add(eval.params.rightToMove)
 			final.subtract(eval.score)
 		}
 
 		eval.checkpoint(`Phase`, eval.material.phase)
-		eval.checkpoint(`Imbalance`, eval.material.score)
+		eval.checkpoint(`Imbalance`, eval.params.imbalance[p.balance])
 		eval.checkpoint(`PST`, p.tally)
 		eval.checkpoint(`Tempo`, tempo)
 		eval.checkpoint(`Final`, final)
-		engine.trace = false
 	}()
 
 	return eval.run(), eval.metrics
 }
 
 func (e *Evaluation) init(p *Position) *Evaluation {
-	eval = Evaluation{}
+	*e = Evaluation{}
 	e.position = p
+	e.params = p.params()
+	e.weights = p.worker.game.engine.weights
 
 	// Initialize the score with incremental PST value and right to move.
 	e.score = p.tally
 	if p.color == White {
-		e.score.add(rightToMove)
+		e.score.add(e.params.rightToMove)
 	} else {
-		e.score.subtract(rightToMove)
+		e.score.subtract(e.params.rightToMove)
 	}
 
 	// Set up king and pawn attacks for both sides.
@@ -117,7 +132,7 @@ func (e *Evaluation) init(p *Position) *Evaluation {
 func (e *Evaluation) run() int {
 	e.material = &materialBase[e.position.balance]
 
-	e.score.add(e.material.score)
+	e.score.add(e.params.imbalance[e.position.balance])
 	if e.material.flags & knownEndgame != 0 {
 		return e.evaluateEndgame()
 	}
@@ -147,6 +162,11 @@ func (e *Evaluation) wrapUp() {
 	}
 }
 
+// Returns true if evaluation metrics are being captured.
+func (e *Evaluation) tracing() bool {
+	return e.metrics != nil
+}
+
 func (e *Evaluation) checkpoint(tag string, metric interface{}) {
 	e.metrics[tag] = metric
 }
@@ -32,16 +32,46 @@ func (e *Evaluation) strongerSide() uint8 {
 }
 
 // Known endgames where we calculate the exact score.
-func (e *Evaluation) winAgainstBareKing() int { // STUB.
-	return e.score.blended(e.material.phase)
+func (e *Evaluation) winAgainstBareKing() int {
+	color := e.strongerSide()
+	king, bareKing := int(e.position.king[color]), int(e.position.king[color^1])
+
+	// Drive bare king to the edge of the board and bring our king closer.
+	return e.winning(color, pushToEdge(bareKing) + pushClose(king, bareKing))
 }
 
-func (e *Evaluation) knightAndBishopVsBareKing() int { // STUB.
-	return e.score.blended(e.material.phase)
+func (e *Evaluation) knightAndBishopVsBareKing() int {
+	color := e.strongerSide()
+	outposts := &e.position.outposts
+	if outposts[color^1].count() > 1 { // The opponent is not a bare king.
+		return e.score.blended(e.material.phase)
+	}
+
+	// Checkmate is only possible in the corner of the bishop's color, i.e.
+	// A1 or H8 for dark squared bishop, and A8 or H1 for light squared one.
+	corner := A1
+	if outposts[bishop(color)] & maskDark == 0 {
+		corner = A8
+	}
+
+	king, bareKing := int(e.position.king[color]), int(e.position.king[color^1])
+	return e.winning(color, pushToCorner(bareKing, corner) + pushClose(king, bareKing))
 }
 
-func (e *Evaluation) twoBishopsVsBareKing() int { // STUB.
-	return e.score.blended(e.material.phase)
+func (e *Evaluation) twoBishopsVsBareKing() int {
+	color := e.strongerSide()
+	outposts := &e.position.outposts
+	if outposts[color^1].count() > 1 { // The opponent is not a bare king.
+		return e.score.blended(e.material.phase)
+	}
+
+	// Same colored bishops can't checkmate.
+	if bishops := outposts[bishop(color)]; bishops & maskDark == 0 || bishops & ^maskDark == 0 {
+		return 0
+	}
+
+	king, bareKing := int(e.position.king[color]), int(e.position.king[color^1])
+	return e.winning(color, pushToEdge(bareKing) + pushClose(king, bareKing))
 }
 
 func (e *Evaluation) kingAndPawnVsBareKing() int {
@@ -111,20 +141,119 @@ func (e *Evaluation) drawishBishops() int {
 	return -1
 }
 
-func (e *Evaluation) kingAndPawnVsKingAndPawn() int { // STUB.
-	return -1 // 96
+// King and pawn vs. king and pawn: it's a draw if the stronger side has rook
+// pawn with the defending king next to its promotion square, and neither pawn
+// wins on its own, i.e. the weaker side can't win the race either.
+func (e *Evaluation) kingAndPawnVsKingAndPawn() int {
+	color := e.strongerSide()
+	outposts := &e.position.outposts
+	ours, theirs := outposts[pawn(color)].first(), outposts[pawn(color^1)].first()
+
+	if col(ours) != 0 && col(ours) != 7 {
+		return -1
+	}
+	promo := square(7 * int(color^1), col(ours))
+	if distance[e.position.king[color^1]][promo] > 1 {
+		return -1
+	}
+	if e.kingAndPawnWins(color, ours) || e.kingAndPawnWins(color^1, theirs) {
+		return -1
+	}
+	return 0
 }
 
-func (e *Evaluation) bishopAndPawnVsBareKing() int { // STUB.
-	return -1 // 96
+// Bishop and rook pawn vs. bare king: it's a draw if the bishop doesn't control
+// the promotion square and the bare king gets there first.
+func (e *Evaluation) bishopAndPawnVsBareKing() int {
+	color := e.strongerSide()
+	outposts := &e.position.outposts
+
+	pawns := outposts[pawn(color)]
+	if pawns & (maskFile[0] | maskFile[7]) == 0 {
+		return -1
+	}
+
+	promo := square(7 * int(color^1), col(pawns.first()))
+	if same(promo) & outposts[bishop(color)] == 0 && distance[e.position.king[color^1]][promo] <= 1 {
+		return 0
+	}
+	return -1
 }
 
-func (e *Evaluation) rookAndPawnVsRook() int { // STUB.
-	return -1 // 96
+// Rook and pawn vs. rook: recognize Philidor position and other defensive
+// setups where the weaker side holds the draw, and Lucena position where the
+// stronger side wins.
+func (e *Evaluation) rookAndPawnVsRook() int {
+	color := e.strongerSide()
+	p := e.position
+
+	// Make sure it's exactly rook and pawn vs. rook.
+	if p.outposts[rook(color)].count() != 1 || p.outposts[pawn(color)].count() != 1 ||
+	   p.outposts[color^1].count() != 2 || p.outposts[rook(color^1)] == 0 {
+		return -1
+	}
+
+	// Normalize the squares so that the stronger side is white.
+	normalize := func(square int) int {
+		if color == Black {
+			return square ^ 56
+		}
+		return square
+	}
+	wKing, wRook := normalize(int(p.king[color])), normalize(p.outposts[rook(color)].first())
+	bKing, bRook := normalize(int(p.king[color^1])), normalize(p.outposts[rook(color^1)].first())
+	wPawn := normalize(p.outposts[pawn(color)].first())
+	promo := square(A8H8, col(wPawn))
+
+	tempo := 0 // Set if the stronger side is to move.
+	if p.color == color {
+		tempo = 1
+	}
+
+	// Philidor position: defending king is in front of the pawn, and the
+	// rook on the sixth rank keeps the attacking king away.
+	if row(wPawn) <= A5H5 && distance[bKing][promo] <= 1 && row(wKing) <= A5H5 &&
+	   (row(bRook) == A6H6 || (row(wPawn) <= A3H3 && row(wRook) != A6H6)) {
+		return 0
+	}
+
+	// Once the pawn reaches the sixth rank the defending rook checks the
+	// king from behind.
+	if row(wPawn) == A6H6 && distance[bKing][promo] <= 1 && row(wKing) + tempo <= A6H6 &&
+	   (row(bRook) == A1H1 || (tempo == 0 && abs(col(bRook) - col(wPawn)) >= 3)) {
+		return 0
+	}
+
+	// Defending king blocks the pawn and attacking king is too far away.
+	if row(wPawn) <= A5H5 && bKing == wPawn + 8 && distance[wKing][wPawn] - tempo >= 2 && distance[wKing][bRook] - tempo >= 2 {
+		return 0
+	}
+
+	// Lucena position: attacking king is on promotion square in front of
+	// the pawn, and the defending king is cut off by at least one file.
+	if row(wPawn) == A7H7 && col(wPawn) != 0 && col(wPawn) != 7 && wKing == promo && abs(col(bKing) - col(wPawn)) >= 2 {
+		return (2 << 16) | 1 // --> Double the original score.
+	}
+
+	return -1
 }
 
-func (e *Evaluation) queenVsRookAndPawns() int { // STUB.
-	return -1 // 96
+// Queen vs. rook and pawns: fortress where the rook is protected by a pawn next
+// to the king, and the attacking king can't get behind.
+func (e *Evaluation) queenVsRookAndPawns() int {
+	color := uint8(White)
+	if e.position.outposts[Queen] == 0 {
+		color = Black
+	}
+
+	p := e.position
+	king, rook := int(p.king[color^1]), p.outposts[rook(color^1)].first()
+
+	if rank(color^1, king) <= A2H2 && rank(color^1, int(p.king[color])) >= A4H4 && rank(color^1, rook) == A3H3 &&
+	   p.outposts[pawn(color^1)] & kingMoves[king] & pawnMoves[color][rook] != 0 {
+		return 0
+	}
+	return -1
 }
 
 func (e *Evaluation) lastPawnLeft() int {
@@ -172,3 +301,52 @@ func (e *Evaluation) noPawnsLeft() int {
 
 	return -1
 }
+
+// Returns the score of the stronger side adding the bonus that helps to make
+// progress towards checkmate.
+func (e *Evaluation) winning(color uint8, bonus int) int {
+	score := abs(e.score.blended(e.material.phase)) + bonus
+	if color == White {
+		return score
+	}
+	return -score
+}
+
+// Probes KPK bitbase and returns true if the side with the pawn on the given
+// square wins. Other pawns, if any, are ignored.
+func (e *Evaluation) kingAndPawnWins(color uint8, square int) bool {
+	p := e.position
+	stm, wKing, bKing, wPawn := int(p.color), int(p.king[White]), int(p.king[Black]), square
+
+	// Flip the board so that the pawn is white.
+	if color == Black {
+		stm ^= 1
+		wKing = 64 + ^int(p.king[Black])
+		bKing = 64 + ^int(p.king[White])
+		wPawn = 64 + ^square
+	}
+
+	index := stm + (wKing << 1) + (bKing << 7) + ((wPawn - 8) << 13)
+	return bitbase[index / 64] & (1 << uint(index & 0x3F)) != 0
+}
+
+// Bonus for driving the king towards the edge of the board: zero in the center
+// and 60 in the corners.
+func pushToEdge(square int) int {
+	row, col := coordinate(square)
+	return 10 * (max(3 - row, row - 4) + max(3 - col, col - 4))
+}
+
+// Bonus for driving the king towards given corner or the opposite one, ex. A1
+// and H8.
+func pushToCorner(square, corner int) int {
+	manhattan := func(from, to int) int {
+		return abs(row(from) - row(to)) + abs(col(from) - col(to))
+	}
+	return 10 * (14 - min(manhattan(square, corner), manhattan(square, corner ^ 63)))
+}
+
+// Bonus for bringing the kings closer to each other.
+func pushClose(king, bareKing int) int {
+	return 10 * (7 - distance[king][bareKing])
+}
@@ -218,3 +218,99 @@ func TestEndgame263(t *testing.T) {
 	black := game.start()
 	expect.Eq(t, black.Evaluate(), BlackWinning)
 }
+
+// King and rook vs. bare king: drive bare king to the edge.
+func TestEndgame300(t *testing.T) {
+	center := NewGame(`Ka1,Rh1`, `Ke5`).start().Evaluate()
+	edge := NewGame(`Ka1,Rh1`, `Ke8`).start().Evaluate()
+	expect.True(t, edge > center)
+}
+
+// King, knight, and bishop vs. bare king: drive bare king to the corner of
+// bishop's color.
+func TestEndgame310(t *testing.T) {
+	right := NewGame(`Ke6,Nd4,Bc4`, `Ka8`).start().Evaluate() // Light bishop, light corner.
+	wrong := NewGame(`Ke6,Nd4,Bc4`, `Kh8`).start().Evaluate()
+	expect.True(t, right > wrong)
+}
+
+func TestEndgame311(t *testing.T) {
+	right := NewGame(`Kb6`, `Kh1,M,Nd4,Bc5`).start().Evaluate() // Dark bishop, dark corner.
+	wrong := NewGame(`Ka6`, `Kh1,M,Nd4,Bc5`).start().Evaluate()
+	expect.True(t, right < wrong)
+}
+
+// Two bishops of the same color can't checkmate.
+func TestEndgame320(t *testing.T) {
+	game := NewGame(`Ke1,Bc4,Bd5`, `Ke8`)
+	expect.Eq(t, game.start().Evaluate(), 0)
+}
+
+func TestEndgame321(t *testing.T) {
+	game := NewGame(`Ke1,Bc4,Bc5`, `Ke8`)
+	expect.True(t, game.start().Evaluate() > 0)
+}
+
+// King and pawn vs. king and pawn: drawn if the pawn can't be promoted.
+func TestEndgame330(t *testing.T) {
+	game := NewGame(`Kc1,a2`, `Ka8,h7`)
+	expect.Eq(t, game.start().Evaluate(), 0)
+}
+
+// Not a draw if the opponent's pawn can't be stopped.
+func TestEndgame331(t *testing.T) {
+	game := NewGame(`Kb5,a6`, `M,Ka8,h5`)
+	expect.True(t, game.start().Evaluate() != 0)
+}
+
+func TestEndgame332(t *testing.T) {
+	game := NewGame(`Kb6,a5`, `M,Ka8,h4`)
+	expect.True(t, game.start().Evaluate() != 0)
+}
+
+// Not a draw if the defending king is away from the rook pawn.
+func TestEndgame333(t *testing.T) {
+	game := NewGame(`Ke1,a5`, `Kd8,h7`)
+	expect.True(t, game.start().Evaluate() > 0)
+}
+
+// Rook pawn and the bishop of the wrong color.
+func TestEndgame340(t *testing.T) {
+	game := NewGame(`Kc1,Bc4,h5`, `Kg8`)
+	expect.Eq(t, game.start().Evaluate(), 0)
+}
+
+func TestEndgame341(t *testing.T) {
+	game := NewGame(`Kc1,Bc5,h5`, `Kg8`)
+	expect.True(t, game.start().Evaluate() > 0)
+}
+
+// Philidor position, and the same one with colors flipped.
+func TestEndgame350(t *testing.T) {
+	game := NewGame(`Kd4,Rb2,e5`, `Ke8,Rh6`)
+	expect.Eq(t, game.start().Evaluate(), 0)
+}
+
+func TestEndgame351(t *testing.T) {
+	game := NewGame(`Ke1,Rh3`, `M,Kd5,Rb7,e4`)
+	expect.Eq(t, game.start().Evaluate(), 0)
+}
+
+// Lucena position is scored higher than similar position with defending
+// king next to the pawn.
+func TestEndgame360(t *testing.T) {
+	lucena := NewGame(`Ke8,Rd1,e7`, `Kg7,Ra2`).start().Evaluate()
+	other := NewGame(`Ke8,Rd1,e7`, `Kf7,Ra2`).start().Evaluate()
+	expect.True(t, lucena > other)
+}
+
+// Queen vs. rook and pawn fortress.
+func TestEndgame370(t *testing.T) {
+	game := NewGame(`Kd5,Qa5`, `Kg8,Rf6,g7`)
+	expect.Eq(t, game.start().Evaluate(), 0)
+}
+
+func TestEndgame371(t *testing.T) {
+	game := NewGame(`Kd5,Qa5`, `Kg8,Rf4,g7`)
+	expect.True(t, game.start().Evaluate() > 0)
+}
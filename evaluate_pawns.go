@@ -17,14 +17,22 @@ type PawnCache [8192]PawnEntry
 func (e *Evaluation) analyzePawns() {
 	key := e.position.pawnHash
 
+	// Expire cached pawn scores if evaluation parameters or weights have
+	// changed since they were cached.
+	worker := e.position.worker
+	if token := worker.game.engine.pawnToken; worker.pawnToken != token {
+		worker.pawnCache, worker.pawnToken = PawnCache{}, token
+	}
+
 	// Since pawn hash is fairly small we can use much faster 32-bit index.
-	index := uint32(key) % uint32(len(game.pawnCache))
-	e.pawns = &game.pawnCache[index]
+	pawnCache := &worker.pawnCache
+	index := uint32(key) % uint32(len(pawnCache))
+	e.pawns = &pawnCache[index]
 
 	// Bypass pawns cache if evaluation tracing is enabled.
-	if e.pawns.hash != key || engine.trace {
+	if e.pawns.hash != key || e.tracing() {
 		white, black := e.pawnStructure(White), e.pawnStructure(Black)
-		white.apply(weights[1]); black.apply(weights[1]) // <-- Pawn structure weight.
+		white.apply(e.weights[1]); black.apply(e.weights[1]) // <-- Pawn structure weight.
 		e.pawns.score.clear().add(white).subtract(black)
 		e.pawns.hash = key
 
@@ -32,7 +40,7 @@ func (e *Evaluation) analyzePawns() {
 		// will be viewed as if the king has moved.
 		e.pawns.king[White], e.pawns.king[Black] = 0xFF, 0xFF
 
-		if engine.trace {
+		if e.tracing() {
 			e.checkpoint(`Pawns`, Total{white, black})
 		}
 	}
@@ -43,14 +51,14 @@ func (e *Evaluation) analyzePawns() {
 func (e *Evaluation) analyzePassers() {
 	var white, black Score
 
-	if engine.trace {
+	if e.tracing() {
 		defer func() {
 			e.checkpoint(`Passers`, Total{white, black})
 		}()
 	}
 
 	white, black = e.pawnPassers(White), e.pawnPassers(Black)
-	white.apply(weights[2]); black.apply(weights[2]) // <-- Passed pawns weight.
+	white.apply(e.weights[2]); black.apply(e.weights[2]) // <-- Passed pawns weight.
 	e.score.add(white).subtract(black)
 }
 
@@ -76,9 +84,9 @@ func (e *Evaluation) pawnStructure(color uint8) (score Score) {
 		exposed := (maskInFront[color][square] & herPawns == 0)
 		if isolated {
 			if !exposed {
-				score.subtract(penaltyIsolatedPawn[col])
+				score.subtract(e.params.penaltyIsolatedPawn[col])
 			} else {
-				score.subtract(penaltyWeakIsolatedPawn[col])
+				score.subtract(e.params.penaltyWeakIsolatedPawn[col])
 			}
 		}
 
@@ -87,7 +95,7 @@ func (e *Evaluation) pawnStructure(color uint8) (score Score) {
 		// isolated.
 		doubled := (maskInFront[color][square] & hisPawns != 0)
 		if doubled {
-			score.subtract(penaltyDoubledPawn[col])
+			score.subtract(e.params.penaltyDoubledPawn[col])
 		}
 
 		// Bonus if the pawn is supported by friendly pawn(s) on the same
@@ -95,7 +103,7 @@ func (e *Evaluation) pawnStructure(color uint8) (score Score) {
 		supported := (maskIsolated[col] & (maskRank[row] | maskRank[row].pushed(color^1)) & hisPawns != 0)
 		if supported {
 			flipped := flip(color, square)
-			score.add(Score{bonusSupportedPawn[flipped], bonusSupportedPawn[flipped]})
+			score.add(Score{e.params.bonusSupportedPawn[flipped], e.params.bonusSupportedPawn[flipped]})
 		}
 
 		// The pawn is passed if a) there are no enemy pawns in the same
@@ -122,9 +130,9 @@ func (e *Evaluation) pawnStructure(color uint8) (score Score) {
 					if (enemy | enemy.pushed(color)) & herPawns != 0 {
 						backward = true
 						if !exposed {
-							score.subtract(penaltyBackwardPawn[col])
+							score.subtract(e.params.penaltyBackwardPawn[col])
 						} else {
-							score.subtract(penaltyWeakBackwardPawn[col])
+							score.subtract(e.params.penaltyWeakBackwardPawn[col])
 						}
 					}
 				}
@@ -136,19 +144,19 @@ func (e *Evaluation) pawnStructure(color uint8) (score Score) {
 			his := maskPassed[color^1][square + eight[color]] & maskIsolated[col] & hisPawns
 			her := maskPassed[color][square] & maskIsolated[col] & herPawns
 			if his.count() >= her.count() {
-				score.add(bonusSemiPassedPawn[rank(color, square)])
+				score.add(e.params.bonusSemiPassedPawn[rank(color, square)])
 			}
 		}
 
 		// Encourage center pawn moves.
 		if maskCenter.on(square) {
-			score.midgame += bonusPawn[0][flip(color, square)] / 2
+			score.midgame += e.params.bonusPawn[0][flip(color, square)] / 2
 		}
 	}
 
 	// Penalty for blocked pawns.
 	blocked := (hisPawns.pushed(color) & e.position.board).count()
-	score.subtract(pawnBlocked.times(blocked))
+	score.subtract(e.params.pawnBlocked.times(blocked))
 
 	return
 }
@@ -160,10 +168,10 @@ func (e *Evaluation) pawnPassers(color uint8) (score Score) {
 	for pawns != 0 {
 		square := pawns.pop()
 		rank := rank(color, square)
-		bonus := bonusPassedPawn[rank]
+		bonus := e.params.bonusPassedPawn[rank]
 
 		if rank > A2H2 {
-			extra := extraPassedPawn[rank]
+			extra := e.params.extraPassedPawn[rank]
 			nextSquare := square + eight[color]
 
 			// Adjust endgame bonus based on how close the kings are from the
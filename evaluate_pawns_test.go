@@ -10,7 +10,7 @@ import(`github.com/michaeldv/donna/expect`; `testing`)
 func TestEvaluatePawns100(t *testing.T) {
 	p := NewGame(`Ke1,h2,h3`, `Kd8,a7,a6`).start()
 	score := p.Evaluate()
-	expect.Eq(t, score, rightToMove.endgame) // Right to move only.
+	expect.Eq(t, score, defaultParams.rightToMove.endgame) // Right to move only.
 }
 
 func TestEvaluatePawns110(t *testing.T) {
@@ -9,7 +9,7 @@ func (e *Evaluation) analyzePieces() {
 	var mobile Score
 	var knight, bishop, rook, queen, mobility Total
 
-	if engine.trace {
+	if e.tracing() {
 		defer func() {
 			var his, her Score
 			e.checkpoint(`Mobility`, mobility)
@@ -84,8 +84,8 @@ func (e *Evaluation) analyzePieces() {
 	e.attacks[Black] |= e.attacks[BlackKnight] | e.attacks[BlackBishop] | e.attacks[BlackRook] | e.attacks[BlackQueen]
 
 	// Apply weights to the mobility scores.
-	mobility.white.apply(weights[0])
-	mobility.black.apply(weights[0])
+	mobility.white.apply(e.weights[0])
+	mobility.black.apply(e.weights[0])
 
 	// Update cumulative score based on white vs. black bonuses and mobility.
 	e.score.add(knight.white).add(bishop.white).add(rook.white).add(queen.white).add(mobility.white)
@@ -101,21 +101,21 @@ func (e *Evaluation) knights(color uint8, maskSafe Bitmask, isEnemyKingThreatene
 		attacks := p.attacks(square)
 
 		// Bonus for knight's mobility.
-		mobility.add(mobilityKnight[(attacks & maskSafe).count()])
+		mobility.add(e.params.mobilityKnight[(attacks & maskSafe).count()])
 
 		// Penalty if knight is attacked by enemy's pawn.
 		if maskPawn[color^1][square] & p.outposts[pawn(color^1)] != 0 {
-			score.subtract(penaltyPawnThreat[Knight/2])
+			score.subtract(e.params.penaltyPawnThreat[Knight/2])
 		}
 
 		// Bonus if knight is behind friendly pawn.
 		if rank(color, square) < 4 && p.outposts[pawn(color)].on(square + eight[color]) {
-			score.add(behindPawn)
+			score.add(e.params.behindPawn)
 		}
 
 		// Extra bonus if knight is in the center. Increase the extra bonus
 		// if the knight is supported by a pawn and can't be exchanged.
-		if extra := extraKnight[flip(color, square)]; extra > 0 {
+		if extra := e.params.extraKnight[flip(color, square)]; extra > 0 {
 			if p.pawnAttacks(color).on(square) {
 				extra += extra / 2 // Supported by a pawn.
 				if p.outposts[knight(color^1)] == 0 && (same(square) & p.outposts[bishop(color^1)]).empty() {
@@ -145,21 +145,21 @@ func (e *Evaluation) bishops(color uint8, maskSafe Bitmask, isEnemyKingThreatene
 		attacks := p.xrayAttacks(square)
 
 		// Bonus for bishop's mobility
-		mobility.add(mobilityBishop[(attacks & maskSafe).count()])
+		mobility.add(e.params.mobilityBishop[(attacks & maskSafe).count()])
 
 		// Penalty for light/dark-colored pawns restricting a bishop.
 		if count := (same(square) & p.outposts[pawn(color)]).count(); count > 0 {
-			score.subtract(bishopPawn.times(count))
+			score.subtract(e.params.bishopPawn.times(count))
 		}
 
 		// Penalty if bishop is attacked by enemy's pawn.
 		if maskPawn[color^1][square] & p.outposts[pawn(color^1)] != 0 {
-			score.subtract(penaltyPawnThreat[Bishop/2])
+			score.subtract(e.params.penaltyPawnThreat[Bishop/2])
 		}
 
 		// Bonus if bishop is behind friendly pawn.
 		if rank(color, square) < 4 && p.outposts[pawn(color)].on(square + eight[color]) {
-			score.add(behindPawn)
+			score.add(e.params.behindPawn)
 		}
 
 		// Middle game penalty for boxed bishop.
@@ -167,19 +167,19 @@ func (e *Evaluation) bishops(color uint8, maskSafe Bitmask, isEnemyKingThreatene
 			if color == White {
 				if (square == C1 && p.pieces[D2].isPawn() && p.pieces[D3] != 0) ||
 				   (square == F1 && p.pieces[E2].isPawn() && p.pieces[E3] != 0) {
-					score.midgame -= bishopBoxed.midgame
+					score.midgame -= e.params.bishopBoxed.midgame
 				}
 			} else {
 				if (square == C8 && p.pieces[D7].isPawn() && p.pieces[D6] != 0) ||
 				   (square == F8 && p.pieces[E7].isPawn() && p.pieces[E6] != 0) {
-					score.midgame -= bishopBoxed.midgame
+					score.midgame -= e.params.bishopBoxed.midgame
 				}
 			}
 		}
 
 		// Extra bonus if bishop is in the center. Increase the extra bonus
 		// if the bishop is supported by a pawn and can't be exchanged.
-		if extra := extraBishop[flip(color, square)]; extra > 0 {
+		if extra := e.params.extraBishop[flip(color, square)]; extra > 0 {
 			if p.pawnAttacks(color).on(square) {
 				extra += extra / 2 // Supported by a pawn.
 				if p.outposts[knight(color^1)] == 0 && (same(square) & p.outposts[bishop(color^1)]).empty() {
@@ -209,7 +209,7 @@ func (e *Evaluation) rooks(color uint8, maskSafe Bitmask, isEnemyKingThreatened
 
 	// Bonus if rook is on 7th rank and enemy's king trapped on 8th.
 	if count := (outposts & mask7th[color]).count(); count > 0 && p.outposts[king(color^1)] & mask8th[color] != 0 {
-		score.add(rookOn7th.times(count))
+		score.add(e.params.rookOn7th.times(count))
 	}
 	for outposts != 0 {
 		square := outposts.pop()
@@ -217,17 +217,17 @@ func (e *Evaluation) rooks(color uint8, maskSafe Bitmask, isEnemyKingThreatened
 
 		// Bonus for rook's mobility
 		safeSquares := (attacks & maskSafe).count()
-		mobility.add(mobilityRook[safeSquares])
+		mobility.add(e.params.mobilityRook[safeSquares])
 
 		// Penalty if rook is attacked by enemy's pawn.
 		if maskPawn[color^1][square] & herPawns != 0 {
-			score.subtract(penaltyPawnThreat[Rook/2])
+			score.subtract(e.params.penaltyPawnThreat[Rook/2])
 		}
 
 		// Bonus if rook is attacking enemy's pawns.
 		if rank(color, square) >= 4 {
 			if count := (attacks & herPawns).count(); count > 0 {
-				score.add(rookOnPawn.times(count))
+				score.add(e.params.rookOnPawn.times(count))
 			}
 		}
 
@@ -236,9 +236,9 @@ func (e *Evaluation) rooks(color uint8, maskSafe Bitmask, isEnemyKingThreatened
 		isFileAjar := (hisPawns & maskFile[column] == 0)
 		if isFileAjar {
 			if herPawns & maskFile[column] == 0 {
-				score.add(rookOnOpen)
+				score.add(e.params.rookOnOpen)
 			} else {
-				score.add(rookOnSemiOpen)
+				score.add(e.params.rookOnSemiOpen)
 			}
 		}
 
@@ -251,14 +251,14 @@ func (e *Evaluation) rooks(color uint8, maskSafe Bitmask, isEnemyKingThreatened
 			// Queenside box: king on D/C/B vs. rook on A/B/C files. Increase the
 			// the penalty since no castle is possible.
 			if column < kingColumn && rookBoxA[color].on(square) && kingBoxA[color].on(kingSquare) {
-				score.midgame -= (rookBoxed.midgame - safeSquares * 10) * 2
+				score.midgame -= (e.params.rookBoxed.midgame - safeSquares * 10) * 2
 			}
 
 			// Kingside box: king on E/F/G vs. rook on H/G/F files.
 			if column > kingColumn && rookBoxH[color].on(square) && kingBoxH[color].on(kingSquare) {
-				score.midgame -= (rookBoxed.midgame - safeSquares * 10)
+				score.midgame -= (e.params.rookBoxed.midgame - safeSquares * 10)
 				if p.castles & castleKingside[color] == 0 {
-					score.midgame -= (rookBoxed.midgame - safeSquares * 10)
+					score.midgame -= (e.params.rookBoxed.midgame - safeSquares * 10)
 				}
 			}
 		}
@@ -280,23 +280,23 @@ func (e *Evaluation) queens(color uint8, maskSafe Bitmask, isEnemyKingThreatened
 
 	// Bonus if queen is on 7th rank and enemy's king trapped on 8th.
 	if count := (outposts & mask7th[color]).count(); count > 0 && p.outposts[king(color^1)] & mask8th[color] != 0 {
-		score.add(queenOn7th.times(count))
+		score.add(e.params.queenOn7th.times(count))
 	}
 	for outposts != 0 {
 		square := outposts.pop()
 		attacks := p.attacks(square)
 
 		// Bonus for queen's mobility.
-		mobility.add(mobilityQueen[min(15, (attacks & maskSafe).count())])
+		mobility.add(e.params.mobilityQueen[min(15, (attacks & maskSafe).count())])
 
 		// Penalty if queen is attacked by enemy's pawn.
 		if maskPawn[color^1][square] & p.outposts[pawn(color^1)] != 0 {
-			score.subtract(penaltyPawnThreat[Queen/2])
+			score.subtract(e.params.penaltyPawnThreat[Queen/2])
 		}
 
 		// Bonus if queen is out and attacking enemy's pawns.
 		if count := (attacks & p.outposts[pawn(color^1)]).count(); count > 0 && rank(color, square) > 3 {
-			score.add(queenOnPawn.times(count))
+			score.add(e.params.queenOnPawn.times(count))
 		}
 
 		// Track if queen attacks squares around enemy's king.
@@ -316,7 +316,7 @@ func (e *Evaluation) enemyKingThreat(piece Piece, attacks Bitmask) {
 
 	if attacks & e.safety[color].fort != 0 {
 		e.safety[color].attackers++
-		e.safety[color].threats += bonusKingThreat[piece.kind()/2]
+		e.safety[color].threats += e.params.bonusKingThreat[piece.kind()/2]
 		if bits := attacks & e.attacks[king(color)]; bits != 0 {
 			e.safety[color].attacks += bits.count()
 		}
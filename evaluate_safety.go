@@ -13,7 +13,7 @@ func (e *Evaluation) analyzeSafety() {
 	whiteKingMoved := e.position.king[White] != e.pawns.king[White]
 	blackKingMoved := e.position.king[Black] != e.pawns.king[Black]
 
-	if engine.trace {
+	if e.tracing() {
 		defer func() {
 			var his, her Score
 			e.checkpoint(`+King`, Total{*his.add(cover.white).add(safety.white), *her.add(cover.black).add(safety.black)})
@@ -46,19 +46,19 @@ func (e *Evaluation) analyzeSafety() {
 	// Compute king's safety for both sides.
 	safety.white = e.kingSafety(White)
 	if e.material.flags & whiteKingSafety != 0 && oppositeBishops(cover.white.midgame + safety.white.midgame) {
-		safety.white.midgame -= bishopDanger.midgame
+		safety.white.midgame -= e.params.bishopDanger.midgame
 	}
 	safety.black = e.kingSafety(Black)
 	if e.material.flags & blackKingSafety != 0 && oppositeBishops(cover.black.midgame + safety.black.midgame) {
-		safety.black.midgame -= bishopDanger.midgame
+		safety.black.midgame -= e.params.bishopDanger.midgame
 	}
 
 	// Apply weights by mapping Black to our king safety index [3], and White
 	// to enemy's king safety index [4].
-	cover.white.apply(weights[3+color])
-	cover.black.apply(weights[4-color])
-	safety.white.apply(weights[3+color])
-	safety.black.apply(weights[4-color])
+	cover.white.apply(e.weights[3+color])
+	cover.black.apply(e.weights[4-color])
+	safety.white.apply(e.weights[3+color])
+	safety.black.apply(e.weights[4-color])
 	e.score.add(cover.white).add(safety.white).subtract(cover.black).subtract(safety.black)
 }
 
@@ -84,7 +84,7 @@ func (e *Evaluation) kingSafety(color uint8) (score Score) {
 		             e.attacks[king(color^1)]
 		checks := weak & e.attacks[queen(color^1)] & protected & ^p.outposts[color^1]
 		if checks != 0 {
-			safetyIndex += bonusCloseCheck[Queen/2] * checks.count()
+			safetyIndex += e.params.bonusCloseCheck[Queen/2] * checks.count()
 		}
 
 		// Find possible rook checks within king's home zone. Unlike
@@ -96,7 +96,7 @@ func (e *Evaluation) kingSafety(color uint8) (score Score) {
 		checks = weak & e.attacks[rook(color^1)] & protected & ^p.outposts[color^1]
 		checks &= rookMagicMoves[square][0]
 		if checks != 0 {
-			safetyIndex += bonusCloseCheck[Rook/2] * checks.count()
+			safetyIndex += e.params.bonusCloseCheck[Rook/2] * checks.count()
 		}
 
 		// Double safety index if the enemy has right to move.
@@ -111,34 +111,34 @@ func (e *Evaluation) kingSafety(color uint8) (score Score) {
 		// Are there any safe squares from where enemy Knight could give
 		// us a check?
 		if checks := knightMoves[square] & safe & e.attacks[knight(color^1)]; checks != 0 {
-			safetyIndex += bonusDistanceCheck[Knight/2] * checks.count()
+			safetyIndex += e.params.bonusDistanceCheck[Knight/2] * checks.count()
 		}
 
 		// Are there any safe squares from where enemy Bishop could give
 		// us a check?
 		safeBishopMoves := p.bishopMoves(square) & safe
 		if checks := safeBishopMoves & e.attacks[bishop(color^1)]; checks != 0 {
-			safetyIndex += bonusDistanceCheck[Bishop/2] * checks.count()
+			safetyIndex += e.params.bonusDistanceCheck[Bishop/2] * checks.count()
 		}
 
 		// Are there any safe squares from where enemy Rook could give
 		// us a check?
 		safeRookMoves := p.rookMoves(square) & safe
 		if checks := safeRookMoves & e.attacks[rook(color^1)]; checks != 0 {
-			safetyIndex += bonusDistanceCheck[Rook/2] * checks.count()
+			safetyIndex += e.params.bonusDistanceCheck[Rook/2] * checks.count()
 		}
 
 		// Are there any safe squares from where enemy Queen could give
 		// us a check?
 		if checks := (safeBishopMoves | safeRookMoves) & e.attacks[queen(color^1)]; checks != 0 {
-			safetyIndex += bonusDistanceCheck[Queen/2] * checks.count()
+			safetyIndex += e.params.bonusDistanceCheck[Queen/2] * checks.count()
 		}
 
 		threatIndex := min(12, e.safety[color].attackers * e.safety[color].threats / 3) + (e.safety[color].attacks + weak.count()) * 2
 		safetyIndex = min(63, safetyIndex + threatIndex)
 
-		score.midgame -= kingSafety[safetyIndex]
-		score.endgame -= bonusKing[1][flip(color, square)]
+		score.midgame -= e.params.kingSafety[safetyIndex]
+		score.endgame -= e.params.bonusKing[1][flip(color, square)]
 	}
 
 	return
@@ -182,9 +182,9 @@ func (e *Evaluation) kingCoverBonus(color uint8, square, flipped int) (bonus int
 	for column := from; column <= to; column++ {
 		if cover := (pawns & maskFile[column]); cover != 0 {
 			closest := rank(color, cover.closest(color))
-			bonus -= penaltyCover[closest - r]
+			bonus -= e.params.penaltyCover[closest - r]
 		} else {
-			bonus -= coverMissing.midgame
+			bonus -= e.params.coverMissing.midgame
 		}
 	}
 
@@ -200,7 +200,7 @@ func (e *Evaluation) kingPawnProximity(color uint8) (penalty int) {
 		for pawns != 0 {
 			proximity = min(proximity, distance[king][pawns.pop()])
 		}
-		penalty = -kingByPawn.endgame * (proximity - 1)
+		penalty = -e.params.kingByPawn.endgame * (proximity - 1)
 	}
 
 	return
@@ -7,7 +7,7 @@ package donna
 func (e *Evaluation) analyzeThreats() {
 	var threats, center Total
 
-	if engine.trace {
+	if e.tracing() {
 		defer func() {
 			e.checkpoint(`Threats`, threats)
 			if e.material.turf != 0 && e.material.flags & (whiteKingSafety | blackKingSafety) != 0 {
@@ -42,7 +42,7 @@ func (e *Evaluation) threats(color uint8, hisAttacks, herAttacks Bitmask) (score
 		targets := weak & (e.attacks[pawn(color)] | e.attacks[knight(color)] | e.attacks[bishop(color)])
 		if targets != 0 {
 			piece := p.strongestPiece(color^1, targets)
-			score.add(bonusMinorThreat[piece.kind()/2])
+			score.add(e.params.bonusMinorThreat[piece.kind()/2])
 		}
 
 		// Threat bonus for strongest enemy piece attacked by our rooks
@@ -50,7 +50,7 @@ func (e *Evaluation) threats(color uint8, hisAttacks, herAttacks Bitmask) (score
 		targets = weak & (e.attacks[rook(color)] | e.attacks[queen(color)])
 		if targets != 0 {
 			piece := p.strongestPiece(color^1, targets)
-			score.add(bonusMajorThreat[piece.kind()/2])
+			score.add(e.params.bonusMajorThreat[piece.kind()/2])
 		}
 
 		// Extra bonus when attacking enemy pieces that are hanging. Side
@@ -60,7 +60,7 @@ func (e *Evaluation) threats(color uint8, hisAttacks, herAttacks Bitmask) (score
 			if p.color == color {
 				hanging++
 			}
-			score.add(hangingAttack.times(hanging))
+			score.add(e.params.hangingAttack.times(hanging))
 		}
 	}
 	return
@@ -9,43 +9,43 @@ import(`github.com/michaeldv/donna/expect`; `testing`)
 // Attacks by minor piece.
 func TestEvaluateThreats000(t *testing.T) {
 	// Baseline: bishop defended by pawn.
-	NewGame(`Kh1,Ne4`, `Kg7,Bf6,e7`).start().EvaluateWithTrace()
-	baseline := eval.metrics[`Threats`].(Total).white
+	_, metrics := NewGame(`Kh1,Ne4`, `Kg7,Bf6,e7`).start().EvaluateWithTrace()
+	baseline := metrics[`Threats`].(Total).white
 
 	// Bishop not defended by pawn.
-	NewGame(`Kh1,Ne4,a2`, `Ke7,Bf6,a7`).start().EvaluateWithTrace()
-	score := eval.metrics[`Threats`].(Total).white
-	expect.Eq(t, score.minus(baseline), bonusMinorThreat[Bishop/2])
+	_, metrics = NewGame(`Kh1,Ne4,a2`, `Ke7,Bf6,a7`).start().EvaluateWithTrace()
+	score := metrics[`Threats`].(Total).white
+	expect.Eq(t, score.minus(baseline), defaultParams.bonusMinorThreat[Bishop/2])
 
 	// Bishop and rook not defended by pawn (rook is stronger).
-	NewGame(`Kh1,Ne4,a2`, `Ke7,Bf6,Rd6,a7`).start().EvaluateWithTrace()
-	score = eval.metrics[`Threats`].(Total).white
-	expect.Eq(t, score.minus(baseline), bonusMinorThreat[Rook/2])
+	_, metrics = NewGame(`Kh1,Ne4,a2`, `Ke7,Bf6,Rd6,a7`).start().EvaluateWithTrace()
+	score = metrics[`Threats`].(Total).white
+	expect.Eq(t, score.minus(baseline), defaultParams.bonusMinorThreat[Rook/2])
 
 	// Hanging bishop with extra bonus for the right to move.
-	NewGame(`Kh1,Ne4,a2`, `Ka8,Bf6,a7`).start().EvaluateWithTrace()
-	score = eval.metrics[`Threats`].(Total).white
-	expect.Eq(t, score.minus(baseline), bonusMinorThreat[Bishop/2].plus(hangingAttack.times(2)))
+	_, metrics = NewGame(`Kh1,Ne4,a2`, `Ka8,Bf6,a7`).start().EvaluateWithTrace()
+	score = metrics[`Threats`].(Total).white
+	expect.Eq(t, score.minus(baseline), defaultParams.bonusMinorThreat[Bishop/2].plus(defaultParams.hangingAttack.times(2)))
 }
 
 // Attacks by major piece.
 func TestEvaluateThreats010(t *testing.T) {
 	// Baseline: bishop defended by pawn.
-	NewGame(`Kh1,Rf1`, `Kg7,Bf6,e7`).start().EvaluateWithTrace()
-	baseline := eval.metrics[`Threats`].(Total).white
+	_, metrics := NewGame(`Kh1,Rf1`, `Kg7,Bf6,e7`).start().EvaluateWithTrace()
+	baseline := metrics[`Threats`].(Total).white
 
 	// Bishop not defended by pawn.
-	NewGame(`Kh1,Rf1`, `Ke7,Bf6`).start().EvaluateWithTrace()
-	score := eval.metrics[`Threats`].(Total).white
-	expect.Eq(t, score.minus(baseline), bonusMajorThreat[Bishop/2])
+	_, metrics = NewGame(`Kh1,Rf1`, `Ke7,Bf6`).start().EvaluateWithTrace()
+	score := metrics[`Threats`].(Total).white
+	expect.Eq(t, score.minus(baseline), defaultParams.bonusMajorThreat[Bishop/2])
 
 	// Bishop and queen not defended by pawn (queen is stronger).
-	NewGame(`Kh1,Rf1`, `Ke7,Qa1,Bf6`).start().EvaluateWithTrace()
-	score = eval.metrics[`Threats`].(Total).white
-	expect.Eq(t, score.minus(baseline), bonusMajorThreat[Queen/2])
+	_, metrics = NewGame(`Kh1,Rf1`, `Ke7,Qa1,Bf6`).start().EvaluateWithTrace()
+	score = metrics[`Threats`].(Total).white
+	expect.Eq(t, score.minus(baseline), defaultParams.bonusMajorThreat[Queen/2])
 
 	// Hanging bishop with extra bonus for the right to move.
-	NewGame(`Kh1,Rf1`, `Kh8,Bf6`).start().EvaluateWithTrace()
-	score = eval.metrics[`Threats`].(Total).white
-	expect.Eq(t, score.minus(baseline), bonusMajorThreat[Bishop/2].plus(hangingAttack.times(2)))
+	_, metrics = NewGame(`Kh1,Rf1`, `Kh8,Bf6`).start().EvaluateWithTrace()
+	score = metrics[`Threats`].(Total).white
+	expect.Eq(t, score.minus(baseline), defaultParams.bonusMajorThreat[Bishop/2].plus(defaultParams.hangingAttack.times(2)))
 }
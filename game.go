@@ -6,7 +6,10 @@ package donna
 
 import (
 	`fmt`
+	`sort`
 	`strings`
+	`sync`
+	`sync/atomic`
 	`time`
 )
 
@@ -15,40 +18,34 @@ type Killers [MaxPly][2]Move
 type RootPv  []Move
 type Pv      [MaxPly]RootPv
 
+// Root move variation reported in MultiPV mode.
+type Variation struct {
+	score       int 	// Variation score.
+	moves       RootPv 	// Variation moves starting with the root move.
+}
+
 type Game struct {
-	nodes       int 	// Number of regular nodes searched.
-	qnodes      int 	// Number of quiescence nodes searched.
+	engine      *Engine 	// Engine the game is played by.
 	token       uint8 	// Cache's expiration token.
-	deepening   bool 	// True when searching first root move.
-	improving   bool 	// True when root search score is not falling.
-	volatility  float32 	// Root search stability count.
 	initial     string   	// Initial position (FEN or algebraic).
-	history     History  	// Good moves history.
-	killers     Killers  	// Killer moves.
-	rootpv      RootPv 	// Principal variation for root moves.
-	pv          Pv 		// Principal variations for each ply.
-	cache       Cache 	// Transposition table.
-	pawnCache   PawnCache 	// Cache of pawn structures.
+	cache       Cache 	// Transposition table shared by all workers.
+	workers     []*Worker 	// Search workers, workers[0] being the main one.
+	rootMoves   []Move 	// Root moves to search, or all the moves if empty.
+	castling    Castling 	// Castle setup (standard or Chess960).
+	book        *Book 	// Opening book along with its probing state.
+	progress    func(depth, score int, move Move, duration int64) // Optional callback invoked after each search iteration.
 }
 
-// Use single statically allocated variable.
-var game Game
-
 // We have two ways to initialize the game: 1) pass FEN string, and 2) specify
 // white and black pieces using regular chess notation.
 //
 // In latter case we need to tell who gets to move first when starting the game.
 // The second option is a bit less pricise (ex. no en-passant square) but it is
 // much more useful when writing tests from memory.
-func NewGame(args ...string) *Game {
-	game = Game{}
-	game.cache = NewCache(engine.cacheSize)
-	game.pawnCache = PawnCache{}
-
-	game.rootpv = make([]Move, 0, MaxPly)
-	for ply := 0;  ply < MaxPly; ply++ {
-		game.pv[ply] = make([]Move, 0, MaxPly)
-	}
+func (e *Engine) NewGame(args ...string) *Game {
+	game := &Game{engine: e, castling: NewCastling(e.chess960)}
+	game.cache = NewCache(e.cacheSize)
+	game.workers = []*Worker{ NewWorker(game, 0) }
 
 	switch len(args) {
 	case 0: // Initial position.
@@ -59,12 +56,18 @@ func NewGame(args ...string) *Game {
 		game.initial = args[0] + ` : ` + args[1]
 	}
 
-	return &game
+	return game
+}
+
+// Starts new game played by the engine with default settings.
+func NewGame(args ...string) *Game {
+	return NewEngine().NewGame(args...)
 }
 
 func (game *Game) start() *Position {
-	engine.clock.halt = false
-	tree, node, rootNode = [1024]Position{}, 0, 0
+	game.engine.clock.reset()
+	main := game.workers[0]
+	main.tree, main.node, main.rootNode = [1024]Position{}, 0, 0
 
 	// Was the game started with FEN or algebraic notation?
 	sides := strings.Split(game.initial, ` : `)
@@ -74,38 +77,97 @@ func (game *Game) start() *Position {
 	return NewPositionFromFEN(game, game.initial)
 }
 
+// Sets up the game's initial position same way as start() does, and makes sure
+// the position is valid. Invalid FEN or DCF strings and positions that could
+// not arise in a legal game are reported as *PositionError.
+func (game *Game) setup() (position *Position, err error) {
+	sides := strings.Split(game.initial, ` : `)
+	if len(sides) != 2 {
+		if err = checkFEN(game.initial); err != nil {
+			return nil, err
+		}
+	} else {
+		defer func() { // Invalid DCF notation panics.
+			if recover() != nil {
+				position, err = nil, positionError(InvalidFormat, `invalid position "%s"`, game.initial)
+			}
+		}()
+	}
+
+	position = game.start()
+	if err = position.validate(); err != nil {
+		return nil, err
+	}
+	return position, nil
+}
+
 func (game *Game) position() *Position {
-	return &tree[node]
+	return game.workers[0].position()
 }
 
-// Resets principal variation as well as killer moves and move history. Cache
-// entries get expired by incrementing cache token. Root node gets set to the
-// current tree node to match the position.
+// Gets all the workers ready for the search: the number of workers matches the
+// number of threads set in engine options. Cache entries get expired by
+// incrementing cache token.
 func (game *Game) getReady() *Game {
-	game.rootpv = game.rootpv[:0]
-	for ply := 0;  ply < MaxPly; ply++ {
-		game.pv[ply] = game.pv[ply][:0]
+	threads := max(1, game.engine.threads)
+	for id := len(game.workers); id < threads; id++ {
+		game.workers = append(game.workers, NewWorker(game, id))
 	}
-
-	game.killers = Killers{}
-	game.history = History{}
-	game.deepening = false
-	game.improving = true
-	game.volatility = 0.0
+	game.workers = game.workers[:threads]
+	game.workers[0].getReady()
 	game.token++ // <-- Wraps around: ...254, 255, 0, 1...
 
-	rootNode = node
 	return game
 }
 
+// Starts helper workers searching the main worker's root position. The helpers
+// run until the search gets halted.
+func (game *Game) startHelpers(wg *sync.WaitGroup) *Game {
+	for _, helper := range game.workers[1:] {
+		position := helper.follow(game.workers[0])
+		wg.Add(1)
+		go func(helper *Worker) {
+			defer wg.Done()
+			helper.help(position)
+		}(helper)
+	}
+
+	return game
+}
+
+// Returns number of regular and quiescence nodes searched by all the workers.
+func (game *Game) nodeCount() (nodes, qnodes int) {
+	for _, w := range game.workers {
+		nodes += int(atomic.LoadInt64(&w.nodes))
+		qnodes += int(atomic.LoadInt64(&w.qnodes))
+	}
+	return
+}
+
+// Searches for the best move in current position.
 func (game *Game) Think() Move {
-	start := time.Now()
-	position := game.position()
-	game.nodes, game.qnodes = 0, 0
+	game.engine.clock.reset()
+	return game.think()
+}
+
+// Does the actual search assuming the clock has been reset by the caller, i.e.
+// the search started on its own goroutine could be stopped right away.
+func (game *Game) think() Move {
+	game.engine.beginSearch()
+	defer game.engine.endSearch()
 
-	if len(engine.bookFile) != 0 {
-		if book, err := NewBook(engine.bookFile); err == nil {
+	engine, start := game.engine, time.Now()
+	main, position := game.workers[0], game.position()
+	for _, w := range game.workers {
+		w.nodes, w.qnodes = 0, 0
+	}
+
+	// Polyglot books cover standard chess openings only, and know nothing
+	// about the moves restricted by "go searchmoves".
+	if engine.ownBook && len(engine.bookFile) != 0 && !game.castling.chess960 && len(engine.options.searchMoves) == 0 {
+		if book := game.openBook(); book != nil {
 			if move := book.pickMove(position); move != 0 {
+				engine.waitForStop()
 				game.printBestMove(move, since(start))
 				return move
 			}
@@ -115,22 +177,38 @@ func (game *Game) Think() Move {
 	game.getReady()
 	score, move, status, alpha, beta := 0, Move(0), InProgress, -Checkmate, Checkmate
 
+	// Search root moves requested by "go searchmoves", if any. In tablebase
+	// positions narrow them down to the ones that preserve the outcome.
+	game.rootMoves = engine.options.searchMoves
+	if engine.tablebase != nil {
+		if moves := engine.tablebase.rootMoves(position, game.rootMoves...); len(moves) > 0 {
+			engine.debug("# Tablebase moves %v\n", moves)
+			game.rootMoves = moves
+		}
+	}
+
 	if engine.uci {
 		engine.debug(position.String())
-	} else {
+	} else if !engine.quiet {
 		fmt.Println(`Depth   Time      Nodes     QNodes   Nodes/s   Score   Best`)
 	}
 
-	if !engine.fixedDepth() {
-		engine.startClock(); defer engine.stopClock();
+	// Pondering search starts the clock on "ponderhit".
+	if !engine.fixedDepth() && !engine.options.ponder {
+		engine.startClock()
 	}
+	defer engine.stopClock()
+
+	// Start helper workers, if any.
+	var wg sync.WaitGroup
+	game.startHelpers(&wg)
 
 	for depth := 1; status == InProgress && game.keepThinking(depth, move); depth++ {
 		// Save previous best score in case search gets interrupted.
 		bestScore := score
 
 		// Assume volatility decreases with each new iteration.
-		game.volatility /= 2.0
+		main.volatility /= 2.0
 
 		// At low depths do the search with full alpha/beta spread.
 		// Aspiration window searches kick in at depth 5 and up.
@@ -138,7 +216,9 @@ func (game *Game) Think() Move {
 			score = position.search(alpha, beta, depth)
 			if score > alpha {
 				bestScore = score
-				game.rootpv = append(game.rootpv[:0], game.pv[0]...)
+				main.rootpv = append(main.rootpv[:0], main.pv[0]...)
+			} else if len(main.rootpv) == 0 { // Halted before the first move got searched.
+				main.rootpv = append(main.rootpv[:0], main.pv[0]...)
 			}
 		} else {
 			aspiration := onePawn / 3
@@ -153,15 +233,15 @@ func (game *Game) Think() Move {
 				score = position.search(alpha, beta, depth)
 				if score > alpha {
 					bestScore = score
-					game.rootpv = append(game.rootpv[:0], game.pv[0]...)
+					main.rootpv = append(main.rootpv[:0], main.pv[0]...)
 				}
 
-				if !engine.fixedDepth() && engine.clock.halt {
+				if engine.clock.stopped() {
 					break
 				}
 
 				if score <= alpha {
-					game.improving = false
+					main.improving = false
 					alpha = max(score - aspiration, -Checkmate)
 				} else if score >= beta {
 					beta = min(score + aspiration, Checkmate)
@@ -173,35 +253,104 @@ func (game *Game) Think() Move {
 			}
 			// TBD: position.cache(game.rootpv[0], score, 0, 0)
 		}
-		if engine.clock.halt {
-			//Log("\ttimed out pv => %v\n\ttimed out rv => %v\n", game.pv[0], game.rootpv)
+		if engine.clock.stopped() {
+			//Log("\ttimed out pv => %v\n\ttimed out rv => %v\n", main.pv[0], main.rootpv)
 			score = bestScore
 		}
 
-		move = game.rootpv[0]
+		if engine.multiPV > 1 {
+			score = game.searchVariations(depth, score)
+		}
+		move = main.rootpv[0]
 		status = position.status(move, score)
 		game.printPrincipal(depth, score, status, since(start))
+		if game.progress != nil {
+			game.progress(depth, score, move, since(start))
+		}
 	}
 
+	// Halt helper workers and wait till they are done. When pondering or doing
+	// infinite search hold on to the best move until "ponderhit" or "stop".
+	engine.waitForStop()
+	engine.clock.stop(); wg.Wait()
 	game.printBestMove(move, since(start))
 
+	// Let the book know how the game is going for learning.
+	if game.book != nil && game.book.learn {
+		game.book.track(position, status, score)
+	}
+
 	return move
 }
 
+// Searches secondary variations in MultiPV mode. The search starts off the best
+// variation found by regular root search, and each next one is the best among
+// root moves not covered by preceding variations. When the search gets halted we
+// drop variations that have not been searched at this depth. Returns the score
+// of the best variation.
+func (game *Game) searchVariations(depth, score int) int {
+	main, position := game.workers[0], game.position()
+	count := min(game.engine.multiPV, NewGen(position, MaxPly).generateRootMoves(game.rootMoves...).size())
+
+	for len(main.variations) < count {
+		main.variations = append(main.variations, Variation{})
+	}
+	main.variations = main.variations[:count]
+	main.variations[0].score = score
+	main.variations[0].moves = append(main.variations[0].moves[:0], main.rootpv...)
+
+	for i := 1; i < count; i++ {
+		main.skip = main.skip[:0]
+		for _, variation := range main.variations[:i] {
+			main.skip = append(main.skip, variation.moves[0])
+		}
+
+		score := position.search(-Checkmate, Checkmate, depth)
+		if game.engine.clock.stopped() || len(main.pv[0]) == 0 {
+			main.variations = main.variations[:i]
+			break
+		}
+		main.variations[i].score = score
+		main.variations[i].moves = append(main.variations[i].moves[:0], main.pv[0]...)
+	}
+	main.skip = main.skip[:0]
+
+	// Rank the variations by their score. Secondary variations are searched
+	// with full window so one of them might turn out better than the best one
+	// found by aspiration search, and if so it becomes principal variation.
+	sort.Stable(byVariationScore(main.variations))
+	main.rootpv = append(main.rootpv[:0], main.variations[0].moves...)
+
+	return main.variations[0].score
+}
+
+type byVariationScore []Variation
+
+func (her byVariationScore) Len() int           { return len(her) }
+func (her byVariationScore) Swap(i, j int)      { her[i], her[j] = her[j], her[i] }
+func (her byVariationScore) Less(i, j int) bool { return her[i].score > her[j].score }
+
 func (game *Game) keepThinking(depth int, move Move) bool {
+	engine := game.engine
 	if depth == 1 {
 		return true
 	}
 
-	if engine.fixedDepth() {
-		return depth <= engine.options.maxDepth
-	} else if engine.clock.halt {
+	if depth > MaxDepth {
+		return false
+	} else if engine.pondering() { // Ignore time controls till "ponderhit".
+		return !engine.clock.stopped() && (!engine.fixedDepth() || depth <= engine.options.maxDepth)
+	} else if engine.options.infinite || engine.fixedNodes() {
+		return !engine.clock.stopped()
+	} else if engine.fixedDepth() {
+		return !engine.clock.stopped() && depth <= engine.options.maxDepth
+	} else if engine.clock.stopped() {
 		engine.debug("# Depth %02d Early out with %s\n", depth, move)
 		return false
 	}
 
 	// Stop deepening if it's the only move.
-	gen := NewRootGen(nil, depth)
+	gen := NewRootGen(game.position(), depth)
 	if gen.onlyMove() {
 		engine.debug("# Depth %02d Only move %s\n", depth, move)
 		return false
@@ -221,10 +370,11 @@ func (game *Game) keepThinking(depth int, move Move) bool {
 	// Stop if the time left is not enough to gets through the next iteration.
 	if engine.varyingTime() {
 		elapsed := engine.elapsed(time.Now())
-		remaining := engine.factor(depth, game.volatility).remaining()
+		volatility := game.workers[0].volatility
+		remaining := engine.factor(depth, volatility).remaining()
 
-		engine.debug("# Depth %02d Volatility %.2f Elapsed %s Remaining %s\n", depth, game.volatility, ms(elapsed), ms(remaining))
-		if elapsed > engine.factor(depth, game.volatility).remaining() {
+		engine.debug("# Depth %02d Volatility %.2f Elapsed %s Remaining %s\n", depth, volatility, ms(elapsed), ms(remaining))
+		if elapsed > engine.factor(depth, volatility).remaining() {
 			engine.debug("# Depth %02d Bailing out with %s\n", depth, move)
 			return false
 		}
@@ -234,10 +384,12 @@ func (game *Game) keepThinking(depth int, move Move) bool {
 }
 
 func (game *Game) printBestMove(move Move, duration int64) {
-	if engine.uci {
-		engine.uciBestMove(move, duration)
+	if game.engine.quiet {
+		return
+	} else if game.engine.uci {
+		game.engine.uciBestMove(game, move, duration)
 	} else {
-		engine.replBestMove(move)
+		game.engine.replBestMove(game, move)
 	}
 }
 
@@ -245,43 +397,18 @@ func (game *Game) printBestMove(move Move, duration int64) {
 // and advantage black is -score whereas in UCI +score is advantage current side
 // and -score is advantage opponent.
 func (game *Game) printPrincipal(depth, score, status int, duration int64) {
-	if engine.uci {
-		engine.uciPrincipal(depth, score, duration)
+	if game.engine.quiet {
+		return
+	} else if game.engine.uci {
+		game.engine.uciPrincipal(game, depth, score, duration)
 	} else {
 		if game.position().color == Black {
 			score = -score
 		}
-		engine.replPrincipal(depth, score, status, duration)
+		game.engine.replPrincipal(game, depth, score, status, duration)
 	}
 }
 
-func (game *Game) saveBest(ply int, move Move) *Game {
-	game.pv[ply] = append(game.pv[ply][0:ply], move)
-
-	next := ply + 1
-	if length := len(game.pv[next]); length > 0 {
-		game.pv[ply] = append(game.pv[ply], game.pv[next][next : length]...)
-	}
-
-	return game
-}
-
-func (game *Game) saveGood(depth int, move Move) *Game {
-	if ply := ply(); move.isQuiet() && move != game.killers[ply][0] {
-		game.killers[ply][1] = game.killers[ply][0]
-		game.killers[ply][0] = move
-		game.history[move.piece()][move.to()] += depth * depth
-	}
-
-	return game
-}
-
-// Checks whether the move is among good moves captured so far and returns its
-// history value.
-func (game *Game) good(move Move) int {
-	return game.history[move.piece()][move.to()]
-}
-
 func (game *Game) String() string {
 	return game.position().String()
 }
@@ -23,16 +23,11 @@ type MoveGen struct {
 	obvious  Move
 }
 
-// Pre-allocate move generator array (one entry per ply) to avoid garbage
-// collection overhead. Last entry serves for utility move generation, ex. when
-// converting string notations or determining a stalemate.
-var moveList [MaxPly+1]MoveGen
-
-// Returns "new" move generator for the given ply. Since move generator array
-// has been pre-allocated already we simply return a pointer to the existing
+// Returns "new" move generator for the given ply. Since worker's move generator
+// array has been pre-allocated already we simply return a pointer to the existing
 // array element re-initializing all its data.
 func NewGen(p *Position, ply int) (gen *MoveGen) {
-	gen = &moveList[ply]
+	gen = &p.worker.moveList[ply]
 	gen.p = p
 	gen.list = [128]MoveWithScore{}
 	gen.ply = ply
@@ -45,7 +40,7 @@ func NewGen(p *Position, ply int) (gen *MoveGen) {
 
 // Convenience method to return move generator for the current ply.
 func NewMoveGen(p *Position) *MoveGen {
-	return NewGen(p, ply())
+	return NewGen(p, p.worker.ply())
 }
 
 // Returns new move generator for the initial step of iterative deepening
@@ -54,7 +49,7 @@ func NewRootGen(p *Position, depth int) *MoveGen {
 	if depth == 1 {
 		return NewGen(p, 0) // Zero ply.
 	}
-	return &moveList[0]
+	return &p.worker.moveList[0]
 }
 
 func (gen *MoveGen) reset() *MoveGen {
@@ -142,13 +137,13 @@ func (gen *MoveGen) rank(bestMove Move) *MoveGen {
 		if move == bestMove {
 			gen.list[i].score = 0xFFFF
 		} else if move & isCapture != 0 {
-			gen.list[i].score = 8192 + move.value()
-		} else if move == game.killers[gen.ply][0] {
+			gen.list[i].score = 8192 + move.value(gen.p.params())
+		} else if move == gen.p.worker.killers[gen.ply][0] {
 			gen.list[i].score = 4096
-		} else if move == game.killers[gen.ply][1] {
+		} else if move == gen.p.worker.killers[gen.ply][1] {
 			gen.list[i].score = 2048
 		} else {
-			gen.list[i].score = game.good(move)
+			gen.list[i].score = gen.p.worker.good(move)
 		}
 	}
 
@@ -163,9 +158,9 @@ func (gen *MoveGen) quickRank() *MoveGen {
 
 	for i := gen.head; i < gen.tail; i++ {
 		if move := gen.list[i].move; move & isCapture != 0 {
-			gen.list[i].score = 8192 + move.value()
+			gen.list[i].score = 8192 + move.value(gen.p.params())
 		} else {
-			gen.list[i].score = game.good(move)
+			gen.list[i].score = gen.p.worker.good(move)
 		}
 	}
 
@@ -184,12 +179,12 @@ func (gen *MoveGen) rootRank(bestMove Move) *MoveGen {
 		current := &gen.list[i]
 		if current.move == bestMove {
 			best = i
-		} else if current.move == game.killers[gen.ply][0] {
+		} else if current.move == gen.p.worker.killers[gen.ply][0] {
 			killer = i
-		} else if current.move == game.killers[gen.ply][1] {
+		} else if current.move == gen.p.worker.killers[gen.ply][1] {
 			semikiller = i
 		}
-		current.score += game.good(current.move) >> 3
+		current.score += gen.p.worker.good(current.move) >> 3
 		if current.score > highest {
 			highest = current.score
 		}
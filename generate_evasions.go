@@ -47,11 +47,13 @@ func (gen *MoveGen) generateEvasions() *MoveGen {
 	// the attacking piece?
 	pawns := maskPawn[color][attackSquare] & p.outposts[pawn(color)]
 	for pawns != 0 {
-		move := NewMove(p, pawns.pop(), attackSquare)
+		from := pawns.pop()
 		if attackSquare >= A8 || attackSquare <= H1 {
-			move = move.promote(Queen)
+			mQ, mR, mB, mN := NewPromotion(p, from, attackSquare)
+			gen.add(mQ).add(mR).add(mB).add(mN)
+		} else {
+			gen.add(NewMove(p, from, attackSquare))
 		}
-		gen.add(move)
 	}
 
 	// Rare case when the check could be avoided by en-passant capture.
@@ -80,15 +82,17 @@ func (gen *MoveGen) generateEvasions() *MoveGen {
 	}
 	pawns &= block; jumps &= block
 
-	// Handle one-square pawn pushes: promote to Queen if reached last rank.
+	// Handle one-square pawn pushes: promote to all four pieces if reached
+	// last rank.
 	for pawns != 0 {
 		to := pawns.pop()
 		from := to - eight[color]
-		move := NewMove(p, from, to) // Can't cause en-passant.
 		if to >= A8 || to <= H1 {
-			move = move.promote(Queen)
+			mQ, mR, mB, mN := NewPromotion(p, from, to)
+			gen.add(mQ).add(mR).add(mB).add(mN)
+		} else {
+			gen.add(NewMove(p, from, to)) // Can't cause en-passant.
 		}
-		gen.add(move)
 	}
 
 	// Handle two-square pawn jumps that can cause en-passant.
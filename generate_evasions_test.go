@@ -174,7 +174,7 @@ func TestGenerate440(t *testing.T) {
 	game := NewGame(`Kf1,Qf3,Nf2`, `Ka1,b2`)
 	white := game.start()
 	black := NewMoveGen(white.makeMove(NewMove(white, F3, D1))).generateEvasions()
-	expect.Eq(t, black.allMoves(), `[Ka1-a2 b2-b1Q]`)
+	expect.Eq(t, black.allMoves(), `[Ka1-a2 b2-b1Q b2-b1R b2-b1B b2-b1N]`)
 }
 
 // Pawn promotion to block or capture.
@@ -182,13 +182,20 @@ func TestGenerate450(t *testing.T) {
 	game := NewGame(`Kf1,Qf3,Nf2`, `Ka1,b2,c2`)
 	white := game.start()
 	black := NewMoveGen(white.makeMove(NewMove(white, F3, D1))).generateEvasions()
-	expect.Eq(t, black.allMoves(), `[Ka1-a2 c2xd1Q b2-b1Q c2-c1Q]`)
+	expect.Eq(t, black.allMoves(), `[Ka1-a2 c2xd1Q c2xd1R c2xd1B c2xd1N b2-b1Q b2-b1R b2-b1B b2-b1N c2-c1Q c2-c1R c2-c1B c2-c1N]`)
 }
 
-// Pawn promotion to capture.
+// Pawn promotion to capture (including underpromotions).
 func TestGenerate460(t *testing.T) {
 	game := NewGame(`Kf1,Qf3,Nf2`, `Kc1,c2,d2`)
 	white := game.start()
 	black := NewMoveGen(white.makeMove(NewMove(white, F3, D1))).generateEvasions()
-	expect.Eq(t, black.allMoves(), `[Kc1-b2 c2xd1Q]`)
+	expect.Eq(t, black.allMoves(), `[Kc1-b2 c2xd1Q c2xd1R c2xd1B c2xd1N]`)
+}
+
+// Pawn promotion to block the check (including underpromotions).
+func TestGenerate470(t *testing.T) {
+	game := NewGame(`Kc8,e7`, `Kd1,Rf8`)
+	white := NewMoveGen(game.start()).generateEvasions()
+	expect.Eq(t, white.allMoves(), `[Kc8-b7 Kc8-c7 Kc8-d7 e7xf8Q e7xf8R e7xf8B e7xf8N e7-e8Q e7-e8R e7-e8B e7-e8N]`)
 }
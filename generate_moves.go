@@ -4,8 +4,13 @@
 
 package donna
 
-func (gen *MoveGen) generateRootMoves() *MoveGen {
+// Generates root moves. Search moves, if any, restrict the root moves to the
+// ones that are valid in current position.
+func (gen *MoveGen) generateRootMoves(searchMoves ...Move) *MoveGen {
 	gen.generateAllMoves()
+	if len(searchMoves) > 0 {
+		gen.restrict(searchMoves)
+	}
 	if gen.onlyMove() {
 		return gen
 	}
@@ -19,9 +24,36 @@ func (gen *MoveGen) generateRootMoves() *MoveGen {
 	return gen
 }
 
+// Removes generated moves that are either invalid or not among the given moves.
+// The list stays intact if none of the given moves is valid.
+func (gen *MoveGen) restrict(moves []Move) *MoveGen {
+	matches := func(move Move) bool {
+		for _, someMove := range moves {
+			if move == someMove {
+				return gen.isValid(move)
+			}
+		}
+		return false
+	}
+
+	found := false
+	for move := gen.NextMove(); move != 0 && !found; move = gen.NextMove() {
+		found = matches(move)
+	}
+	if found {
+		for move := gen.reset().NextMove(); move != 0; move = gen.NextMove() {
+			if !matches(move) {
+				gen.remove()
+			}
+		}
+	}
+
+	return gen.reset()
+}
+
 func (gen *MoveGen) rearrangeRootMoves() *MoveGen {
-	if len(game.rootpv) > 0 {
-		return gen.reset().rootRank(game.rootpv[0])
+	if rootpv := gen.p.worker.rootpv; len(rootpv) > 0 {
+		return gen.reset().rootRank(rootpv[0])
 	}
 	return gen.reset().rootRank(Move(0))
 }
@@ -79,10 +111,10 @@ func (gen *MoveGen) kingMoves(color uint8) *MoveGen {
 
 		kingside, queenside := gen.p.canCastle(color)
 		if kingside {
-			gen.moveKing(square, bit[G1 + 56 * color])
+			gen.add(NewCastle(gen.p, square, G1 + 56 * int(color)))
 		}
 		if queenside {
-			gen.moveKing(square, bit[C1 + 56 * color])
+			gen.add(NewCastle(gen.p, square, C1 + 56 * int(color)))
 		}
 	}
 	return gen
@@ -68,3 +68,24 @@ func TestGenerate210(t *testing.T) {
 	black := NewMoveGen(p).generateMoves().validOnly()
 	expect.Eq(t, black.allMoves(), `[Ka8-a7 Ka8-b7 Ka8-b8]`)
 }
+
+// Root moves restricted by search moves.
+func TestGenerate220(t *testing.T) {
+	p := NewGame(`Ke1,Qe2,d2`, `Ke8,e4`).start()
+	p = p.makeMove(NewEnpassant(p, D2, D4))
+
+	// Invalid e4xd3 en-passant capture gets removed too.
+	black := NewRootGen(p, 1).generateRootMoves(NewMove(p, E8, D7), NewMove(p, E8, F8), NewMove(p, E4, D3))
+	expect.Eq(t, black.size(), 2)
+	expect.True(t, black.amongValid(NewMove(p, E8, D7)))
+	expect.True(t, black.reset().amongValid(NewMove(p, E8, F8)))
+}
+
+// None of the search moves is valid: all root moves stay.
+func TestGenerate230(t *testing.T) {
+	p := NewGame(`Ke1,Qe2,d2`, `Ke8,e4`).start()
+	p = p.makeMove(NewEnpassant(p, D2, D4))
+
+	black := NewRootGen(p, 1).generateRootMoves(NewMove(p, E4, D3))
+	expect.Eq(t, black.size(), 6)
+}
@@ -52,16 +52,18 @@ var (
 	// Most-significant bit (MSB) lookup table.
 	msbLookup[256]int
 
-	// Precomputed database of material imbalance scores, evaluation flags,
-	// and endgame handlers.
+	// Precomputed database of game phases, evaluation flags, and endgame
+	// handlers. Material imbalance scores depend on evaluation parameters
+	// so they are kept along with the parameters.
 	materialBase [2*2*3*3*3*3*3*3*9*9]MaterialEntry
 )
 
 func init() {
 	initMasks()
+	initTablebase()
 	initArrays()
-	initPST()
 	initMaterial()
+	defaultParams.init()
 }
 
 func initMasks() {
@@ -193,27 +195,73 @@ func initArrays() {
 	}
 }
 
-func initPST() {
+func (params *Params) initPST() {
+	pst := &params.pst
 	for square := A1; square <= H8; square++ {
 
 		// White pieces: flip square index since bonus points have been
 		// set up from black's point of view.
 		flip := square ^ A8
-		pst[Pawn]  [square].add(Score{bonusPawn  [0][flip], bonusPawn  [1][flip]}).add(valuePawn)
-		pst[Knight][square].add(Score{bonusKnight[0][flip], bonusKnight[1][flip]}).add(valueKnight)
-		pst[Bishop][square].add(Score{bonusBishop[0][flip], bonusBishop[1][flip]}).add(valueBishop)
-		pst[Rook]  [square].add(Score{bonusRook  [0][flip], bonusRook  [1][flip]}).add(valueRook)
-		pst[Queen] [square].add(Score{bonusQueen [0][flip], bonusQueen [1][flip]}).add(valueQueen)
-		pst[King]  [square].add(Score{bonusKing  [0][flip], bonusKing  [1][flip]})
+		pst[Pawn]  [square].add(Score{params.bonusPawn  [0][flip], params.bonusPawn  [1][flip]}).add(params.valuePawn)
+		pst[Knight][square].add(Score{params.bonusKnight[0][flip], params.bonusKnight[1][flip]}).add(params.valueKnight)
+		pst[Bishop][square].add(Score{params.bonusBishop[0][flip], params.bonusBishop[1][flip]}).add(params.valueBishop)
+		pst[Rook]  [square].add(Score{params.bonusRook  [0][flip], params.bonusRook  [1][flip]}).add(params.valueRook)
+		pst[Queen] [square].add(Score{params.bonusQueen [0][flip], params.bonusQueen [1][flip]}).add(params.valueQueen)
+		pst[King]  [square].add(Score{params.bonusKing  [0][flip], params.bonusKing  [1][flip]})
 
 		// Black pieces: use square index as is, and assign negative
 		// values so we could use white + black without extra condition.
-		pst[BlackPawn]  [square].subtract(Score{bonusPawn  [0][square], bonusPawn  [1][square]}).subtract(valuePawn)
-		pst[BlackKnight][square].subtract(Score{bonusKnight[0][square], bonusKnight[1][square]}).subtract(valueKnight)
-		pst[BlackBishop][square].subtract(Score{bonusBishop[0][square], bonusBishop[1][square]}).subtract(valueBishop)
-		pst[BlackRook]  [square].subtract(Score{bonusRook  [0][square], bonusRook  [1][square]}).subtract(valueRook)
-		pst[BlackQueen] [square].subtract(Score{bonusQueen [0][square], bonusQueen [1][square]}).subtract(valueQueen)
-		pst[BlackKing]  [square].subtract(Score{bonusKing  [0][square], bonusKing  [1][square]})
+		pst[BlackPawn]  [square].subtract(Score{params.bonusPawn  [0][square], params.bonusPawn  [1][square]}).subtract(params.valuePawn)
+		pst[BlackKnight][square].subtract(Score{params.bonusKnight[0][square], params.bonusKnight[1][square]}).subtract(params.valueKnight)
+		pst[BlackBishop][square].subtract(Score{params.bonusBishop[0][square], params.bonusBishop[1][square]}).subtract(params.valueBishop)
+		pst[BlackRook]  [square].subtract(Score{params.bonusRook  [0][square], params.bonusRook  [1][square]}).subtract(params.valueRook)
+		pst[BlackQueen] [square].subtract(Score{params.bonusQueen [0][square], params.bonusQueen [1][square]}).subtract(params.valueQueen)
+		pst[BlackKing]  [square].subtract(Score{params.bonusKing  [0][square], params.bonusKing  [1][square]})
+	}
+}
+
+// Builds all the tables derived from evaluation parameters.
+func (params *Params) init() *Params {
+	params.initValues()
+	params.initImbalance()
+	return params
+}
+
+// Rebuilds the tables derived from piece values and bonus arrays. This gets
+// called when evaluation parameters change after startup, ex. by the tuner.
+func (params *Params) initValues() {
+	params.pst = [14][64]Score{}
+	params.initPST()
+
+	for i, value := range []Score{ params.valuePawn, params.valueKnight, params.valueBishop, params.valueRook, params.valueQueen } {
+		piece := Pawn + i * 2
+		params.pieceValue[piece], params.pieceValue[piece | 1] = value.midgame, value.midgame
+		params.exchangeScores[piece], params.exchangeScores[piece | 1] = value.midgame, value.midgame
+	}
+	params.exchangeScores[King], params.exchangeScores[BlackKing] = params.valueQueen.midgame * 8, params.valueQueen.midgame * 8
+}
+
+// Computes material imbalance scores for all the material balances. The piece
+// counts are recovered from the material balance index.
+func (params *Params) initImbalance() {
+	params.imbalance = make([]Score, len(materialBase))
+	for index := range params.imbalance {
+		count := func(piece, limit int) int {
+			return index / materialBalance[piece] % limit
+		}
+		wP, bP := count(Pawn, 9), count(BlackPawn, 9)
+		wN, bN := count(Knight, 3), count(BlackKnight, 3)
+		wB, bB := count(Bishop, 3), count(BlackBishop, 3)
+		wR, bR := count(Rook, 3), count(BlackRook, 3)
+		wQ, bQ := count(Queen, 2), count(BlackQueen, 2)
+
+		if wQ != bQ || wR != bR || wB != bB || wN != bN || wP != bP {
+			white := params.imbalanceScore(wB/2, wP, wN, wB, wR, wQ,  bB/2, bP, bN, bB, bR, bQ)
+			black := params.imbalanceScore(bB/2, bP, bN, bB, bR, bQ,  wB/2, wP, wN, wB, wR, wQ)
+
+			adjustment := (white - black) / 32
+			params.imbalance[index] = Score{adjustment, adjustment}
+		}
 	}
 }
 
@@ -248,16 +296,6 @@ func initMaterial() {
 		// Set up evaluation flags and endgame handlers.
 		materialBase[index].flags,
 		materialBase[index].endgame = endgames(wP, wN, wB, wR, wQ, bP, bN, bB, bR, bQ)
-
-		// Compute material imbalance scores.
-		if wQ != bQ || wR != bR || wB != bB || wN != bN || wP != bP {
-			white := imbalance(wB/2, wP, wN, wB, wR, wQ,  bB/2, bP, bN, bB, bR, bQ)
-			black := imbalance(bB/2, bP, bN, bB, bR, bQ,  wB/2, wP, wN, wB, wR, wQ)
-
-			adjustment := (white - black) / 32
-			materialBase[index].score.midgame += adjustment
-			materialBase[index].score.endgame += adjustment
-		}
 										}
 									}
 								}
@@ -270,18 +308,20 @@ func initMaterial() {
 	}
 }
 
-// Simplified second-degree polynomial material imbalance by Tord Romstad.
-func imbalance(w2, wP, wN, wB, wR, wQ, b2, bP, bN, bB, bR, bQ int) int {
-	polynom := func(x, a, b, c int) int {
-		return a * (x * x) + (b + c) * x
+// Simplified second-degree polynomial material imbalance by Tord Romstad. The
+// coefficients are defined in data_evaluate.go.
+func (params *Params) imbalanceScore(w2, wP, wN, wB, wR, wQ, b2, bP, bN, bB, bR, bQ int) (score int) {
+	ours, theirs := [6]int{ w2, wP, wN, wB, wR, wQ }, [6]int{ b2, bP, bN, bB, bR, bQ }
+
+	for i, count := range ours {
+		linear := params.imbalanceLinear[i]
+		for j := 0; j < i; j++ {
+			linear += params.imbalanceOurs[i][j] * ours[j] + params.imbalanceTheirs[i][j] * theirs[j]
+		}
+		score += params.imbalanceQuadratic[i] * (count * count) + linear * count
 	}
 
-	return polynom(w2,    0, (   0                                                                                    ),  1852) +
-	       polynom(wP,    2, (  39*w2 +                                      37*b2                                    ),  -162) +
-	       polynom(wN,   -4, (  35*w2 + 271*wP +                             10*b2 +  62*bP                           ), -1122) +
-	       polynom(wB,    0, (   0*w2 + 105*wP +   4*wN +                    57*b2 +  64*bP +  39*bN                  ),  -183) +
-	       polynom(wR, -141, ( -27*w2 +  -2*wP +  46*wN + 100*wB +           50*b2 +  40*bP +  23*bN + -22*bB         ),   249) +
-	       polynom(wQ,    0, (-177*w2 +  25*wP + 129*wN + 142*wB + -137*wR + 98*b2 + 105*bP + -39*bN + 141*bB + 274*bR),  -154)
+	return
 }
 
 func endgames(wP, wN, wB, wR, wQ, bP, bN, bB, bR, bQ int) (flags uint8, endgame Function) {
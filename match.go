@@ -0,0 +1,452 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`fmt`
+	`math`
+	`os`
+	`strconv`
+	`strings`
+	`time`
+)
+
+// Match player: either Donna engine running in the same process or external
+// engine talking UCI protocol.
+type Player interface {
+	Name() string
+	NewGame() error
+	Go(fen string, moves []string, clock *MatchClock) (move string, score int, err error)
+	Close() error
+}
+
+// Time control of the match game, ex. 40 moves in 60 seconds plus 1 second
+// increment. Fixed depth, nodes, or time per move take precedence over the
+// clock if set.
+type TimeControl struct {
+	moves       int64 	// Number of moves per time control, 0 for the whole game.
+	time        int64 	// Time per time control in milliseconds.
+	increment   int64 	// Time increment per move in milliseconds.
+	moveTime    int64 	// Fixed time per move in milliseconds.
+	depth       int 	// Fixed search depth.
+	nodes       int 	// Fixed number of nodes.
+}
+
+// Game clock as seen by the side to move.
+type MatchClock struct {
+	tc          *TimeControl 	// Time control of the game.
+	timeLeft    [2]int64 		// Time left for White and Black in milliseconds.
+	movesToGo   int64 		// Moves to make till the next time control, if any.
+	color       uint8 		// Side to move.
+}
+
+// Match score from the first player's point of view.
+type MatchScore struct {
+	Wins        int
+	Losses      int
+	Draws       int
+}
+
+// Engine match: two players take turns playing White and Black starting from
+// the same opening position.
+type Match struct {
+	players     [2]Player 	// The first player plays White in even games.
+	openings    []string 	// Opening positions in FEN; each one is played twice.
+	games       int 	// Number of games to play.
+	tc          TimeControl // Time control for both players.
+	event       string 	// Event tag of the games.
+	pgnFile     string 	// PGN file to append finished games to.
+	drawNumber  int 	// Earliest move number to adjudicate a draw.
+	drawCount   int 	// Number of consecutive moves with draw score, 0 to disable.
+	drawScore   int 	// Maximum absolute score (in centipawns) considered a draw.
+	resignCount int 	// Number of consecutive moves with losing score, 0 to disable.
+	resignScore int 	// Minimum losing score (in centipawns) to resign.
+	maxMoves    int 	// Game is drawn once it reaches this number of moves.
+}
+
+// Default adjudication rules are similar to the ones commonly used with
+// cutechess-cli: draw after move 40 if the score stays within 0.1 of zero for
+// 8 moves, and resign if the score stays at -3.5 or below for 8 moves. The
+// games are capped at 400 moves since that's as many as the game tree can hold.
+func NewMatch(first, second Player, args ...interface{}) *Match {
+	match := &Match{players: [2]Player{ first, second }, games: 2, event: `Donna Match`}
+	match.tc = TimeControl{moves: 40, time: 60000, increment: 1000}
+	match.drawNumber, match.drawCount, match.drawScore = 40, 8, 10
+	match.resignCount, match.resignScore = 8, 350
+	match.maxMoves = 400
+
+	for i := 0; i < len(args); i += 2 {
+		switch value := args[i+1]; args[i] {
+		case `games`:
+			match.games = value.(int)
+		case `openings`:
+			match.openings = value.([]string)
+		case `tc`:
+			match.tc = value.(TimeControl)
+		case `event`:
+			match.event = value.(string)
+		case `pgnfile`:
+			match.pgnFile = value.(string)
+		case `drawnumber`:
+			match.drawNumber = value.(int)
+		case `drawcount`:
+			match.drawCount = value.(int)
+		case `drawscore`:
+			match.drawScore = value.(int)
+		case `resigncount`:
+			match.resignCount = value.(int)
+		case `resignscore`:
+			match.resignScore = value.(int)
+		case `maxmoves`:
+			match.maxMoves = max(1, min(value.(int), 400))
+		}
+	}
+
+	return match
+}
+
+// Parses time control string: "40/60+1" for 40 moves in 60 seconds with one
+// second increment, "60+0.5" or "60" for the whole game, and "movetime=500"
+// (milliseconds), "depth=8", or "nodes=100000" for fixed limits per move.
+func NewTimeControl(str string) (tc TimeControl, err error) {
+	if pair := strings.SplitN(str, `=`, 2); len(pair) == 2 {
+		n, err := strconv.Atoi(pair[1])
+		if err != nil || n <= 0 {
+			return tc, fmt.Errorf(`tc: invalid %s in "%s"`, pair[0], str)
+		}
+		switch pair[0] {
+		case `movetime`:
+			tc.moveTime = int64(n)
+		case `depth`:
+			tc.depth = n
+		case `nodes`:
+			tc.nodes = n
+		default:
+			return tc, fmt.Errorf(`tc: unknown limit "%s"`, pair[0])
+		}
+		return tc, nil
+	}
+
+	seconds := func(str string) (int64, error) {
+		value, err := strconv.ParseFloat(str, 64)
+		if err != nil || value < 0.0 {
+			return 0, fmt.Errorf(`tc: invalid time "%s"`, str)
+		}
+		return int64(value * 1000.0), nil
+	}
+
+	if slash := strings.Index(str, `/`); slash >= 0 {
+		if tc.moves, err = strconv.ParseInt(str[:slash], 10, 64); err != nil || tc.moves <= 0 {
+			return tc, fmt.Errorf(`tc: invalid number of moves in "%s"`, str)
+		}
+		str = str[slash+1:]
+	}
+	if plus := strings.Index(str, `+`); plus >= 0 {
+		if tc.increment, err = seconds(str[plus+1:]); err != nil {
+			return
+		}
+		str = str[:plus]
+	}
+	if tc.time, err = seconds(str); err == nil && tc.time == 0 {
+		err = fmt.Errorf(`tc: time can't be zero`)
+	}
+
+	return
+}
+
+// Returns true if the search is limited by the game clock rather than fixed
+// depth, nodes, or time per move.
+func (tc *TimeControl) clocked() bool {
+	return tc.moveTime == 0 && tc.depth == 0 && tc.nodes == 0
+}
+
+func (tc TimeControl) String() string {
+	switch {
+	case tc.moveTime > 0:
+		return fmt.Sprintf(`movetime=%d`, tc.moveTime)
+	case tc.depth > 0:
+		return fmt.Sprintf(`depth=%d`, tc.depth)
+	case tc.nodes > 0:
+		return fmt.Sprintf(`nodes=%d`, tc.nodes)
+	}
+
+	str := strconv.FormatFloat(float64(tc.time) / 1000.0, 'f', -1, 64)
+	if tc.moves > 0 {
+		str = fmt.Sprintf(`%d/%s`, tc.moves, str)
+	}
+	if tc.increment > 0 {
+		str += `+` + strconv.FormatFloat(float64(tc.increment) / 1000.0, 'f', -1, 64)
+	}
+	return str
+}
+
+// Plays the match and returns the score from the first player's point of
+// view. The callback, if any, gets invoked after each game.
+func (m *Match) Play(callback func(round int, pgn *Pgn, score MatchScore)) (score MatchScore, err error) {
+	for round := 0; round < m.games; round++ {
+		pgn, err := m.round(round, &score)
+		if err != nil {
+			return score, err
+		}
+		if callback != nil {
+			callback(round + 1, pgn, score)
+		}
+	}
+
+	return
+}
+
+// Plays the given round of the match and updates the score. Even rounds start
+// off next opening, and odd rounds replay it with colors reversed so that each
+// opening gets played by both players as White and as Black.
+func (m *Match) round(round int, score *MatchScore) (*Pgn, error) {
+	fen := ``
+	if len(m.openings) > 0 {
+		fen = m.openings[(round / 2) % len(m.openings)]
+	}
+
+	white, black := m.players[round % 2], m.players[(round + 1) % 2]
+	pgn, err := m.play(round + 1, fen, white, black)
+	if err != nil {
+		return nil, err
+	}
+	score.add(pgn.Result, round % 2 == 1)
+
+	if m.pgnFile != `` {
+		if err = appendPgn(m.pgnFile, pgn); err != nil {
+			return nil, err
+		}
+	}
+
+	return pgn, nil
+}
+
+// Plays single game and returns it along with the result. The game starts off
+// the given FEN or the initial position if FEN is empty.
+func (m *Match) play(round int, fen string, white, black Player) (*Pgn, error) {
+	players, engine := [2]Player{ white, black }, NewEngine()
+	game := engine.NewGame()
+	if fen != `` {
+		game = engine.NewGame(fen)
+	}
+	position, err := game.setup()
+	if err != nil {
+		return nil, err
+	}
+
+	pgn := (&Pgn{}).SetTag(`Event`, m.event).SetTag(`Site`, `?`).SetTag(`Date`, pgnDate())
+	pgn.SetTag(`Round`, strconv.Itoa(round)).SetTag(`White`, white.Name()).SetTag(`Black`, black.Name())
+	if fen != `` {
+		fen = position.fen()
+		pgn.SetTag(`SetUp`, `1`).SetTag(`FEN`, fen)
+	}
+	pgn.SetTag(`TimeControl`, m.tc.String())
+
+	for _, player := range players {
+		if err := player.NewGame(); err != nil {
+			return nil, err
+		}
+	}
+
+	clock := &MatchClock{tc: &m.tc, timeLeft: [2]int64{ m.tc.time, m.tc.time }}
+	moves, scores, made := []string{}, []int{}, [2]int64{}
+	result, termination := `*`, `normal`
+
+	for result == `*` {
+		color := position.color
+		clock.color = color
+		if m.tc.moves > 0 {
+			clock.movesToGo = m.tc.moves - made[color] % m.tc.moves
+		}
+
+		start := time.Now()
+		notation, score, err := players[color].Go(fen, moves, clock)
+		elapsed := since(start)
+
+		// Loss on time or when the player fails to make a valid move.
+		loss := func(reason string) {
+			result, termination = `1-0`, reason
+			if color == White {
+				result = `0-1`
+			}
+		}
+		if err != nil {
+			loss(`abandoned`)
+			break
+		}
+		if m.tc.clocked() {
+			if clock.timeLeft[color] -= elapsed; clock.timeLeft[color] < 0 {
+				loss(`time forfeit`)
+				break
+			}
+			made[color]++
+			clock.timeLeft[color] += m.tc.increment
+			if m.tc.moves > 0 && made[color] % m.tc.moves == 0 {
+				clock.timeLeft[color] += m.tc.time
+			}
+		}
+		// Note that the game tree keeps growing so we can't use move
+		// generator of the current ply.
+		move := Move(0)
+		if isNotation(notation) {
+			move = NewMoveFromNotation(position, notation)
+		}
+		if move == Move(0) || !NewGen(position, MaxPly).generateAllMoves().validOnly().amongValid(move) {
+			loss(`illegal move`)
+			break
+		}
+
+		pgn.add(position, move)
+		pgn.Moves[len(pgn.Moves) - 1].Comment = fmt.Sprintf(`%+.2f %.1fs`, float64(score) / 100.0, float64(elapsed) / 1000.0)
+		position = position.makeMove(move)
+		moves, scores = append(moves, notation), append(scores, score)
+
+		if result = position.result(); result == `*` {
+			result, termination = m.adjudicate(position, scores)
+		}
+	}
+
+	pgn.Result = result
+	pgn.SetTag(`Termination`, termination)
+
+	// Players that learn from game results, ex. by updating opening book.
+	for _, player := range players {
+		if learner, ok := player.(interface{ learn(string) }); ok {
+			learner.learn(result)
+		}
+	}
+
+	return pgn, nil
+}
+
+// Adjudicates the game based on the scores reported by the players after each
+// move (from the point of view of the player who has made the move).
+func (m *Match) adjudicate(p *Position, scores []int) (result, termination string) {
+	plies := len(scores)
+
+	// The player who has just moved resigns if its score stays low enough
+	// for the given number of moves.
+	if m.resignCount > 0 && plies >= m.resignCount * 2 - 1 {
+		resign := true
+		for i := plies - 1; i >= plies - m.resignCount * 2 + 1 && resign; i -= 2 {
+			resign = scores[i] <= -m.resignScore
+		}
+		if resign {
+			if p.color == White { // Black has just moved.
+				return `1-0`, `adjudication`
+			}
+			return `0-1`, `adjudication`
+		}
+	}
+
+	// Both players agree on a draw if their scores stay close to zero.
+	if m.drawCount > 0 && int(p.fullmove) >= m.drawNumber && plies >= m.drawCount * 2 {
+		draw := true
+		for i := plies - 1; i >= plies - m.drawCount * 2 && draw; i-- {
+			draw = abs(scores[i]) <= m.drawScore
+		}
+		if draw {
+			return `1/2-1/2`, `adjudication`
+		}
+	}
+
+	if int(p.fullmove) > m.maxMoves {
+		return `1/2-1/2`, `adjudication`
+	}
+
+	return `*`, `normal`
+}
+
+// Appends the game to PGN file, creating the file as necessary.
+func appendPgn(fileName string, pgn *Pgn) error {
+	file, err := os.OpenFile(fileName, os.O_CREATE | os.O_WRONLY | os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	_, err = file.WriteString(pgn.String() + "\n")
+	return err
+}
+
+// Updates the score with the game result. The first player is White unless
+// the colors have been reversed.
+func (s *MatchScore) add(result string, reversed bool) *MatchScore {
+	switch result {
+	case `1/2-1/2`:
+		s.Draws++
+	case `1-0`:
+		if reversed {
+			s.Losses++
+		} else {
+			s.Wins++
+		}
+	case `0-1`:
+		if reversed {
+			s.Wins++
+		} else {
+			s.Losses++
+		}
+	}
+	return s
+}
+
+// Tallies the results of the given player in PGN games. Games the player did
+// not take part in are ignored.
+func PgnScore(games []*Pgn, player string) (score MatchScore) {
+	for _, pgn := range games {
+		if pgn.Tag(`White`) == player {
+			score.add(pgn.Result, false)
+		} else if pgn.Tag(`Black`) == player {
+			score.add(pgn.Result, true)
+		}
+	}
+	return
+}
+
+func (s MatchScore) Games() int {
+	return s.Wins + s.Losses + s.Draws
+}
+
+// Returns percentage of points scored: 1 for win and 1/2 for draw.
+func (s MatchScore) Ratio() float64 {
+	if s.Games() == 0 {
+		return 0.0
+	}
+	return (float64(s.Wins) + float64(s.Draws) / 2.0) / float64(s.Games())
+}
+
+// Returns Elo difference between the players along with 95% confidence margin
+// based on the standard deviation of the game results.
+func (s MatchScore) Elo() (elo, margin float64) {
+	games := float64(s.Games())
+	if games == 0.0 {
+		return 0.0, 0.0
+	}
+
+	ratio := s.Ratio()
+	deviation := math.Sqrt((float64(s.Wins) * math.Pow(1.0 - ratio, 2) +
+	                        float64(s.Losses) * math.Pow(ratio, 2) +
+	                        float64(s.Draws) * math.Pow(0.5 - ratio, 2)) / games) / math.Sqrt(games)
+
+	// The margin is infinite when one of the players has scored all the
+	// points since there is no way to tell how much stronger it is.
+	elo = eloDifference(ratio)
+	if math.IsInf(elo, 0) {
+		return elo, math.Inf(1)
+	}
+	upper, lower := math.Min(ratio + 1.96 * deviation, 1.0), math.Max(ratio - 1.96 * deviation, 0.0)
+	return elo, (eloDifference(upper) - eloDifference(lower)) / 2.0
+}
+
+// Converts expected score to Elo difference.
+func eloDifference(ratio float64) float64 {
+	return 400.0 * math.Log10(ratio / (1.0 - ratio))
+}
+
+func (s MatchScore) String() string {
+	elo, margin := s.Elo()
+	return fmt.Sprintf(`+%d =%d -%d (%.1f%%) Elo %+.1f +/- %.1f`, s.Wins, s.Draws, s.Losses, s.Ratio() * 100.0, elo, margin)
+}
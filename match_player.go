@@ -0,0 +1,271 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`fmt`
+	`io`
+	`os/exec`
+	`strconv`
+	`strings`
+	`time`
+)
+
+// Donna engine playing in the same process. Each player must have its own
+// engine instance since the engine keeps search options and the clock.
+type EnginePlayer struct {
+	name        string 	// Player name as it appears in PGN.
+	engine      *Engine 	// Engine configuration (weights, cache size, etc.)
+	game        *Game 	// Current game.
+	position    *Position 	// Current position of the game.
+	fen         string 	// Starting position of the current game.
+	played      int 	// Number of moves made in the current game.
+}
+
+// External engine that talks UCI protocol. Engine output is read on its own
+// goroutine so that we could bail out if the engine stops responding.
+type UciPlayer struct {
+	name        string 	// Engine name as reported by "id name".
+	cmd         *exec.Cmd 	// Engine process.
+	stdin       io.WriteCloser
+	lines       chan string // Lines received from the engine.
+}
+
+// How long to wait for external engine to respond to commands other than "go".
+const uciTimeout = 10 * time.Second
+
+func NewEnginePlayer(name string, engine *Engine) *EnginePlayer {
+	engine.quiet = true
+	return &EnginePlayer{name: name, engine: engine}
+}
+
+func (ep *EnginePlayer) Name() string {
+	return ep.name
+}
+
+func (ep *EnginePlayer) NewGame() error {
+	ep.game, ep.position = nil, nil
+	return nil
+}
+
+// Catches up with the moves made since the last call and searches for the best
+// move with time controls set by the match clock. Returns the move in UCI
+// notation along with its score in centipawns.
+func (ep *EnginePlayer) Go(fen string, moves []string, clock *MatchClock) (move string, score int, err error) {
+	if ep.game == nil || ep.fen != fen || ep.played > len(moves) {
+		ep.game, ep.fen, ep.played = ep.engine.NewGame(), fen, 0
+		if fen != `` {
+			ep.game = ep.engine.NewGame(fen)
+		}
+		if ep.position, err = ep.game.setup(); err != nil {
+			return ``, 0, err
+		}
+	}
+	for _, notation := range moves[ep.played:] {
+		move := Move(0)
+		if isNotation(notation) {
+			move = NewMoveFromNotation(ep.position, notation)
+		}
+		if move == Move(0) || !NewGen(ep.position, MaxPly).generateAllMoves().validOnly().amongValid(move) {
+			ep.game = nil // Start over next time.
+			return ``, 0, fmt.Errorf(`%s: invalid move %s`, ep.name, notation)
+		}
+		ep.position = ep.position.makeMove(move)
+	}
+	ep.played = len(moves)
+
+	if tc := clock.tc; tc.clocked() {
+		ep.engine.varyingLimits(Options{timeLeft: clock.timeLeft[clock.color], timeInc: tc.increment, movesToGo: clock.movesToGo})
+	} else {
+		ep.engine.fixedLimit(Options{moveTime: tc.moveTime, maxDepth: tc.depth, maxNodes: tc.nodes})
+	}
+
+	ep.game.progress = func(depth, best int, move Move, duration int64) {
+		score = best * 100 / onePawn
+	}
+	if best := ep.game.Think(); best != Move(0) {
+		move = best.uci(ep.game)
+	}
+
+	return move, score, nil
+}
+
+// Lets the engine learn from the game result, ex. update opening book moves.
+func (ep *EnginePlayer) learn(result string) {
+	if ep.game != nil {
+		ep.game.learn(result)
+	}
+}
+
+func (ep *EnginePlayer) Close() error {
+	ep.game, ep.position = nil, nil
+	return nil
+}
+
+// Starts external UCI engine and waits till it's ready to play. UCI options
+// are set right after the "uci" command, ex. { "Hash": "64" }.
+func NewUciPlayer(options map[string]string, command string, args ...string) (*UciPlayer, error) {
+	up := &UciPlayer{name: command, cmd: exec.Command(command, args...), lines: make(chan string, 256)}
+	stdout, err := up.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if up.stdin, err = up.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	if err = up.cmd.Start(); err != nil {
+		return nil, err
+	}
+
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			up.lines <- strings.TrimSpace(scanner.Text())
+		}
+		close(up.lines)
+	}()
+
+	up.send(`uci`)
+	err = up.expect(`uciok`, uciTimeout, func(line string) {
+		if strings.HasPrefix(line, `id name `) {
+			up.name = line[8:]
+		}
+	})
+	for name, value := range options {
+		up.send(`setoption name %s value %s`, name, value)
+	}
+	if err == nil {
+		err = up.ready()
+	}
+	if err != nil {
+		up.Close()
+		return nil, err
+	}
+
+	return up, nil
+}
+
+func (up *UciPlayer) Name() string {
+	return up.name
+}
+
+func (up *UciPlayer) NewGame() error {
+	up.send(`ucinewgame`)
+	return up.ready()
+}
+
+// Sends the position along with the clock to the engine and waits for the best
+// move. Unless the search is limited by depth or nodes we give up once the
+// time runs out.
+func (up *UciPlayer) Go(fen string, moves []string, clock *MatchClock) (move string, score int, err error) {
+	position := `position startpos`
+	if fen != `` {
+		position = `position fen ` + fen
+	}
+	if len(moves) > 0 {
+		position += ` moves ` + strings.Join(moves, ` `)
+	}
+	up.send(position)
+
+	timeout := time.Duration(0)
+	switch tc := clock.tc; {
+	case tc.moveTime > 0:
+		up.send(`go movetime %d`, tc.moveTime)
+		timeout = time.Duration(tc.moveTime) * time.Millisecond + uciTimeout
+	case tc.depth > 0:
+		up.send(`go depth %d`, tc.depth)
+	case tc.nodes > 0:
+		up.send(`go nodes %d`, tc.nodes)
+	default:
+		limits := fmt.Sprintf(`go wtime %d btime %d winc %d binc %d`, clock.timeLeft[White], clock.timeLeft[Black], tc.increment, tc.increment)
+		if clock.movesToGo > 0 {
+			limits += fmt.Sprintf(` movestogo %d`, clock.movesToGo)
+		}
+		up.send(limits)
+		timeout = time.Duration(clock.timeLeft[clock.color]) * time.Millisecond + uciTimeout
+	}
+
+	// Pick the score from the last "info" line before "bestmove". Mate
+	// scores get converted to large centipawn values.
+	err = up.expect(`bestmove`, timeout, func(line string) {
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[0] == `bestmove` {
+			move = fields[1]
+			return
+		}
+		for i := 0; i < len(fields) - 2; i++ {
+			if fields[i] != `score` {
+				continue
+			}
+			if n, err := strconv.Atoi(fields[i+2]); err == nil {
+				switch {
+				case fields[i+1] == `cp`:
+					score = n
+				case fields[i+1] == `mate` && n > 0:
+					score = 32000 - n
+				case fields[i+1] == `mate`:
+					score = -32000 - n
+				}
+			}
+		}
+	})
+
+	return move, score, err
+}
+
+// Asks the engine to quit and kills it if it doesn't.
+func (up *UciPlayer) Close() error {
+	up.send(`quit`)
+	up.stdin.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- up.cmd.Wait()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(uciTimeout):
+		up.cmd.Process.Kill()
+		return <-done
+	}
+}
+
+func (up *UciPlayer) send(format string, args ...interface{}) *UciPlayer {
+	fmt.Fprintf(up.stdin, format + "\n", args...)
+	return up
+}
+
+func (up *UciPlayer) ready() error {
+	up.send(`isready`)
+	return up.expect(`readyok`, uciTimeout, nil)
+}
+
+// Reads engine output till the line that starts with the given token. Zero
+// timeout means wait as long as it takes.
+func (up *UciPlayer) expect(token string, timeout time.Duration, callback func(string)) error {
+	var expired <-chan time.Time
+	if timeout > 0 {
+		expired = time.After(timeout)
+	}
+
+	for {
+		select {
+		case line, ok := <-up.lines:
+			if !ok {
+				return fmt.Errorf(`uci: %s has quit while waiting for "%s"`, up.name, token)
+			}
+			if callback != nil {
+				callback(line)
+			}
+			if strings.HasPrefix(line, token) {
+				return nil
+			}
+		case <-expired:
+			return fmt.Errorf(`uci: %s has not responded with "%s"`, up.name, token)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`github.com/michaeldv/donna/expect`
+	`io/ioutil`
+	`math`
+	`os`
+	`strings`
+	`testing`
+)
+
+// Time control parsing.
+func TestMatch000(t *testing.T) {
+	tc, err := NewTimeControl(`40/60+1`)
+	expect.Eq(t, err, nil)
+	expect.Eq(t, tc.moves, int64(40))
+	expect.Eq(t, tc.time, int64(60000))
+	expect.Eq(t, tc.increment, int64(1000))
+	expect.True(t, tc.clocked())
+	expect.Eq(t, tc.String(), `40/60+1`)
+}
+
+func TestMatch010(t *testing.T) {
+	tc, _ := NewTimeControl(`10+0.1`)
+	expect.Eq(t, tc.moves, int64(0))
+	expect.Eq(t, tc.time, int64(10000))
+	expect.Eq(t, tc.increment, int64(100))
+	expect.Eq(t, tc.String(), `10+0.1`)
+
+	tc, _ = NewTimeControl(`0.5`)
+	expect.Eq(t, tc.time, int64(500))
+	expect.Eq(t, tc.String(), `0.5`)
+}
+
+func TestMatch020(t *testing.T) {
+	tc, _ := NewTimeControl(`movetime=250`)
+	expect.Eq(t, tc.moveTime, int64(250))
+	expect.False(t, tc.clocked())
+	expect.Eq(t, tc.String(), `movetime=250`)
+
+	tc, _ = NewTimeControl(`depth=3`)
+	expect.Eq(t, tc.depth, 3)
+	expect.Eq(t, tc.String(), `depth=3`)
+
+	tc, _ = NewTimeControl(`nodes=5000`)
+	expect.Eq(t, tc.nodes, 5000)
+	expect.Eq(t, tc.String(), `nodes=5000`)
+}
+
+func TestMatch030(t *testing.T) {
+	for _, str := range []string{ ``, `x`, `0`, `/60`, `40/`, `60+x`, `depth=x`, `depth=0`, `ply=5` } {
+		_, err := NewTimeControl(str)
+		expect.Ne(t, err, nil)
+	}
+}
+
+// Match score and Elo difference.
+func TestMatch100(t *testing.T) {
+	score := MatchScore{}
+	elo, margin := score.Elo()
+	expect.Eq(t, score.Games(), 0)
+	expect.Eq(t, elo, 0.0)
+	expect.Eq(t, margin, 0.0)
+}
+
+func TestMatch110(t *testing.T) {
+	score := MatchScore{Wins: 30, Losses: 30, Draws: 40}
+	elo, margin := score.Elo()
+	expect.Eq(t, score.Ratio(), 0.5)
+	expect.Eq(t, elo, 0.0)
+	expect.Eq(t, math.Floor(margin), 53.0)
+	expect.Eq(t, score.String(), `+30 =40 -30 (50.0%) Elo +0.0 +/- 53.2`)
+}
+
+func TestMatch120(t *testing.T) {
+	score := MatchScore{Wins: 50, Losses: 20, Draws: 30}
+	elo, margin := score.Elo()
+	expect.Eq(t, score.Ratio(), 0.65)
+	expect.Eq(t, math.Floor(elo), 107.0)
+	expect.True(t, margin > 50.0 && margin < 70.0)
+}
+
+func TestMatch121(t *testing.T) {
+	elo, margin := MatchScore{Wins: 3}.Elo()
+	expect.True(t, math.IsInf(elo, 1))
+	expect.True(t, math.IsInf(margin, 1))
+	elo, margin = MatchScore{Losses: 1, Draws: 1}.Elo()
+	expect.True(t, math.IsInf(elo, 0) == false && elo < 0.0)
+	expect.True(t, math.IsInf(margin, 1))
+}
+
+func TestMatch130(t *testing.T) {
+	score := (&MatchScore{}).add(`1-0`, false).add(`1-0`, true).add(`0-1`, true).add(`1/2-1/2`, false).add(`*`, false)
+	expect.Eq(t, *score, MatchScore{Wins: 2, Losses: 1, Draws: 1})
+}
+
+// Tallying PGN games.
+func TestMatch140(t *testing.T) {
+	games, _ := ReadPgn(strings.NewReader(
+		"[White \"Donna\"]\n[Black \"GreKo\"]\n[Result \"1-0\"]\n\n1. e4 e5 1-0\n\n" +
+		"[White \"GreKo\"]\n[Black \"Donna\"]\n[Result \"1-0\"]\n\n1. e4 e5 1-0\n\n" +
+		"[White \"GreKo\"]\n[Black \"Donna\"]\n[Result \"0-1\"]\n\n1. e4 e5 0-1\n\n" +
+		"[White \"GreKo\"]\n[Black \"Other\"]\n[Result \"1-0\"]\n\n1. e4 e5 1-0\n"))
+	expect.Eq(t, PgnScore(games, `Donna`), MatchScore{Wins: 2, Losses: 1})
+	expect.Eq(t, PgnScore(games, `GreKo`), MatchScore{Wins: 2, Losses: 2})
+	expect.Eq(t, PgnScore(games, `Nobody`).Games(), 0)
+}
+
+// Adjudication: resign when the score stays low for a number of moves.
+func TestMatch200(t *testing.T) {
+	match := NewMatch(nil, nil, `resigncount`, 2, `resignscore`, 300)
+	p := NewGame(`Ke1,Qd1`, `M,Ke8`).start() // Black to move, i.e. White has just moved.
+	result, termination := match.adjudicate(p, []int{ -310, 0, -400 })
+	expect.Eq(t, result, `0-1`)
+	expect.Eq(t, termination, `adjudication`)
+
+	result, _ = match.adjudicate(p, []int{ -290, 0, -400 })
+	expect.Eq(t, result, `*`)
+}
+
+// Adjudication: draw when both scores stay close to zero.
+func TestMatch210(t *testing.T) {
+	match := NewMatch(nil, nil, `drawnumber`, 30, `drawcount`, 2, `drawscore`, 10)
+	p := NewGame(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 30`).start()
+	result, termination := match.adjudicate(p, []int{ 100, 5, -10, 0, 10 })
+	expect.Eq(t, result, `1/2-1/2`)
+	expect.Eq(t, termination, `adjudication`)
+
+	result, _ = match.adjudicate(p, []int{ 5, -11, 0, 10 })
+	expect.Eq(t, result, `*`)
+
+	p = NewGame(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 29`).start()
+	result, _ = match.adjudicate(p, []int{ 0, 0, 0, 0 })
+	expect.Eq(t, result, `*`)
+	// Default rules: scores within 10 centipawns for 8 moves after move 40.
+	match, scores := NewMatch(nil, nil), []int{}
+	for i := 0; i < 16; i++ {
+		scores = append(scores, 10 - 20 * (i % 2))
+	}
+	p = NewGame(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 40`).start()
+	result, _ = match.adjudicate(p, scores)
+	expect.Eq(t, result, `1/2-1/2`)
+
+	result, _ = match.adjudicate(p, append(scores[1:], 11))
+	expect.Eq(t, result, `*`)
+}
+
+// Adjudication: draw when the game gets too long.
+func TestMatch220(t *testing.T) {
+	match := NewMatch(nil, nil, `maxmoves`, 10)
+	result, _ := match.adjudicate(NewGame(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 10`).start(), []int{ 50 })
+	expect.Eq(t, result, `*`)
+	result, _ = match.adjudicate(NewGame(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 11`).start(), []int{ 50 })
+	expect.Eq(t, result, `1/2-1/2`)
+}
+
+// Self-play: each opening is played twice with colors reversed, and the games
+// are appended to PGN file.
+func TestMatch300(t *testing.T) {
+	file, _ := ioutil.TempFile(``, `donna`)
+	file.Close()
+	defer os.Remove(file.Name())
+
+	tc, _ := NewTimeControl(`depth=1`)
+	first := NewEnginePlayer(`First`, NewEngine())
+	second := NewEnginePlayer(`Second`, NewEngine(`mobility`, 150))
+	opening := `rnbqkb1r/1p3ppp/p2ppn2/8/3NP3/2N1BP2/PPP3PP/R2QKB1R b KQkq - 0 7`
+	match := NewMatch(first, second, `games`, 2, `tc`, tc, `openings`, []string{ opening }, `maxmoves`, 12, `pgnfile`, file.Name())
+
+	rounds := 0
+	score, err := match.Play(func(round int, pgn *Pgn, score MatchScore) {
+		rounds++
+		expect.Eq(t, round, rounds)
+		expect.Eq(t, score.Games(), round)
+	})
+	expect.Eq(t, err, nil)
+	expect.Eq(t, rounds, 2)
+	expect.Eq(t, score.Games(), 2)
+	expect.Eq(t, second.engine.weights[0], Score{150, 150})
+
+	content, _ := os.Open(file.Name())
+	defer content.Close()
+	games, _ := ReadPgn(content)
+	expect.Eq(t, len(games), 2)
+	expect.Eq(t, games[0].Tag(`White`), `First`)
+	expect.Eq(t, games[0].Tag(`Black`), `Second`)
+	expect.Eq(t, games[1].Tag(`White`), `Second`)
+	expect.Eq(t, games[1].Tag(`Black`), `First`)
+	expect.Eq(t, games[0].Tag(`FEN`), opening)
+	expect.Eq(t, games[0].Tag(`TimeControl`), `depth=1`)
+	expect.Eq(t, PgnScore(games, `First`), score)
+}
+
+// Resign adjudication ends hopeless game early.
+func TestMatch310(t *testing.T) {
+	tc, _ := NewTimeControl(`depth=2`)
+	first, second := NewEnginePlayer(`First`, NewEngine()), NewEnginePlayer(`Second`, NewEngine())
+	match := NewMatch(first, second, `games`, 1, `tc`, tc, `openings`, []string{ `4k3/8/8/8/8/8/8/Q3K3 w - - 0 1` }, `resigncount`, 2)
+
+	var game *Pgn
+	score, _ := match.Play(func(round int, pgn *Pgn, score MatchScore) {
+		game = pgn
+	})
+	expect.Eq(t, score, MatchScore{Wins: 1})
+	expect.Eq(t, game.Result, `1-0`)
+	expect.Eq(t, game.Tag(`Termination`), `adjudication`)
+	expect.True(t, len(game.Moves) < 10)
+}
+
+// Engine player refuses to play after an invalid move.
+func TestMatch320(t *testing.T) {
+	tc, _ := NewTimeControl(`depth=1`)
+	player := NewEnginePlayer(`Donna`, NewEngine())
+	clock := &MatchClock{tc: &tc}
+
+	_, _, err := player.Go(``, []string{ `e2e4`, `e2e5` }, clock)
+	expect.Ne(t, err, nil)
+	_, _, err = player.Go(``, []string{ `e2e4`, `zz99` }, clock)
+	expect.Ne(t, err, nil)
+
+	move, _, err := player.Go(``, []string{ `e2e4`, `e7e5` }, clock)
+	expect.Eq(t, err, nil)
+	expect.Ne(t, move, ``)
+}
+
+// Runs the test binary as UCI engine for TestMatch900.
+func TestMatchUciHelper(t *testing.T) {
+	if os.Getenv(`DONNA_UCI_HELPER`) != `1` {
+		return
+	}
+	NewEngine().Uci()
+	os.Exit(0)
+}
+
+// Playing against external UCI engine.
+func TestMatch900(t *testing.T) {
+	os.Setenv(`DONNA_UCI_HELPER`, `1`)
+	opponent, err := NewUciPlayer(map[string]string{ `Hash`: `1` }, os.Args[0], `-test.run=^TestMatchUciHelper$`)
+	os.Unsetenv(`DONNA_UCI_HELPER`)
+	expect.Eq(t, err, nil)
+	defer opponent.Close()
+	expect.Eq(t, opponent.Name(), `Donna ` + Version)
+
+	tc, _ := NewTimeControl(`depth=2`)
+	match := NewMatch(NewEnginePlayer(`Donna`, NewEngine()), opponent, `games`, 2, `tc`, tc, `maxmoves`, 5)
+
+	var games []*Pgn
+	score, err := match.Play(func(round int, pgn *Pgn, score MatchScore) {
+		games = append(games, pgn)
+	})
+	expect.Eq(t, err, nil)
+	expect.Eq(t, score.Games(), 2)
+	expect.Eq(t, games[1].Tag(`White`), `Donna ` + Version)
+	expect.Eq(t, games[0].Tag(`Termination`), `adjudication`)
+	expect.Eq(t, games[1].Tag(`Termination`), `adjudication`)
+	expect.True(t, len(games[1].Moves) >= 8)
+}
@@ -7,6 +7,7 @@ package donna
 import (
 	`bytes`
 	`regexp`
+	`strings`
 )
 
 const (
@@ -69,10 +70,17 @@ func NewMoveFromNotation(p *Position, e2e4 string) Move {
 	from := square(int(e2e4[1] - '1'), int(e2e4[0] - 'a'))
 	to := square(int(e2e4[3] - '1'), int(e2e4[2] - 'a'))
 
-	// Check if this is a castle.
-	if p.pieces[from].isKing() && abs(from - to) == 2 {
+	// Check if this is a castle. In Chess960 the castle is encoded as the king
+	// capturing its own rook.
+	if p.pieces[from].isKing() && abs(from - to) == 2 && !p.worker.game.castling.chess960 {
 		return NewCastle(p, from, to)
 	}
+	if p.pieces[from].isKing() && p.pieces[to] == rook(p.pieces[from].color()) {
+		if to > from {
+			return NewCastle(p, from, G1 + 56 * int(p.pieces[from].color()))
+		}
+		return NewCastle(p, from, C1 + 56 * int(p.pieces[from].color()))
+	}
 
 	// Special handling for pawn pushes because they might cause en-passant
 	// and result in promotion.
@@ -96,8 +104,15 @@ func NewMoveFromNotation(p *Position, e2e4 string) Move {
 	return NewMove(p, from, to)
 }
 
-// Decodes a string in long algebraic notation and returns a move. All invalid
-// moves are discarded and returned as Move(0).
+// Returns true if the string looks like a move in coordinate notation, ex. e2e4.
+func isNotation(e2e4 string) bool {
+	matched, _ := regexp.MatchString(`^[a-h][1-8][a-h][1-8][QqRrBbNn]?$`, e2e4)
+	return matched
+}
+
+// Decodes a string in long algebraic notation, ex. `Ng1-f3` or `e7e8Q`, or in
+// standard algebraic notation, ex. `Nf3`, `exd5`, `O-O`, or `e8=Q+`, and returns
+// a move. All invalid moves are discarded and returned as Move(0).
 func NewMoveFromString(p *Position, e2e4 string) (move Move, validMoves []Move) {
 	re := regexp.MustCompile(`([KkQqRrBbNn]?)([a-h])([1-8])[-x]?([a-h])([1-8])([QqRrBbNn]?)\+?[!\?]{0,2}`)
 	matches := re.FindStringSubmatch(e2e4)
@@ -139,21 +154,61 @@ func NewMoveFromString(p *Position, e2e4 string) (move Move, validMoves []Move)
 		return
 	}
 
-	// Special castle move notation.
-	if e2e4 == `0-0` || e2e4 == `0-0-0` {
-		kingside, queenside := p.canCastle(p.color)
-		if e2e4 == `0-0` && kingside {
-			from, to := int(p.king[p.color]), G1 + int(p.color) * A8
-			move = NewCastle(p, from, to)
-			return
+	// Standard algebraic notation including castles.
+	move = NewMoveFromSan(p, e2e4)
+	return
+}
+
+// Decodes a string in standard algebraic notation (SAN), ex. `Nf3`, `exd5`,
+// `O-O`, or `e8=Q+`, by matching it against valid moves in the position. Check
+// and annotation suffixes are ignored. Ambiguous and invalid moves are returned
+// as Move(0).
+func NewMoveFromSan(p *Position, san string) Move {
+	san = strings.TrimRight(san, `+#!?`)
+
+	// Castles are matched by the side the king is castling to.
+	if san == `O-O` || san == `0-0` || san == `O-O-O` || san == `0-0-0` {
+		for _, move := range NewGen(p, MaxPly).generateAllMoves().validOnly().allMoves() {
+			if move.isCastle() && (move.castleSide() == 0) == (len(san) == 3) {
+				return move
+			}
 		}
-		if e2e4 == `0-0-0` && queenside {
-			from, to := int(p.king[p.color]), C1 + int(p.color) * A8
-			move = NewCastle(p, from, to)
-			return
+		return Move(0)
+	}
+
+	matches := reSan.FindStringSubmatch(san)
+	if len(matches) == 0 {
+		return Move(0)
+	}
+
+	kind, promo := Pawn, 0
+	if matches[1] != `` {
+		kind = pieceKind(matches[1][0])
+	}
+	if matches[5] != `` {
+		promo = pieceKind(matches[5][len(matches[5]) - 1])
+	}
+	to := square(int(matches[4][1] - '1'), int(matches[4][0] - 'a'))
+
+	found := Move(0)
+	for _, move := range NewGen(p, MaxPly).generateAllMoves().validOnly().allMoves() {
+		from := move.from()
+		if move.to() != to || move.piece().kind() != kind || move.promo().kind() != promo || move.isCastle() {
+			continue
+		}
+		if matches[2] != `` && col(from) != int(matches[2][0] - 'a') {
+			continue
+		}
+		if matches[3] != `` && row(from) != int(matches[3][0] - '1') {
+			continue
 		}
+		if found != Move(0) { // Ambiguous move.
+			return Move(0)
+		}
+		found = move
 	}
-	return
+
+	return found
 }
 
 func (m Move) from() int {
@@ -190,8 +245,8 @@ func (m Move) promote(kind int) Move {
 }
 
 // Capture value based on most valueable victim/least valueable attacker.
-func (m Move) value() int {
-	return pieceValue[m.capture()] - m.piece().kind()
+func (m Move) value(params *Params) int {
+	return params.pieceValue[m.capture()] - m.piece().kind()
 }
 
 func (m Move) isCastle() bool {
@@ -232,34 +287,40 @@ func (m Move) notation() string {
 	return buffer.String()
 }
 
-// Returns string representation of the move in long algebraic notation using
-// ASCII characters only.
-func (m Move) str() (str string) {
-	if engine.fancy {
-		defer func() { engine.fancy = true }()
-		engine.fancy = false
+// Returns the move in UCI notation. In Chess960 the castle is represented as
+// the king capturing its own rook, ex. `g1h1`.
+func (m Move) uci(game *Game) string {
+	if m.isCastle() && game.castling.chess960 {
+		from, color := m.from(), m.color()
+		rook := game.castling.rook[color][m.castleSide()]
+		return string([]byte{ byte(col(from)) + 'a', byte(row(from)) + '1', byte(col(rook)) + 'a', byte(row(rook)) + '1' })
 	}
+	return m.notation()
+}
 
-	return m.String()
+// Returns string representation of the move in long algebraic notation using
+// ASCII characters only.
+func (m Move) String() string {
+	return m.format(false)
 }
 
-// By default the move is represented in long algebraic notation utilizing fancy
-// UTF-8 engine setting. For example: `♘g1-f3` (fancy), `e4xd5` or `h7-h8Q`.
-// This notation is used in tests, REPL, and when showing principal variation.
-func (m Move) String() (str string) {
+// Represents the move in long algebraic notation optionally using UTF-8 piece
+// figurines. For example: `♘g1-f3` (fancy), `e4xd5` or `h7-h8Q`. This notation
+// is used in tests, REPL, and when showing principal variation.
+func (m Move) format(fancy bool) string {
 	var buffer bytes.Buffer
 
 	from, to, piece, capture := m.split()
 	if m.isCastle() {
-		if to > from {
+		if m.castleSide() == 0 {
 			return `0-0`
 		}
 		return `0-0-0`
 	}
 
 	if !piece.isPawn() {
-		if engine.fancy { // Figurine notation is more readable with extra space.
-			buffer.WriteString(piece.String() + ` `)
+		if fancy { // Figurine notation is more readable with extra space.
+			buffer.WriteString(piece.format(fancy) + ` `)
 		} else {
 			buffer.WriteByte(piece.char())
 		}
@@ -279,3 +340,81 @@ func (m Move) String() (str string) {
 
 	return buffer.String()
 }
+
+// Returns string representation of the move in standard algebraic notation,
+// ex. `Nf3`, `Rad1`, `exd5`, `O-O`, or `e8=Q+`. The move is expected to be
+// valid in the given position.
+func (m Move) san(p *Position) string {
+	return m.formatSan(p, false)
+}
+
+// Represents the move in standard algebraic notation optionally using UTF-8
+// piece figurines, ex. `♘ f3` (fancy). This notation is used in REPL and when
+// showing principal variation.
+func (m Move) formatSan(p *Position, fancy bool) string {
+	var buffer bytes.Buffer
+
+	from, to, piece, capture := m.split()
+	if m.isCastle() {
+		if m.castleSide() == 0 {
+			buffer.WriteString(`O-O`)
+		} else {
+			buffer.WriteString(`O-O-O`)
+		}
+	} else {
+		if piece.isPawn() {
+			if capture != 0 {
+				buffer.WriteByte(byte(col(from)) + 'a')
+			}
+		} else {
+			if fancy { // Figurine notation is more readable with extra space.
+				buffer.WriteString(piece.format(fancy) + ` `)
+			} else {
+				buffer.WriteByte(piece.char())
+			}
+
+			// Disambiguate the move if other piece of the same kind can
+			// move to the same square.
+			ambiguous, sameCol, sameRow := false, false, false
+			for _, move := range NewGen(p, MaxPly).generateAllMoves().validOnly().allMoves() {
+				if move != m && move.to() == to && move.piece() == piece && !move.isCastle() {
+					ambiguous = true
+					sameCol = sameCol || col(move.from()) == col(from)
+					sameRow = sameRow || row(move.from()) == row(from)
+				}
+			}
+			if ambiguous {
+				if !sameCol {
+					buffer.WriteByte(byte(col(from)) + 'a')
+				} else if !sameRow {
+					buffer.WriteByte(byte(row(from)) + '1')
+				} else {
+					buffer.WriteByte(byte(col(from)) + 'a')
+					buffer.WriteByte(byte(row(from)) + '1')
+				}
+			}
+		}
+		if capture != 0 {
+			buffer.WriteByte('x')
+		}
+		buffer.WriteByte(byte(col(to)) + 'a')
+		buffer.WriteByte(byte(row(to)) + '1')
+		if m & isPromo != 0 {
+			buffer.WriteByte('=')
+			buffer.WriteByte(m.promo().char())
+		}
+	}
+
+	// Check or checkmate.
+	position := p.makeMove(m)
+	if position.isInCheck(position.color) {
+		if NewGen(position, MaxPly).generateAllMoves().anyValid() {
+			buffer.WriteByte('+')
+		} else {
+			buffer.WriteByte('#')
+		}
+	}
+	position.undoLastMove()
+
+	return buffer.String()
+}
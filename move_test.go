@@ -10,60 +10,60 @@ import(`github.com/michaeldv/donna/expect`; `testing`)
 func TestMove000(t *testing.T) {
 	game := NewGame(`Kd6,Qd1,Ra5,Nc3,Bc4,e4`, `Kh8,Qd5`)
 	p := game.start()
-	expect.Eq(t, NewMove(p, E4, D5).value(), 1258) // PxQ
-	expect.Eq(t, NewMove(p, C3, D5).value(), 1256) // NxQ
-	expect.Eq(t, NewMove(p, C4, D5).value(), 1254) // BxQ
-	expect.Eq(t, NewMove(p, A5, D5).value(), 1252) // RxQ
-	expect.Eq(t, NewMove(p, D1, D5).value(), 1250) // QxQ
-	expect.Eq(t, NewMove(p, D6, D5).value(), 1248) // KxQ
+	expect.Eq(t, NewMove(p, E4, D5).value(&defaultParams), 1258) // PxQ
+	expect.Eq(t, NewMove(p, C3, D5).value(&defaultParams), 1256) // NxQ
+	expect.Eq(t, NewMove(p, C4, D5).value(&defaultParams), 1254) // BxQ
+	expect.Eq(t, NewMove(p, A5, D5).value(&defaultParams), 1252) // RxQ
+	expect.Eq(t, NewMove(p, D1, D5).value(&defaultParams), 1250) // QxQ
+	expect.Eq(t, NewMove(p, D6, D5).value(&defaultParams), 1248) // KxQ
 }
 
 // PxR, NxR, BxR, RxR, QxR, KxR
 func TestMove010(t *testing.T) {
 	game := NewGame(`Kd6,Qd1,Ra5,Nc3,Bc4,e4`, `Kh8,Rd5`)
 	p := game.start()
-	expect.Eq(t, NewMove(p, E4, D5).value(), 633) // PxR
-	expect.Eq(t, NewMove(p, C3, D5).value(), 631) // NxR
-	expect.Eq(t, NewMove(p, C4, D5).value(), 629) // BxR
-	expect.Eq(t, NewMove(p, A5, D5).value(), 627) // RxR
-	expect.Eq(t, NewMove(p, D1, D5).value(), 625) // QxR
-	expect.Eq(t, NewMove(p, D6, D5).value(), 623) // KxR
+	expect.Eq(t, NewMove(p, E4, D5).value(&defaultParams), 633) // PxR
+	expect.Eq(t, NewMove(p, C3, D5).value(&defaultParams), 631) // NxR
+	expect.Eq(t, NewMove(p, C4, D5).value(&defaultParams), 629) // BxR
+	expect.Eq(t, NewMove(p, A5, D5).value(&defaultParams), 627) // RxR
+	expect.Eq(t, NewMove(p, D1, D5).value(&defaultParams), 625) // QxR
+	expect.Eq(t, NewMove(p, D6, D5).value(&defaultParams), 623) // KxR
 }
 
 // PxB, NxB, BxB, RxB, QxB, KxB
 func TestMove020(t *testing.T) {
 	game := NewGame(`Kd6,Qd1,Ra5,Nc3,Bc4,e4`, `Kh8,Bd5`)
 	p := game.start()
-	expect.Eq(t, NewMove(p, E4, D5).value(), 416) // PxB
-	expect.Eq(t, NewMove(p, C3, D5).value(), 414) // NxB
-	expect.Eq(t, NewMove(p, C4, D5).value(), 412) // BxB
-	expect.Eq(t, NewMove(p, A5, D5).value(), 410) // RxB
-	expect.Eq(t, NewMove(p, D1, D5).value(), 408) // QxB
-	expect.Eq(t, NewMove(p, D6, D5).value(), 406) // KxB
+	expect.Eq(t, NewMove(p, E4, D5).value(&defaultParams), 416) // PxB
+	expect.Eq(t, NewMove(p, C3, D5).value(&defaultParams), 414) // NxB
+	expect.Eq(t, NewMove(p, C4, D5).value(&defaultParams), 412) // BxB
+	expect.Eq(t, NewMove(p, A5, D5).value(&defaultParams), 410) // RxB
+	expect.Eq(t, NewMove(p, D1, D5).value(&defaultParams), 408) // QxB
+	expect.Eq(t, NewMove(p, D6, D5).value(&defaultParams), 406) // KxB
 }
 
 // PxN, NxN, BxN, RxN, QxN, KxN
 func TestMove030(t *testing.T) {
 	game := NewGame(`Kd6,Qd1,Ra5,Nc3,Bc4,e4`, `Kh8,Nd5`)
 	p := game.start()
-	expect.Eq(t, NewMove(p, E4, D5).value(), 406) // PxN
-	expect.Eq(t, NewMove(p, C3, D5).value(), 404) // NxN
-	expect.Eq(t, NewMove(p, C4, D5).value(), 402) // BxN
-	expect.Eq(t, NewMove(p, A5, D5).value(), 400) // RxN
-	expect.Eq(t, NewMove(p, D1, D5).value(), 398) // QxN
-	expect.Eq(t, NewMove(p, D6, D5).value(), 396) // KxN
+	expect.Eq(t, NewMove(p, E4, D5).value(&defaultParams), 406) // PxN
+	expect.Eq(t, NewMove(p, C3, D5).value(&defaultParams), 404) // NxN
+	expect.Eq(t, NewMove(p, C4, D5).value(&defaultParams), 402) // BxN
+	expect.Eq(t, NewMove(p, A5, D5).value(&defaultParams), 400) // RxN
+	expect.Eq(t, NewMove(p, D1, D5).value(&defaultParams), 398) // QxN
+	expect.Eq(t, NewMove(p, D6, D5).value(&defaultParams), 396) // KxN
 }
 
 // PxP, NxP, BxP, RxP, QxP, KxP
 func TestMove040(t *testing.T) {
 	game := NewGame(`Kd6,Qd1,Ra5,Nc3,Bc4,e4`, `Kh8,d5`)
 	p := game.start()
-	expect.Eq(t, NewMove(p, E4, D5).value(), 98) // PxP
-	expect.Eq(t, NewMove(p, C3, D5).value(), 96) // NxP
-	expect.Eq(t, NewMove(p, C4, D5).value(), 94) // BxP
-	expect.Eq(t, NewMove(p, A5, D5).value(), 92) // RxP
-	expect.Eq(t, NewMove(p, D1, D5).value(), 90) // QxP
-	expect.Eq(t, NewMove(p, D6, D5).value(), 88) // KxP
+	expect.Eq(t, NewMove(p, E4, D5).value(&defaultParams), 98) // PxP
+	expect.Eq(t, NewMove(p, C3, D5).value(&defaultParams), 96) // NxP
+	expect.Eq(t, NewMove(p, C4, D5).value(&defaultParams), 94) // BxP
+	expect.Eq(t, NewMove(p, A5, D5).value(&defaultParams), 92) // RxP
+	expect.Eq(t, NewMove(p, D1, D5).value(&defaultParams), 90) // QxP
+	expect.Eq(t, NewMove(p, D6, D5).value(&defaultParams), 88) // KxP
 }
 
 // NewMoveFromString: move from algebraic notation.
@@ -233,3 +233,73 @@ func TestMove340(t *testing.T) {
 	expect.Eq(t, bK & isCapture, Move(0))
 	expect.Ne(t, bP & isCapture, Move(0)) // Ne() for Pawn.
 }
+
+// Move from standard algebraic notation.
+func TestMove400(t *testing.T) {
+	p := NewGame().start()
+	expect.Eq(t, NewMoveFromSan(p, `e4`), NewPawnMove(p, E2, E4))
+	expect.Eq(t, NewMoveFromSan(p, `Nf3`), NewMove(p, G1, F3))
+	expect.Eq(t, NewMoveFromSan(p, `Nf3!?`), NewMove(p, G1, F3))
+	expect.Eq(t, NewMoveFromSan(p, `Ne2`), Move(0)) // Occupied.
+	expect.Eq(t, NewMoveFromSan(p, `e5`), Move(0))  // Invalid.
+}
+
+// Move to standard algebraic notation.
+func TestMove410(t *testing.T) {
+	p := NewGame(`Ke1,Ra1,Rh1,Nb1,Nf3,g7`, `Ke8,Qd2,Rh8`).start()
+	expect.Eq(t, NewMove(p, E1, D2).san(p), `Kxd2`)
+	expect.Eq(t, NewMove(p, F3, D2).san(p), `Nfxd2`)
+	expect.Eq(t, NewMove(p, G7, H8).promote(Queen).san(p), `gxh8=Q+`)
+}
+
+// Disambiguation by file, rank, and both.
+func TestMove420(t *testing.T) {
+	p := NewGame(`Kh1,Qa1,Qa3,Qc1,Ra8,Rh8,Nb1,Nf1`, `Kf5`).start()
+	expect.Eq(t, NewMove(p, B1, D2).san(p), `Nbd2`)
+	expect.Eq(t, NewMove(p, A8, D8).san(p), `Rad8`)
+	expect.Eq(t, NewMove(p, A1, B2).san(p), `Qa1b2`)
+	expect.Eq(t, NewMove(p, A3, A2).san(p), `Q3a2`)
+	expect.Eq(t, NewMove(p, H1, G1).san(p), `Kg1`)
+}
+
+// Castles, en-passant, promotions, and checkmate.
+func TestMove430(t *testing.T) {
+	p := NewGame(`Ke1,Ra1,Rh1,e5`, `Ke8,d7`).start()
+	expect.Eq(t, NewCastle(p, E1, G1).san(p), `O-O`)
+	expect.Eq(t, NewCastle(p, E1, C1).san(p), `O-O-O`)
+
+	p = p.makeMove(NewPawnMove(p, E1, E2))
+	p = p.makeMove(NewPawnMove(p, D7, D5))
+	expect.Eq(t, NewMove(p, E5, D6).san(p), `exd6`)
+
+	p = NewGame(`Kf6,a7`, `Kh8`).start()
+	expect.Eq(t, NewMove(p, A7, A8).promote(Queen).san(p), `a8=Q+`)
+	expect.Eq(t, NewMove(p, A7, A8).promote(Knight).san(p), `a8=N`)
+
+	p = NewGame(`Kg6,a7`, `Kh8`).start()
+	expect.Eq(t, NewMove(p, A7, A8).promote(Rook).san(p), `a8=R#`)
+}
+
+func TestMove440(t *testing.T) {
+	p := NewGame(`Ke1,Ra1,Rh1,Nb1,Nf3,a7`, `Ke8,Rb8`).start()
+	expect.Eq(t, NewMoveFromSan(p, `O-O`), NewCastle(p, E1, G1))
+	expect.Eq(t, NewMoveFromSan(p, `0-0-0`), Move(0)) // Knight on b1.
+	expect.Eq(t, NewMoveFromSan(p, `Nd2`), Move(0))   // Ambiguous.
+	expect.Eq(t, NewMoveFromSan(p, `Nbd2`), NewMove(p, B1, D2))
+	expect.Eq(t, NewMoveFromSan(p, `axb8=N`), NewMove(p, A7, B8).promote(Knight))
+	expect.Eq(t, NewMoveFromSan(p, `axb8Q+`), NewMove(p, A7, B8).promote(Queen))
+	expect.Eq(t, NewMoveFromSan(p, `axb8`), Move(0)) // Promotion piece is required.
+}
+
+// Move from string accepts both long and standard algebraic notations.
+func TestMove450(t *testing.T) {
+	p := NewGame().start()
+	move, _ := NewMoveFromString(p, `Ng1-f3`)
+	expect.Eq(t, move, NewMove(p, G1, F3))
+	move, _ = NewMoveFromString(p, `Nf3`)
+	expect.Eq(t, move, NewMove(p, G1, F3))
+	move, _ = NewMoveFromString(p, `d4`)
+	expect.Eq(t, move, NewPawnMove(p, D2, D4))
+	move, _ = NewMoveFromString(p, `Nf4`)
+	expect.Eq(t, move, Move(0))
+}
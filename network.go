@@ -0,0 +1,236 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`encoding/binary`
+	`fmt`
+	`io`
+	`os`
+)
+
+// Network inputs: 6 piece kinds of both colors on 64 squares. Each side sees
+// the board from its own perspective, i.e. its own pieces come first and the
+// board is flipped for Black.
+const networkInputs = 2 * 6 * 64
+
+// Quantization: hidden layer activations are clipped to [0, networkQA], and
+// output weights are scaled by networkQB.
+const (
+	networkQA = 255
+	networkQB = 64
+)
+
+// Network weights file starts with this signature.
+const networkMagic = `DNN1`
+
+// Efficiently updatable neural network: both sides' inputs are transformed by
+// the same hidden layer, and the resulting accumulators (side to move first)
+// feed the output neuron through clipped ReLU. Accumulators get updated
+// incrementally when a piece moves, gets captured or promoted.
+type Network struct {
+	hidden      int 	// Hidden layer size per perspective.
+	scale       int32 	// Output scale to convert network output to centipawns.
+	outputBias  int32 	// Output neuron bias.
+	weights     []int16 	// Hidden layer weights, [networkInputs][hidden].
+	biases      []int16 	// Hidden layer biases, [hidden].
+	output      []int16 	// Output weights, side to move accumulator first, [2][hidden].
+}
+
+// Hidden layer values for both perspectives indexed by color.
+type Accumulator [2][]int16
+
+// Creates network with all weights set to zero.
+func NewNetwork(hidden int, scale int32) *Network {
+	return &Network{
+		hidden:  hidden,
+		scale:   scale,
+		weights: make([]int16, networkInputs * hidden),
+		biases:  make([]int16, hidden),
+		output:  make([]int16, 2 * hidden),
+	}
+}
+
+// Reads network weights from the file.
+func LoadNetwork(fileName string) (*Network, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return ReadNetwork(bufio.NewReader(file))
+}
+
+// Reads network weights, all little-endian: the signature, hidden layer size,
+// output scale and output bias (int32 each), followed by hidden layer weights,
+// hidden layer biases, and output weights (int16 each).
+func ReadNetwork(r io.Reader) (*Network, error) {
+	magic := make([]byte, len(networkMagic))
+	if _, err := io.ReadFull(r, magic); err != nil || string(magic) != networkMagic {
+		return nil, fmt.Errorf(`network: invalid weights file signature`)
+	}
+
+	var header [3]int32
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, fmt.Errorf(`network: invalid weights file header`)
+	}
+	if header[0] < 1 || header[0] > 4096 {
+		return nil, fmt.Errorf(`network: invalid hidden layer size %d`, header[0])
+	}
+
+	n := NewNetwork(int(header[0]), header[1])
+	n.outputBias = header[2]
+	for _, data := range [][]int16{ n.weights, n.biases, n.output } {
+		if err := binary.Read(r, binary.LittleEndian, data); err != nil {
+			return nil, fmt.Errorf(`network: truncated weights file`)
+		}
+	}
+
+	return n, nil
+}
+
+// Writes network weights in the format expected by ReadNetwork.
+func (n *Network) Save(w io.Writer) error {
+	if _, err := io.WriteString(w, networkMagic); err != nil {
+		return err
+	}
+	for _, data := range []interface{}{ []int32{ int32(n.hidden), n.scale, n.outputBias }, n.weights, n.biases, n.output } {
+		if err := binary.Write(w, binary.LittleEndian, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns the score of the position from the side to move point of view. If
+// the position has no accumulator (ex. the network has been enabled after the
+// position was set up) it gets computed from scratch.
+func (n *Network) Evaluate(p *Position) int {
+	accumulator := p.accumulator
+	if accumulator == nil {
+		accumulator = n.refresh(p, nil)
+	}
+
+	sum := n.outputBias
+	ours, theirs := accumulator[p.color], accumulator[p.color ^ 1]
+	weights := n.output[:n.hidden]
+	for i, value := range ours {
+		sum += int32(clipped(value)) * int32(weights[i])
+	}
+	weights = n.output[n.hidden:]
+	for i, value := range theirs {
+		sum += int32(clipped(value)) * int32(weights[i])
+	}
+
+	score := int(int64(sum) * int64(n.scale) / (networkQA * networkQB))
+	return max(-Checkmate / 4, min(score, Checkmate / 4))
+}
+
+// Computes accumulator values for the position from scratch. New accumulator
+// is allocated if none was given.
+func (n *Network) refresh(p *Position, accumulator *Accumulator) *Accumulator {
+	if accumulator == nil {
+		accumulator = &Accumulator{ make([]int16, n.hidden), make([]int16, n.hidden) }
+	}
+	copy(accumulator[White], n.biases)
+	copy(accumulator[Black], n.biases)
+
+	board := p.board
+	for board != 0 {
+		square := board.pop()
+		n.add(accumulator, p.pieces[square], square)
+	}
+
+	return accumulator
+}
+
+// Returns hidden layer weights of the input that represents the piece on the
+// square as seen by the given side.
+func (n *Network) column(color uint8, piece Piece, square int) []int16 {
+	if color == Black {
+		square ^= A8 // Flip the board vertically.
+	}
+	input := ((int(piece.color() ^ color) * 6 + piece.kind() / 2 - 1) * 64 + square) * n.hidden
+
+	return n.weights[input : input + n.hidden]
+}
+
+// Adds the piece on the square to both perspectives.
+func (n *Network) add(accumulator *Accumulator, piece Piece, square int) {
+	for color := uint8(White); color <= Black; color++ {
+		values, weights := accumulator[color], n.column(color, piece, square)
+		for i := range values {
+			values[i] += weights[i]
+		}
+	}
+}
+
+// Removes the piece on the square from both perspectives.
+func (n *Network) remove(accumulator *Accumulator, piece Piece, square int) {
+	for color := uint8(White); color <= Black; color++ {
+		values, weights := accumulator[color], n.column(color, piece, square)
+		for i := range values {
+			values[i] -= weights[i]
+		}
+	}
+}
+
+// Moves the piece (or replaces a pawn with promoted piece) on both perspectives
+// in a single pass.
+func (n *Network) move(accumulator *Accumulator, piece Piece, from int, promo Piece, to int) {
+	for color := uint8(White); color <= Black; color++ {
+		values, source, target := accumulator[color], n.column(color, piece, from), n.column(color, promo, to)
+		for i := range values {
+			values[i] += target[i] - source[i]
+		}
+	}
+}
+
+// Clipped ReLU activation.
+func clipped(value int16) int16 {
+	if value < 0 {
+		return 0
+	} else if value > networkQA {
+		return networkQA
+	}
+	return value
+}
+
+// Copies accumulator values from another accumulator.
+func (a *Accumulator) copy(from *Accumulator) *Accumulator {
+	copy(a[White], from[White])
+	copy(a[Black], from[Black])
+	return a
+}
+
+// Gets the worker ready to evaluate with the network by allocating one
+// accumulator per tree node.
+func (w *Worker) useNetwork(network *Network) *Worker {
+	if w.network != network || len(w.accumulators) == 0 {
+		w.network = network
+		w.accumulators = make([]Accumulator, len(w.tree))
+		values := make([]int16, 2 * len(w.tree) * network.hidden)
+		for i := range w.accumulators {
+			offset := 2 * i * network.hidden
+			w.accumulators[i][White] = values[offset : offset + network.hidden]
+			w.accumulators[i][Black] = values[offset + network.hidden : offset + 2 * network.hidden]
+		}
+	}
+	return w
+}
+
+// Sets up the accumulator of newly created position if the engine evaluates
+// positions with the network.
+func (p *Position) setupAccumulator() *Position {
+	w := p.worker
+	if network, ok := w.game.engine.evaluator.(*Network); ok {
+		p.accumulator = w.useNetwork(network).network.refresh(p, &w.accumulators[w.node])
+	} else {
+		p.accumulator = nil
+	}
+	return p
+}
@@ -0,0 +1,140 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `bytes`; `os`; `testing`)
+
+// Bundled test network in testdata/material.nnue: hidden neurons count
+// material, pawn advancement, and centralized minor pieces of the perspective
+// side; the last neuron is a bias-only tempo bonus.
+func materialNetwork() *Network {
+	n := NewNetwork(4, 25 * networkQA)
+	values := []int16{ 0, 4, 13, 13, 20, 39, 0 }
+	for piece := Piece(Pawn); piece <= Piece(BlackKing); piece++ {
+		for square := A1; square <= H8; square++ {
+			if piece.color() != White {
+				continue // Opponent's pieces are counted by the other perspective.
+			}
+			weights := n.column(White, piece, square)
+			weights[0] = values[piece.kind() / 2]
+			if piece.isPawn() {
+				weights[1] = int16(row(square))
+			}
+			if (piece.isKnight() || piece.isBishop()) && row(square) >= 2 && row(square) <= 5 && col(square) >= 2 && col(square) <= 5 {
+				weights[2] = 2
+			}
+		}
+	}
+	n.biases[3] = 10
+	copy(n.output, []int16{ 64, 8, 8, 4, -64, -8, -8, 0 })
+
+	return n
+}
+
+func networkEngine(args ...interface{}) *Engine {
+	return NewEngine(append([]interface{}{ `evalfile`, `testdata/material.nnue`, `usenetwork`, true }, args...)...)
+}
+
+func TestNetwork000(t *testing.T) {
+	network, err := LoadNetwork(`testdata/material.nnue`)
+	expect.Eq(t, err, nil)
+	expect.Eq(t, network, materialNetwork())
+}
+
+func TestNetwork010(t *testing.T) {
+	buffer := &bytes.Buffer{}
+	expect.Eq(t, materialNetwork().Save(buffer), nil)
+	expect.Eq(t, buffer.Len(), 4 + 3 * 4 + (networkInputs * 4 + 4 + 8) * 2)
+
+	network, err := ReadNetwork(bytes.NewReader(buffer.Bytes()))
+	expect.Eq(t, err, nil)
+	expect.Eq(t, network, materialNetwork())
+
+	_, err = ReadNetwork(bytes.NewReader(buffer.Bytes()[:buffer.Len() - 1]))
+	expect.Eq(t, err.Error(), `network: truncated weights file`)
+	_, err = ReadNetwork(bytes.NewReader([]byte(`DNN0`)))
+	expect.Eq(t, err.Error(), `network: invalid weights file signature`)
+	_, err = ReadNetwork(bytes.NewReader([]byte("DNN1\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00")))
+	expect.Eq(t, err.Error(), `network: invalid hidden layer size 0`)
+	_, err = LoadNetwork(`testdata/missing.nnue`)
+	expect.True(t, os.IsNotExist(err))
+}
+
+// Initial position: tempo bonus only.
+func TestNetwork020(t *testing.T) {
+	p := networkEngine().NewGame().start()
+	expect.Ne(t, p.accumulator, nil)
+	expect.Eq(t, p.Evaluate(), 15)
+	p = networkEngine().NewGame(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR b KQkq - 0 1`).start()
+	expect.Eq(t, p.Evaluate(), 15)
+}
+
+// Extra queen: (39 * 64 +/- 10 * 4) * 6375 / (255 * 64).
+func TestNetwork030(t *testing.T) {
+	p := networkEngine().NewGame(`Ke1,Qd1`, `Ke8`).start()
+	expect.Eq(t, p.Evaluate(), 990)
+	p = networkEngine().NewGame(`Ke1,Qd1`, `M,Ke8`).start()
+	expect.Eq(t, p.Evaluate(), -959)
+}
+
+// Incrementally updated accumulators match the ones computed from scratch,
+// including captures, en-passant, promotions, and castles.
+func TestNetwork040(t *testing.T) {
+	for _, fen := range []string{
+		`r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1`,
+		`n1n5/PPPk4/8/8/8/8/4Kppp/5N1N b - - 0 1`,
+		`8/8/8/2k5/3Pp3/8/8/4K3 b - d3 0 1`,
+	} {
+		p := networkEngine().NewGame(fen).start()
+		network := p.worker.network
+		for _, move := range NewGen(p, MaxPly).generateAllMoves().validOnly().allMoves() {
+			position := p.makeMove(move)
+			expect.Eq(t, *position.accumulator, *network.refresh(position, nil))
+			for _, reply := range NewGen(position, MaxPly).generateAllMoves().validOnly().allMoves() {
+				position = position.makeMove(reply)
+				expect.Eq(t, *position.accumulator, *network.refresh(position, nil))
+				position = position.undoLastMove()
+			}
+			p = position.undoLastMove()
+		}
+	}
+}
+
+// Evaluation backend switch.
+func TestNetwork050(t *testing.T) {
+	engine := NewEngine(`evalfile`, `testdata/material.nnue`)
+	expect.Eq(t, engine.evaluator, Classical{})
+	expect.Ne(t, engine.network, nil)
+	p := engine.NewGame().start()
+	expect.Eq(t, p.accumulator, nil)
+	expect.Eq(t, p.Evaluate(), defaultParams.rightToMove.blended(p.worker.eval.material.phase))
+
+	engine = NewEngine(`usenetwork`, true)
+	expect.Eq(t, engine.evaluator, Classical{})
+	engine = NewEngine(`evalfile`, `testdata/missing.nnue`, `usenetwork`, true)
+	expect.Eq(t, engine.evaluator, Classical{})
+	expect.Eq(t, engine.evalFile, ``)
+
+	engine = networkEngine()
+	expect.Eq(t, engine.evaluator, engine.network)
+}
+
+// Position set up before the network got enabled gets evaluated from scratch.
+func TestNetwork060(t *testing.T) {
+	engine := NewEngine(`evalfile`, `testdata/material.nnue`)
+	p := engine.NewGame(`Ke1,Qd1`, `Ke8`).start()
+	engine.useNetwork = true
+	engine.pickEvaluator()
+	expect.Eq(t, p.accumulator, nil)
+	expect.Eq(t, p.Evaluate(), 990)
+}
+
+// Search with the network, including helper workers.
+func TestNetwork070(t *testing.T) {
+	engine := networkEngine(`depth`, 4, `threads`, 2, `quiet`, true)
+	game := engine.NewGame(`Kg1,Qd1,Ra1`, `Kg8,Rd8`)
+	game.start()
+	expect.Eq(t, game.Think().uci(game), `d1d8`)
+}
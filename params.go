@@ -0,0 +1,269 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`fmt`
+	`io`
+	`os`
+	`strconv`
+	`strings`
+)
+
+// Evaluation parameters along with the tables derived from them. Each engine
+// evaluates positions using its own parameters: the compiled-in defaults from
+// data_evaluate.go unless the engine has loaded parameters file.
+type Params struct {
+	valuePawn, valueKnight, valueBishop, valueRook, valueQueen Score
+	rightToMove, pawnBlocked, bishopPawn, bishopBoxed, bishopDanger Score
+	rookOnPawn, rookOnOpen, rookOnSemiOpen, rookOn7th, rookBoxed Score
+	queenOnPawn, queenOn7th, behindPawn, hangingAttack, kingByPawn, coverMissing Score
+
+	bonusPawn, bonusKnight, bonusBishop, bonusRook, bonusQueen, bonusKing [2][64]int
+	bonusPassedPawn, bonusSemiPassedPawn [8]Score
+	extraPassedPawn [8]int
+	extraKnight, extraBishop [64]int
+	bonusMinorThreat, bonusMajorThreat [6]Score
+	bonusKingThreat, bonusCloseCheck, bonusDistanceCheck [6]int
+	kingSafety, bonusSupportedPawn [64]int
+	penaltyPawnThreat [6]Score
+	penaltyDoubledPawn, penaltyIsolatedPawn, penaltyWeakIsolatedPawn [8]Score
+	penaltyBackwardPawn, penaltyWeakBackwardPawn [8]Score
+	penaltyCover [8]int
+	mobilityKnight [9]Score
+	mobilityBishop, mobilityRook, mobilityQueen [16]Score
+
+	imbalanceQuadratic, imbalanceLinear [6]int
+	imbalanceOurs, imbalanceTheirs [6][6]int
+
+	// Tables derived from the parameters above, see init.go.
+	pieceValue     [14]int 		// Piece values for most valueable victim/least valueable attacker.
+	exchangeScores [14]int 		// Piece values for static exchange evaluation.
+	pst            [14][64]Score 	// Piece/square table, including piece values.
+	imbalance      []Score 		// Material imbalance scores indexed by material balance.
+}
+
+// Evaluation parameter: its name as it appears in the parameter file, and
+// the pointer to the value it controls.
+type param struct {
+	name        string 	// Ex. "rookOnOpen.midgame" or "bonusPawn[1][12]".
+	value       *int 	// Pointer to the parameter value.
+}
+
+// Returns the list of all evaluation parameters in the order they are defined
+// in data_evaluate.go.
+func (params *Params) list() (list []param) {
+	score := func(name string, score *Score) {
+		list = append(list, param{name + `.midgame`, &score.midgame}, param{name + `.endgame`, &score.endgame})
+	}
+	scores := func(name string, values []Score) {
+		for i := range values {
+			score(fmt.Sprintf(`%s[%d]`, name, i), &values[i])
+		}
+	}
+	ints := func(name string, values []int) {
+		for i := range values {
+			list = append(list, param{fmt.Sprintf(`%s[%d]`, name, i), &values[i]})
+		}
+	}
+	bonus := func(name string, table *[2][64]int) {
+		ints(name + `[0]`, table[0][:])
+		ints(name + `[1]`, table[1][:])
+	}
+
+	score(`valuePawn`, &params.valuePawn)
+	score(`valueKnight`, &params.valueKnight)
+	score(`valueBishop`, &params.valueBishop)
+	score(`valueRook`, &params.valueRook)
+	score(`valueQueen`, &params.valueQueen)
+	score(`rightToMove`, &params.rightToMove)
+	score(`pawnBlocked`, &params.pawnBlocked)
+	score(`bishopPawn`, &params.bishopPawn)
+	score(`bishopBoxed`, &params.bishopBoxed)
+	score(`bishopDanger`, &params.bishopDanger)
+	score(`rookOnPawn`, &params.rookOnPawn)
+	score(`rookOnOpen`, &params.rookOnOpen)
+	score(`rookOnSemiOpen`, &params.rookOnSemiOpen)
+	score(`rookOn7th`, &params.rookOn7th)
+	score(`rookBoxed`, &params.rookBoxed)
+	score(`queenOnPawn`, &params.queenOnPawn)
+	score(`queenOn7th`, &params.queenOn7th)
+	score(`behindPawn`, &params.behindPawn)
+	score(`hangingAttack`, &params.hangingAttack)
+	score(`kingByPawn`, &params.kingByPawn)
+	score(`coverMissing`, &params.coverMissing)
+
+	bonus(`bonusPawn`, &params.bonusPawn)
+	bonus(`bonusKnight`, &params.bonusKnight)
+	bonus(`bonusBishop`, &params.bonusBishop)
+	bonus(`bonusRook`, &params.bonusRook)
+	bonus(`bonusQueen`, &params.bonusQueen)
+	bonus(`bonusKing`, &params.bonusKing)
+
+	scores(`bonusPassedPawn`, params.bonusPassedPawn[:])
+	scores(`bonusSemiPassedPawn`, params.bonusSemiPassedPawn[:])
+	ints(`extraPassedPawn`, params.extraPassedPawn[:])
+	ints(`extraKnight`, params.extraKnight[:])
+	ints(`extraBishop`, params.extraBishop[:])
+	scores(`bonusMinorThreat`, params.bonusMinorThreat[:])
+	scores(`bonusMajorThreat`, params.bonusMajorThreat[:])
+	ints(`bonusKingThreat`, params.bonusKingThreat[:])
+	ints(`bonusCloseCheck`, params.bonusCloseCheck[:])
+	ints(`bonusDistanceCheck`, params.bonusDistanceCheck[:])
+	ints(`kingSafety`, params.kingSafety[:])
+	ints(`bonusSupportedPawn`, params.bonusSupportedPawn[:])
+	scores(`penaltyPawnThreat`, params.penaltyPawnThreat[:])
+	scores(`penaltyDoubledPawn`, params.penaltyDoubledPawn[:])
+	scores(`penaltyIsolatedPawn`, params.penaltyIsolatedPawn[:])
+	scores(`penaltyWeakIsolatedPawn`, params.penaltyWeakIsolatedPawn[:])
+	scores(`penaltyBackwardPawn`, params.penaltyBackwardPawn[:])
+	scores(`penaltyWeakBackwardPawn`, params.penaltyWeakBackwardPawn[:])
+	ints(`penaltyCover`, params.penaltyCover[:])
+	scores(`mobilityKnight`, params.mobilityKnight[:])
+	scores(`mobilityBishop`, params.mobilityBishop[:])
+	scores(`mobilityRook`, params.mobilityRook[:])
+	scores(`mobilityQueen`, params.mobilityQueen[:])
+
+	ints(`imbalanceQuadratic`, params.imbalanceQuadratic[:])
+	ints(`imbalanceLinear`, params.imbalanceLinear[:])
+	for i := 1; i < 6; i++ { // Only the coefficients left of the diagonal are used.
+		ints(fmt.Sprintf(`imbalanceOurs[%d]`, i), params.imbalanceOurs[i][:i])
+		ints(fmt.Sprintf(`imbalanceTheirs[%d]`, i), params.imbalanceTheirs[i][:i])
+	}
+
+	return
+}
+
+// Returns a copy of the parameters that could be changed without affecting
+// the original.
+func (params *Params) clone() *Params {
+	clone := *params
+	clone.imbalance = append([]Score{}, params.imbalance...)
+	return &clone
+}
+
+// Writes all evaluation parameters using "name = value" format, one parameter
+// per line.
+func (params *Params) write(w io.Writer) error {
+	out := bufio.NewWriter(w)
+	fmt.Fprintf(out, "# Donna %s evaluation parameters.\n", Version)
+	for _, param := range params.list() {
+		fmt.Fprintf(out, "%s = %d\n", param.name, *param.value)
+	}
+	return out.Flush()
+}
+
+// Marks the beginning and the end of the search. Searches that get started
+// while the engine's parameters are being changed wait for the change to end.
+func (e *Engine) beginSearch() {
+	e.paramsLock.Lock()
+	for e.changing {
+		e.paramsReady.Wait()
+	}
+	e.searches++
+	e.paramsLock.Unlock()
+}
+
+func (e *Engine) endSearch() {
+	e.paramsLock.Lock()
+	e.searches--
+	e.paramsLock.Unlock()
+}
+
+// Grants exclusive access to the engine's evaluation parameters, or returns
+// an error if some of the engine's games is searching or the parameters are
+// being changed already.
+func (e *Engine) beginParamsChange() error {
+	e.paramsLock.Lock()
+	defer e.paramsLock.Unlock()
+
+	if e.searches > 0 {
+		return fmt.Errorf(`params: can't change evaluation parameters while searching`)
+	} else if e.changing {
+		return fmt.Errorf(`params: evaluation parameters are being changed`)
+	}
+	e.changing = true
+
+	return nil
+}
+
+// Ends parameters change. Cached pawn scores of all the engine's games have
+// old parameters applied so they get expired.
+func (e *Engine) endParamsChange() {
+	e.paramsLock.Lock()
+	e.changing = false
+	e.pawnToken++
+	e.paramsReady.Broadcast()
+	e.paramsLock.Unlock()
+}
+
+// Loads evaluation parameters from the file and rebuilds the tables derived
+// from them. Parameters missing in the file get their compiled-in values, and
+// empty file name restores the defaults. The parameters only affect the games
+// played by this engine, and loading fails if any of them is searching.
+func (e *Engine) LoadParams(fileName string) error {
+	if err := e.beginParamsChange(); err != nil {
+		return err
+	}
+	defer e.endParamsChange()
+
+	if fileName == `` {
+		e.params, e.paramsFile = &defaultParams, ``
+		return nil
+	}
+
+	file, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	params, err := readParams(file)
+	if err != nil {
+		return err
+	}
+	e.params, e.paramsFile = params, fileName
+
+	return nil
+}
+
+// Reads evaluation parameters in "name = value" format as written by the
+// tuner. The parameters start off with compiled-in defaults so that missing
+// ones keep their default values.
+func readParams(r io.Reader) (*Params, error) {
+	params := defaultParams
+	values := map[string]*int{}
+	for _, param := range params.list() {
+		values[param.name] = param.value
+	}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == `` || text[0] == '#' {
+			continue
+		}
+		pair := strings.SplitN(text, `=`, 2)
+		if len(pair) != 2 {
+			return nil, fmt.Errorf(`params: line %d: expected "name = value" in "%s"`, line, text)
+		}
+		name := strings.TrimSpace(pair[0])
+		pointer, ok := values[name]
+		if !ok {
+			return nil, fmt.Errorf(`params: line %d: unknown parameter "%s"`, line, name)
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(pair[1]))
+		if err != nil {
+			return nil, fmt.Errorf(`params: line %d: invalid value of "%s"`, line, name)
+		}
+		*pointer = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return params.init(), nil
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `bytes`; `io/ioutil`; `os`; `strings`; `testing`; `time`)
+
+// Writes parameters file and returns its name.
+func paramsFile(data string) string {
+	file, _ := ioutil.TempFile(``, `donna`)
+	file.WriteString(data)
+	file.Close()
+	return file.Name()
+}
+
+// Parameter names are unique and point to the actual values.
+func TestParams000(t *testing.T) {
+	params := defaultParams.clone()
+	names := map[string]*int{}
+	for _, param := range params.list() {
+		expect.Eq(t, names[param.name], (*int)(nil))
+		names[param.name] = param.value
+	}
+	expect.Eq(t, names[`rookOnOpen.midgame`], &params.rookOnOpen.midgame)
+	expect.Eq(t, names[`bonusPawn[1][12]`], &params.bonusPawn[1][12])
+	expect.Eq(t, names[`mobilityKnight[8].endgame`], &params.mobilityKnight[8].endgame)
+	expect.Eq(t, names[`kingSafety[63]`], &params.kingSafety[63])
+	expect.Eq(t, names[`imbalanceLinear[5]`], &params.imbalanceLinear[5])
+	expect.Eq(t, names[`imbalanceTheirs[5][4]`], &params.imbalanceTheirs[5][4])
+	expect.Eq(t, names[`imbalanceOurs[1][1]`], (*int)(nil))
+}
+
+// Reading parameters rebuilds derived tables and leaves the defaults intact.
+func TestParams010(t *testing.T) {
+	params, err := readParams(strings.NewReader("# Comment.\n\nvalueRook.midgame = 700\n  bonusRook[0][0]=-3\n"))
+	expect.Eq(t, err, nil)
+	expect.Eq(t, params.valueRook, Score{700, 639})
+	expect.Eq(t, params.bonusRook[0][0], -3)
+	expect.Eq(t, params.pst[BlackRook][A1].midgame, -697)
+	expect.Eq(t, params.pieceValue[Rook], 700)
+	expect.Eq(t, params.exchangeScores[BlackRook], 700)
+
+	expect.Eq(t, defaultParams.valueRook, Score{635, 639})
+	expect.Eq(t, defaultParams.pst[BlackRook][A1].midgame, -635 - defaultParams.bonusRook[0][A1])
+	expect.Eq(t, defaultParams.pieceValue[Rook], 635)
+}
+
+// Invalid parameters are rejected.
+func TestParams020(t *testing.T) {
+	for _, data := range []string{
+		"valueRook.midgame = 700\nvalueRook = 700\n",
+		"valueRook.midgame = 700\nvalueRook.midgame 700\n",
+		"valueRook.midgame = 700\nvalueRook.endgame = x\n",
+		"valueRook.midgame = 700\nimbalanceOurs[1][1] = 1\n",
+	} {
+		params, err := readParams(strings.NewReader(data))
+		expect.Ne(t, err, nil)
+		expect.Eq(t, params, (*Params)(nil))
+	}
+	expect.Eq(t, defaultParams.valueRook.midgame, 635)
+}
+
+// Written parameters can be read back.
+func TestParams030(t *testing.T) {
+	params := defaultParams.clone()
+	params.valueQueen.endgame, params.mobilityRook[3].midgame, params.imbalanceOurs[5][4] = 0, 0, 0
+
+	buffer := &bytes.Buffer{}
+	expect.Eq(t, params.write(buffer), nil)
+	expect.Contain(t, buffer.String(), "\nimbalanceQuadratic[4] = -141\n")
+
+	params, err := readParams(buffer)
+	expect.Eq(t, err, nil)
+	expect.Eq(t, params.valueQueen.endgame, 0)
+	expect.Eq(t, params.mobilityRook[3].midgame, 0)
+	expect.Eq(t, params.imbalanceOurs[5][4], 0)
+	expect.Eq(t, params.valueQueen.midgame, 1260)
+}
+
+// Changing imbalance coefficients rebuilds material imbalance table.
+func TestParams040(t *testing.T) {
+	p := NewGame(`Ke1,Bc1,Bf1,e2`, `Ke8,Nb8,Ng8,e7`).start()
+	score := defaultParams.imbalance[p.balance]
+
+	params, err := readParams(strings.NewReader("imbalanceLinear[0] = 5052\n"))
+	expect.Eq(t, err, nil)
+	expect.Eq(t, params.imbalance[p.balance].midgame, score.midgame + 100)
+	expect.Eq(t, defaultParams.imbalance[p.balance], score)
+}
+
+// Parameters loaded by one engine don't affect the others.
+func TestParams050(t *testing.T) {
+	name := paramsFile("rookOnOpen.midgame = 30\n")
+	defer os.Remove(name)
+
+	engine := NewEngine(`paramsfile`, name)
+	expect.Eq(t, engine.paramsFile, name)
+	expect.Eq(t, engine.params.rookOnOpen, Score{30, 10})
+	expect.Eq(t, NewEngine().params.rookOnOpen, Score{22, 10})
+	expect.Eq(t, defaultParams.rookOnOpen, Score{22, 10})
+
+	expect.Eq(t, engine.LoadParams(``), nil)
+	expect.Eq(t, engine.paramsFile, ``)
+	expect.Eq(t, engine.params.rookOnOpen, Score{22, 10})
+	expect.Ne(t, engine.LoadParams(name + `.missing`), nil)
+}
+
+// Each file is applied to the defaults rather than to the parameters loaded
+// before.
+func TestParams060(t *testing.T) {
+	first, second := paramsFile("rookOnOpen.midgame = 30\n"), paramsFile("rookOnOpen.endgame = 20\n")
+	defer os.Remove(first)
+	defer os.Remove(second)
+
+	engine := NewEngine()
+	expect.Eq(t, engine.LoadParams(first), nil)
+	expect.Eq(t, engine.params.rookOnOpen, Score{30, 10})
+	expect.Eq(t, engine.LoadParams(second), nil)
+	expect.Eq(t, engine.params.rookOnOpen, Score{22, 20})
+	expect.Eq(t, engine.LoadParams(second), nil)
+	expect.Eq(t, engine.params.rookOnOpen, Score{22, 20})
+}
+
+// Loading parameters expires cached pawn scores of all the engine's games.
+func TestParams070(t *testing.T) {
+	name := paramsFile("penaltyDoubledPawn[4].midgame = 112\npenaltyDoubledPawn[4].endgame = 124\n")
+	defer os.Remove(name)
+
+	engine := NewEngine()
+	one, two := engine.NewGame(`Ke1,Ra1,e2,e3`, `Ke8,Ra8,d7`), engine.NewGame(`Ke1,Ra1,e2,e3`, `Ke8,Ra8,d7`)
+	before := one.start().Evaluate()
+	expect.Eq(t, two.start().Evaluate(), before)
+
+	expect.Eq(t, engine.LoadParams(name), nil)
+	after := NewEngine(`paramsfile`, name).NewGame(`Ke1,Ra1,e2,e3`, `Ke8,Ra8,d7`).start().Evaluate()
+	expect.True(t, after < before)
+	expect.Eq(t, one.position().Evaluate(), after)
+	expect.Eq(t, two.position().Evaluate(), after)
+}
+
+// Parameters can't be changed while any of the engine's games is searching.
+func TestParams080(t *testing.T) {
+	engine, other := NewEngine(), NewEngine()
+	tuner, _ := engine.NewTuner(strings.NewReader("4k3/8/8/8/8/8/8/4K2R w - - 0 1 [1.0]\n"))
+
+	engine.beginSearch()
+	expect.Ne(t, engine.LoadParams(``), nil)
+	_, err := tuner.Tune(1, nil)
+	expect.Ne(t, err, nil)
+	expect.Eq(t, other.LoadParams(``), nil)
+	engine.endSearch()
+
+	expect.Eq(t, engine.LoadParams(``), nil)
+}
+
+// Search waits while the engine's parameters are being changed.
+func TestParams090(t *testing.T) {
+	engine := NewEngine(`depth`, 1, `quiet`, true)
+	expect.Eq(t, engine.beginParamsChange(), nil)
+	expect.Ne(t, engine.beginParamsChange(), nil)
+
+	done := make(chan Move)
+	go func() {
+		game := engine.NewGame(`Ke1,Rh1`, `Ke8`)
+		game.start()
+		done <- game.Think()
+	}()
+
+	select {
+	case <-done:
+		t.Error(`search didn't wait for parameters change`)
+		engine.endParamsChange()
+	case <-time.After(50 * time.Millisecond):
+		engine.endParamsChange()
+		expect.Ne(t, <-done, Move(0))
+	}
+}
@@ -0,0 +1,576 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`bytes`
+	`fmt`
+	`io`
+	`strconv`
+	`strings`
+	`time`
+)
+
+// PGN tag pair, ex. [Event "F/S Return Match"].
+type PgnTag struct {
+	Name        string 		// Tag name.
+	Value       string 		// Tag value without quotes.
+}
+
+// Move of the PGN game along with its annotations and alternative variations.
+type PgnMove struct {
+	Move        Move 		// The move itself.
+	San         string 		// The move in standard algebraic notation.
+	Nags        []int 		// Numeric annotation glyphs, ex. 1 for "!".
+	Preface     string 		// Comment preceding the first move of the line.
+	Comment     string 		// Comment following the move.
+	Variations  [][]PgnMove 	// Alternatives to the move, if any.
+}
+
+type Pgn struct {
+	Tags        []PgnTag 		// Tag pairs in the order they appear.
+	Moves       []PgnMove 		// Main line moves.
+	Result      string 		// Game termination marker: 1-0, 0-1, 1/2-1/2, or *.
+}
+
+// Seven tag roster that goes first when exporting PGN game.
+var pgnRoster = []string{ `Event`, `Site`, `Date`, `Round`, `White`, `Black`, `Result` }
+
+// Returns the value of the tag or empty string if the tag is not there.
+func (pgn *Pgn) Tag(name string) string {
+	for _, tag := range pgn.Tags {
+		if tag.Name == name {
+			return tag.Value
+		}
+	}
+	return ``
+}
+
+// Sets the value of existing tag, or adds new tag.
+func (pgn *Pgn) SetTag(name, value string) *Pgn {
+	for i := range pgn.Tags {
+		if pgn.Tags[i].Name == name {
+			pgn.Tags[i].Value = value
+			return pgn
+		}
+	}
+	pgn.Tags = append(pgn.Tags, PgnTag{name, value})
+
+	return pgn
+}
+
+// Returns true if the game is Chess960 as specified by the Variant tag, ex.
+// "chess 960" or "fischerandom".
+func (pgn *Pgn) chess960() bool {
+	variant := strings.ToLower(pgn.Tag(`Variant`))
+	return strings.Contains(variant, `960`) || strings.Contains(variant, `fischer`)
+}
+
+// Returns FEN of the game's starting position.
+func (pgn *Pgn) fen() string {
+	if fen := pgn.Tag(`FEN`); fen != `` {
+		return fen
+	}
+	return `rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1`
+}
+
+// Sets up the game's starting position in the given game.
+func (pgn *Pgn) start(game *Game) *Position {
+	game.initial = pgn.fen()
+	return game.start()
+}
+
+// Appends the move made in the given position to the main line.
+func (pgn *Pgn) add(p *Position, move Move) *Pgn {
+	pgn.Moves = append(pgn.Moves, PgnMove{Move: move, San: move.san(p)})
+	return pgn
+}
+
+// Replays the main line in the given game's position tree by making the moves
+// one by one. The callback, if any, gets called with each position before the
+// move is made, and it can stop the replay by returning false. Returns the last
+// position reached.
+func (pgn *Pgn) Walk(game *Game, callback func(*Position, Move) bool) *Position {
+	p := pgn.start(game)
+	if p == nil {
+		return nil
+	}
+
+	for _, move := range pgn.Moves {
+		if callback != nil && !callback(p, move.Move) {
+			break
+		}
+		p = p.makeMove(move.Move)
+	}
+
+	return p
+}
+
+// Exports the game in PGN format: the seven tag roster goes first followed by
+// other tags, and the movetext is wrapped at 80 characters.
+func (pgn *Pgn) String() string {
+	var buffer bytes.Buffer
+
+	tag := func(name, value string) {
+		value = strings.Replace(value, `\`, `\\`, -1)
+		value = strings.Replace(value, `"`, `\"`, -1)
+		buffer.WriteString(fmt.Sprintf("[%s \"%s\"]\n", name, value))
+	}
+
+	result := pgn.Result
+	if result == `` {
+		result = `*`
+	}
+
+	for _, name := range pgnRoster {
+		value := pgn.Tag(name)
+		switch {
+		case name == `Result`:
+			value = result
+		case value != ``:
+		case name == `Date`:
+			value = `????.??.??`
+		default:
+			value = `?`
+		}
+		tag(name, value)
+	}
+
+	NextTag:
+	for _, t := range pgn.Tags {
+		for _, name := range pgnRoster {
+			if t.Name == name {
+				continue NextTag
+			}
+		}
+		tag(t.Name, t.Value)
+	}
+	buffer.WriteByte('\n')
+
+	// Move number and side to move come from the starting position.
+	color, number := uint8(White), 1
+	if fields := strings.Fields(pgn.fen()); len(fields) > 1 {
+		if fields[1] == `b` {
+			color = Black
+		}
+		if len(fields) > 5 {
+			if n, err := strconv.Atoi(fields[5]); err == nil && n > 0 {
+				number = n
+			}
+		}
+	}
+
+	length := 0
+	for _, token := range append(pgn.movetext(pgn.Moves, color, number), result) {
+		if length > 0 && length + 1 + len(token) > 79 {
+			buffer.WriteByte('\n')
+			length = 0
+		} else if length > 0 {
+			buffer.WriteByte(' ')
+			length++
+		}
+		buffer.WriteString(token)
+		length += len(token)
+	}
+	buffer.WriteString("\n\n")
+
+	return buffer.String()
+}
+
+// Returns movetext tokens for the line of moves. Black's move number gets shown
+// at the beginning of the line and after comments and variations.
+func (pgn *Pgn) movetext(moves []PgnMove, color uint8, number int) (tokens []string) {
+	explicit := true
+	for _, move := range moves {
+		if move.Preface != `` {
+			tokens = append(tokens, strings.Fields(`{` + move.Preface + `}`)...)
+			explicit = true
+		}
+		if color == White {
+			tokens = append(tokens, fmt.Sprintf(`%d.`, number))
+		} else if explicit {
+			tokens = append(tokens, fmt.Sprintf(`%d...`, number))
+		}
+		tokens = append(tokens, move.San)
+		for _, nag := range move.Nags {
+			tokens = append(tokens, fmt.Sprintf(`$%d`, nag))
+		}
+		explicit = false
+
+		if move.Comment != `` {
+			tokens = append(tokens, strings.Fields(`{` + move.Comment + `}`)...)
+			explicit = true
+		}
+		for _, variation := range move.Variations {
+			if line := pgn.movetext(variation, color, number); len(line) > 0 {
+				line[0] = `(` + line[0]
+				line[len(line) - 1] += `)`
+				tokens = append(tokens, line...)
+				explicit = true
+			}
+		}
+
+		if color == Black {
+			number++
+		}
+		color ^= 1
+	}
+
+	return
+}
+
+// PGN tokens.
+const (
+	pgnEOF = iota
+	pgnTag
+	pgnComment
+	pgnOpen
+	pgnClose
+	pgnNag
+	pgnNumber
+	pgnResult
+	pgnSymbol
+)
+
+type pgnToken struct {
+	kind        int 		// Token type.
+	text        string 		// Token text, or tag name.
+	value       string 		// Tag value.
+}
+
+// PGN reader parses games one by one replaying their moves to resolve standard
+// algebraic notation, so that all the moves read are valid.
+type PgnReader struct {
+	reader      *bufio.Reader 	// PGN source.
+	game        *Game 		// Scratch game to replay the moves.
+	game960     *Game 		// Scratch game to replay Chess960 moves.
+	token       *pgnToken 		// Token pushed back by the parser.
+	last        byte 		// Last character read.
+}
+
+func NewPgnReader(r io.Reader) *PgnReader {
+	return &PgnReader{reader: bufio.NewReader(r), game: NewGame(), game960: NewEngine(`chess960`, true).NewGame(), last: '\n'}
+}
+
+// Reads all the games from PGN source.
+func ReadPgn(r io.Reader) (games []*Pgn, err error) {
+	reader := NewPgnReader(r)
+	for {
+		pgn, err := reader.Read()
+		if err == io.EOF {
+			return games, nil
+		} else if err != nil {
+			return games, err
+		}
+		games = append(games, pgn)
+	}
+}
+
+// Reads next game. Returns io.EOF when there are no more games to read.
+func (r *PgnReader) Read() (*Pgn, error) {
+	pgn := &Pgn{}
+
+	token, err := r.next()
+	for ; err == nil; token, err = r.next() {
+		if token.kind == pgnTag {
+			pgn.SetTag(token.text, token.value)
+		} else if token.kind != pgnComment || len(pgn.Tags) > 0 {
+			break
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token.kind == pgnEOF && len(pgn.Tags) == 0 {
+		return nil, io.EOF
+	}
+	r.unread(token)
+
+	game := r.game
+	if pgn.chess960() {
+		game = r.game960
+	}
+	game.initial = pgn.fen()
+	position, err := game.setup()
+	if err != nil {
+		return nil, err
+	}
+	if pgn.Moves, err = r.parse(pgn, position, 0); err != nil {
+		return nil, err
+	}
+	if pgn.Result == `` {
+		if pgn.Result = pgn.Tag(`Result`); pgn.Result == `` {
+			pgn.Result = `*`
+		}
+	}
+
+	return pgn, nil
+}
+
+// Parses the line of moves starting from the given position. Variations are
+// parsed recursively by taking back the last move of the line and then making
+// it again once the variation has been parsed. Before returning the variation
+// moves are taken back so that the position tree is restored.
+func (r *PgnReader) parse(pgn *Pgn, p *Position, depth int) (moves []PgnMove, err error) {
+	preface := ``
+	defer func() {
+		if depth > 0 {
+			for _ = range moves {
+				p = p.undoLastMove()
+			}
+		}
+	}()
+
+	for {
+		token, err := r.next()
+		if err != nil {
+			return moves, err
+		}
+
+		switch token.kind {
+		case pgnSymbol:
+			move := NewMoveFromSan(p, token.text)
+			if move == Move(0) {
+				return moves, fmt.Errorf(`pgn: invalid move %s`, token.text)
+			}
+			if p.worker.node >= len(p.worker.tree) - 1 {
+				return moves, fmt.Errorf(`pgn: too many moves`)
+			}
+			moves = append(moves, PgnMove{Move: move, San: move.san(p), Preface: preface})
+			preface = ``
+			p = p.makeMove(move)
+		case pgnNag:
+			if len(moves) > 0 {
+				nag, _ := strconv.Atoi(token.text)
+				moves[len(moves) - 1].Nags = append(moves[len(moves) - 1].Nags, nag)
+			}
+		case pgnComment:
+			if len(moves) == 0 {
+				preface = strings.TrimSpace(preface + ` ` + token.text)
+			} else {
+				last := &moves[len(moves) - 1]
+				last.Comment = strings.TrimSpace(last.Comment + ` ` + token.text)
+			}
+		case pgnOpen:
+			if len(moves) == 0 {
+				return moves, fmt.Errorf(`pgn: unexpected variation`)
+			}
+			last := &moves[len(moves) - 1]
+			p = p.undoLastMove()
+			variation, err := r.parse(pgn, p, depth + 1)
+			if err != nil {
+				return moves, err
+			}
+			if len(variation) > 0 {
+				last.Variations = append(last.Variations, variation)
+			}
+			p = p.makeMove(last.Move)
+		case pgnClose:
+			if depth == 0 {
+				return moves, fmt.Errorf(`pgn: unexpected end of variation`)
+			}
+			return moves, nil
+		case pgnResult, pgnTag, pgnEOF:
+			if depth > 0 {
+				return moves, fmt.Errorf(`pgn: unterminated variation`)
+			}
+			if token.kind == pgnResult {
+				pgn.Result = token.text
+			} else if token.kind == pgnTag { // Next game that follows missing result.
+				r.unread(token)
+			}
+			return moves, nil
+		}
+	}
+}
+
+func (r *PgnReader) unread(token pgnToken) {
+	r.token = &token
+}
+
+func (r *PgnReader) readByte() (char byte, err error) {
+	if char, err = r.reader.ReadByte(); err == nil {
+		r.last = char
+	}
+	return
+}
+
+func (r *PgnReader) unreadByte() {
+	r.reader.UnreadByte()
+}
+
+// Reads characters until the given delimiter and returns them without the
+// delimiter.
+func (r *PgnReader) readUntil(delimiter byte) (string, error) {
+	text, err := r.reader.ReadString(delimiter)
+	if err == nil {
+		r.last = delimiter
+		return text[:len(text) - 1], nil
+	}
+	return text, err
+}
+
+// Returns next PGN token skipping white space and escaped lines.
+func (r *PgnReader) next() (token pgnToken, err error) {
+	if r.token != nil {
+		token, r.token = *r.token, nil
+		return
+	}
+
+	for {
+		newline := (r.last == '\n')
+		char, err := r.readByte()
+		if err == io.EOF {
+			return pgnToken{kind: pgnEOF}, nil
+		} else if err != nil {
+			return token, err
+		}
+
+		switch {
+		case char == '%' && newline:
+			if _, err := r.readUntil('\n'); err != nil && err != io.EOF {
+				return token, err
+			}
+		case char == ' ' || char == '\t' || char == '\r' || char == '\n' || char == '.':
+		case char == '[':
+			return r.tag()
+		case char == '{':
+			text, err := r.readUntil('}')
+			if err != nil {
+				return token, fmt.Errorf(`pgn: unterminated comment`)
+			}
+			return pgnToken{kind: pgnComment, text: strings.Join(strings.Fields(text), ` `)}, nil
+		case char == ';':
+			text, err := r.readUntil('\n')
+			if err != nil && err != io.EOF {
+				return token, err
+			}
+			return pgnToken{kind: pgnComment, text: strings.TrimSpace(text)}, nil
+		case char == '(':
+			return pgnToken{kind: pgnOpen}, nil
+		case char == ')':
+			return pgnToken{kind: pgnClose}, nil
+		case char == '*':
+			return pgnToken{kind: pgnResult, text: `*`}, nil
+		case char == '$':
+			return pgnToken{kind: pgnNag, text: r.symbol(``)}, nil
+		case char == '!' || char == '?':
+			return r.suffix(string(char)), nil
+		case isPgnSymbol(char):
+			return r.classify(r.symbol(string(char))), nil
+		default:
+			return token, fmt.Errorf(`pgn: unexpected character '%c'`, char)
+		}
+	}
+}
+
+// Parses the tag pair after the opening bracket has been read.
+func (r *PgnReader) tag() (token pgnToken, err error) {
+	var buffer bytes.Buffer
+
+	name, err := r.readUntil('"')
+	if err != nil {
+		return token, fmt.Errorf(`pgn: invalid tag`)
+	}
+	for escaped := false; ; escaped = false {
+		char, err := r.readByte()
+		if err != nil {
+			return token, fmt.Errorf(`pgn: invalid tag`)
+		}
+		if char == '\\' {
+			if char, err = r.readByte(); err != nil {
+				return token, fmt.Errorf(`pgn: invalid tag`)
+			}
+			escaped = true
+		}
+		if char == '"' && !escaped {
+			break
+		}
+		buffer.WriteByte(char)
+	}
+	if _, err = r.readUntil(']'); err != nil {
+		return token, fmt.Errorf(`pgn: invalid tag`)
+	}
+
+	return pgnToken{kind: pgnTag, text: strings.TrimSpace(name), value: buffer.String()}, nil
+}
+
+// Reads the rest of the symbol.
+func (r *PgnReader) symbol(prefix string) string {
+	var buffer bytes.Buffer
+
+	buffer.WriteString(prefix)
+	for {
+		char, err := r.readByte()
+		if err != nil {
+			break
+		}
+		if !isPgnSymbol(char) {
+			r.unreadByte()
+			break
+		}
+		buffer.WriteByte(char)
+	}
+
+	return buffer.String()
+}
+
+// Converts traditional suffix annotation like "!?" to numeric annotation glyph.
+func (r *PgnReader) suffix(prefix string) pgnToken {
+	text := prefix
+	for {
+		char, err := r.readByte()
+		if err != nil {
+			break
+		}
+		if char != '!' && char != '?' {
+			r.unreadByte()
+			break
+		}
+		text += string(char)
+	}
+
+	nags := map[string]string{ `!`: `1`, `?`: `2`, `!!`: `3`, `??`: `4`, `!?`: `5`, `?!`: `6` }
+	return pgnToken{kind: pgnNag, text: nags[text]}
+}
+
+// Tells move numbers and game results from the moves.
+func (r *PgnReader) classify(symbol string) pgnToken {
+	switch symbol {
+	case `1-0`, `0-1`, `1/2-1/2`:
+		return pgnToken{kind: pgnResult, text: symbol}
+	}
+	if _, err := strconv.Atoi(symbol); err == nil {
+		return pgnToken{kind: pgnNumber, text: symbol}
+	}
+	return pgnToken{kind: pgnSymbol, text: symbol}
+}
+
+func isPgnSymbol(char byte) bool {
+	return (char >= 'a' && char <= 'z') || (char >= 'A' && char <= 'Z') || (char >= '0' && char <= '9') ||
+		strings.IndexByte(`_+#=:-/`, char) >= 0
+}
+
+// Returns game termination marker for the position.
+func (p *Position) result() string {
+	if !NewGen(p, MaxPly).generateAllMoves().anyValid() {
+		if !p.isInCheck(p.color) {
+			return `1/2-1/2`
+		} else if p.color == White {
+			return `0-1`
+		}
+		return `1-0`
+	}
+	if p.insufficient() || p.thirdRepetition() || p.fifty() {
+		return `1/2-1/2`
+	}
+	return `*`
+}
+
+// Returns today's date as PGN tag value.
+func pgnDate() string {
+	return time.Now().Format(`2006.01.02`)
+}
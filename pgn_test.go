@@ -0,0 +1,180 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `io`; `strings`; `testing`)
+
+const pgnImmortal = `[Event "London"]
+[Site "London ENG"]
+[Date "1851.06.21"]
+[Round "?"]
+[White "Adolf Anderssen"]
+[Black "Lionel Adalbert Bagration Felix Kieseritzky"]
+[Result "1-0"]
+[ECO "C33"]
+
+1.e4 e5 2.f4 exf4 3.Bc4 Qh4+ 4.Kf1 b5 5.Bxb5 Nf6 6.Nf3 Qh6 7.d3 Nh5 8.Nh4 Qg5
+9.Nf5 c6 10.g4 Nf6 11.Rg1 cxb5 12.h4 Qg6 13.h5 Qg5 14.Qf3 Ng8 15.Bxf4 Qf6
+16.Nc3 Bc5 17.Nd5 Qxb2 18.Bd6 Bxg1 {It is from this move that Black's defeat
+stems.} 19. e5 Qxa1+ 20. Ke2 Na6 21.Nxg7+ Kd8 22.Qf6+ Nxf6 23.Be7# 1-0
+`
+
+// Tags and main line.
+func TestPgn000(t *testing.T) {
+	pgn, err := NewPgnReader(strings.NewReader(pgnImmortal)).Read()
+	expect.True(t, err == nil)
+	expect.Eq(t, len(pgn.Tags), 8)
+	expect.Eq(t, pgn.Tag(`White`), `Adolf Anderssen`)
+	expect.Eq(t, pgn.Tag(`ECO`), `C33`)
+	expect.Eq(t, pgn.Result, `1-0`)
+	expect.Eq(t, len(pgn.Moves), 45)
+	expect.Eq(t, pgn.Moves[4].San, `Bc4`)
+	expect.Eq(t, pgn.Moves[5].San, `Qh4+`)
+	expect.Eq(t, pgn.Moves[35].Comment, `It is from this move that Black's defeat stems.`)
+	expect.Eq(t, pgn.Moves[44].San, `Be7#`)
+}
+
+// Replaying the game move by move.
+func TestPgn010(t *testing.T) {
+	pgn, _ := NewPgnReader(strings.NewReader(pgnImmortal)).Read()
+	count := 0
+	p := pgn.Walk(NewGame(), func(p *Position, move Move) bool {
+		count++
+		return true
+	})
+	expect.Eq(t, count, 45)
+	expect.Eq(t, p.result(), `1-0`)
+	expect.Eq(t, p.fen(), `r1bk3r/p2pBpNp/n4n2/1p1NP2P/6P1/3P4/P1P1K3/q5b1 b - - 1 23`)
+}
+
+func TestPgn020(t *testing.T) {
+	pgn, _ := NewPgnReader(strings.NewReader(pgnImmortal)).Read()
+	count := 0
+	pgn.Walk(NewGame(), func(p *Position, move Move) bool {
+		count++
+		return move.san(p) != `Qh4+`
+	})
+	expect.Eq(t, count, 6)
+}
+
+// Comments, NAGs, and nested variations.
+func TestPgn030(t *testing.T) {
+	text := `[Event "Test"]
+
+{Opening} 1. e4 $1 e5 (1... c5 2. Nf3 (2. c3 d5) 2... d6) (1... e6!?) 2. Nf3 ; Develops
+Nc6 *`
+	pgn, err := NewPgnReader(strings.NewReader(text)).Read()
+	expect.True(t, err == nil)
+	expect.Eq(t, pgn.Result, `*`)
+	expect.Eq(t, len(pgn.Moves), 4)
+	expect.Eq(t, pgn.Moves[0].Preface, `Opening`)
+	expect.Eq(t, pgn.Moves[0].Nags, []int{ 1 })
+	expect.Eq(t, len(pgn.Moves[1].Variations), 2)
+	expect.Eq(t, pgn.Moves[1].Variations[0][1].San, `Nf3`)
+	expect.Eq(t, pgn.Moves[1].Variations[0][1].Variations[0][1].San, `d5`)
+	expect.Eq(t, pgn.Moves[1].Variations[1][0].Nags, []int{ 5 })
+	expect.Eq(t, pgn.Moves[2].Comment, `Develops`)
+	expect.Eq(t, pgn.Moves[3].San, `Nc6`)
+}
+
+// Multiple games, and the game from FEN.
+func TestPgn040(t *testing.T) {
+	text := `[Event "One"]
+
+1. d4 d5 1/2-1/2
+
+[Event "Two"]
+[SetUp "1"]
+[FEN "4k3/8/8/8/8/8/4P3/4K3 b - - 0 40"]
+
+40... Kd7 41. e4 0-1
+`
+	reader := NewPgnReader(strings.NewReader(text))
+	one, err := reader.Read()
+	expect.True(t, err == nil)
+	expect.Eq(t, one.Result, `1/2-1/2`)
+	expect.Eq(t, len(one.Moves), 2)
+
+	two, err := reader.Read()
+	expect.True(t, err == nil)
+	expect.Eq(t, two.Tag(`Event`), `Two`)
+	expect.Eq(t, len(two.Moves), 2)
+	expect.Eq(t, two.Moves[1].San, `e4`)
+
+	_, err = reader.Read()
+	expect.True(t, err == io.EOF)
+}
+
+func TestPgn050(t *testing.T) {
+	_, err := NewPgnReader(strings.NewReader(`1. e4 e4 *`)).Read()
+	expect.Eq(t, err.Error(), `pgn: invalid move e4`)
+
+	_, err = NewPgnReader(strings.NewReader(`1. e4 (1. d4 *`)).Read()
+	expect.Eq(t, err.Error(), `pgn: unterminated variation`)
+}
+
+// Writing PGN.
+func TestPgn100(t *testing.T) {
+	text := `[Event "Test"]
+[Annotator "Donna"]
+
+{Opening} 1. e4 $1 e5 (1... c5 2. Nf3 (2. c3 d5) 2... d6) 2. Nf3 {Develops} 2... Nc6 *`
+	pgn, _ := NewPgnReader(strings.NewReader(text)).Read()
+	expect.Eq(t, pgn.String(), `[Event "Test"]
+[Site "?"]
+[Date "????.??.??"]
+[Round "?"]
+[White "?"]
+[Black "?"]
+[Result "*"]
+[Annotator "Donna"]
+
+{Opening} 1. e4 $1 e5 (1... c5 2. Nf3 (2. c3 d5) 2... d6) 2. Nf3 {Develops}
+2... Nc6 *
+
+`)
+}
+
+// Round trip.
+func TestPgn110(t *testing.T) {
+	games, err := ReadPgn(strings.NewReader(pgnImmortal + "\n" + pgnImmortal))
+	expect.True(t, err == nil)
+	expect.Eq(t, len(games), 2)
+
+	again, err := ReadPgn(strings.NewReader(games[0].String()))
+	expect.True(t, err == nil)
+	expect.Eq(t, len(again), 1)
+	expect.Eq(t, again[0].String(), games[0].String())
+	expect.Eq(t, again[0].Tag(`Black`), `Lionel Adalbert Bagration Felix Kieseritzky`)
+}
+
+// Building the game move by move.
+func TestPgn120(t *testing.T) {
+	p := NewGame().start()
+	pgn := &Pgn{}
+	for _, san := range []string{ `f3`, `e5`, `g4`, `Qh4#` } {
+		move := NewMoveFromSan(p, san)
+		pgn.add(p, move)
+		p = p.makeMove(move)
+	}
+	pgn.Result = p.result()
+	expect.Eq(t, pgn.Result, `0-1`)
+	expect.True(t, strings.HasSuffix(pgn.String(), "1. f3 e5 2. g4 Qh4# 0-1\n\n"))
+}
+
+// Chess960 games are told apart by the Variant tag.
+func TestPgn130(t *testing.T) {
+	text := `[Variant "Chess960"]
+[FEN "1r3k1r/pppppppp/8/8/8/8/PPPPPPPP/1R3K1R w HBhb - 0 1"]
+
+1. O-O O-O *`
+	pgn, err := NewPgnReader(strings.NewReader(text)).Read()
+	expect.True(t, err == nil)
+	expect.True(t, pgn.chess960())
+	expect.Eq(t, len(pgn.Moves), 2)
+
+	_, err = NewPgnReader(strings.NewReader(text[strings.Index(text, "\n") + 1:])).Read()
+	expect.Eq(t, err.(*PositionError).Code, InvalidCastles)
+}
@@ -45,11 +45,6 @@ func pawn(color uint8) Piece {
 	return Piece(color | Pawn)
 }
 
-// Returns score points for a piece at given square.
-func (p Piece) score(square int) Score {
-	return pst[p][square]
-}
-
 func (p Piece) polyglot(square int) uint64 {
 	return polyglotRandom[polyglotBase[p] + square]
 }
@@ -94,17 +89,36 @@ func (p Piece) isPawn() bool {
 	return p & 0xFE == Pawn
 }
 
+// Returns piece kind for the given ASCII letter, ex. Knight for 'N' or 'n'.
+func pieceKind(char byte) int {
+	switch char {
+	case 'K', 'k':
+		return King
+	case 'Q', 'q':
+		return Queen
+	case 'R', 'r':
+		return Rook
+	case 'B', 'b':
+		return Bishop
+	case 'N', 'n':
+		return Knight
+	}
+	return Pawn
+}
+
 // Returns colorless ASCII code for the piece.
 func (p Piece) char() byte {
 	return []byte{ 0, 0, 0, 0, 'N', 'N', 'B', 'B', 'R', 'R', 'Q', 'Q', 'K', 'K' }[p]
 }
 
-func (p Piece) String() string {
-	plain := []string{ ` `, ` `, `P`, `p`, `N`, `n`, `B`, `b`, `R`, `r`, `Q`, `q`, `K`, `k` }
-	fancy := []string{ ` `, ` `, "\u2659", "\u265F", "\u2658", "\u265E", "\u2657", "\u265D", "\u2656", "\u265C", "\u2655", "\u265B", "\u2654", "\u265A" }
-
-	if engine.fancy {
-		return fancy[p]
+// Returns piece's UTF-8 figurine when fancy is set, or ASCII letter otherwise.
+func (p Piece) format(fancy bool) string {
+	if fancy {
+		return []string{ ` `, ` `, "\u2659", "\u265F", "\u2658", "\u265E", "\u2657", "\u265D", "\u2656", "\u265C", "\u2655", "\u265B", "\u2654", "\u265A" }[p]
 	}
-	return plain[p]
+	return []string{ ` `, ` `, `P`, `p`, `N`, `n`, `B`, `b`, `R`, `r`, `Q`, `q`, `K`, `k` }[p]
+}
+
+func (p Piece) String() string {
+	return p.format(false)
 }
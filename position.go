@@ -7,13 +7,12 @@ package donna
 import (
 	`bytes`
 	`fmt`
+	`strconv`
 	`strings`
 )
 
-var tree [1024]Position
-var node, rootNode int
-
-type Position struct {		 // 224 bytes long.
+type Position struct {		 // 256 bytes long.
+	worker       *Worker     // Search worker that owns the position tree.
 	hash         uint64      // Polyglot hash value for the position.
 	pawnHash     uint64      // Polyglot hash value for position's pawn structure.
 	board        Bitmask     // Bitmask of all pieces on the board.
@@ -21,17 +20,23 @@ type Position struct {		 // 224 bytes long.
 	pieces       [64]Piece   // Array of 64 squares with pieces on them.
 	outposts     [14]Bitmask // Bitmasks of each piece on the board; [0] all white, [1] all black.
 	tally        Score       // Positional valuation score based on PST.
+	accumulator  *Accumulator // Network hidden layer values, nil when evaluating without the network.
 	balance      int 	 // Material balance index.
 	reversible   bool        // Is this position reversible?
 	color        uint8       // Side to make next move.
 	enpassant    uint8       // En-passant square caused by previous move.
 	castles      uint8       // Castle rights mask.
+	halfmove     uint16      // Half-moves since last capture or pawn move.
+	fullmove     uint16      // Full move number starting at 1.
 }
 
 func NewPosition(game *Game, white, black string) *Position {
-	tree[node] = Position{}
-	p := &tree[node]
+	main := game.workers[0]
+	main.tree[main.node] = Position{worker: main}
+	p := main.position()
+	game.castling = NewCastling(game.engine.chess960)
 
+	p.fullmove = 1
 	p.setupSide(white, White).setupSide(black, Black)
 
 	p.castles = castleKingside[White] | castleQueenside[White] | castleKingside[Black] | castleQueenside[Black]
@@ -63,6 +68,7 @@ func NewPosition(game *Game, white, black string) *Position {
 	p.board = p.outposts[White] | p.outposts[Black]
 	p.hash, p.pawnHash = p.polyglot()
 	p.tally = p.valuation()
+	p.setupAccumulator()
 
 	return p
 }
@@ -91,8 +97,11 @@ func (p *Position) setupSide(str string, color uint8) *Position {
 	}
 
 	for _, move := range strings.Split(str, `,`) {
-		if move[0] == 'M' { // TODO: parse move number.
+		if move[0] == 'M' {
 			p.color = color
+			if number, err := strconv.Atoi(move[1:]); err == nil && number > 0 {
+				p.fullmove = uint16(number)
+			}
 		} else {
 			arr := reMove.FindStringSubmatch(move)
 			if len(arr) == 0 {
@@ -135,8 +144,10 @@ func NewInitialPosition(game *Game) *Position {
 
 // Decodes FEN string and creates new position.
 func NewPositionFromFEN(game *Game, fen string) *Position {
-	tree[node] = Position{}
-	p := &tree[node]
+	main := game.workers[0]
+	main.tree[main.node] = Position{worker: main}
+	p := main.position()
+	game.castling = NewCastling(game.engine.chess960)
 
 	// Expected matches of interest are as follows:
 	// [0] - Pieces (entire board).
@@ -145,8 +156,8 @@ func NewPositionFromFEN(game *Game, fen string) *Position {
 	// [3] - En-passant square.
 	// [4] - Number of half-moves.
 	// [5] - Number of full moves.
-	matches := strings.Split(fen, ` `)
-	if len(matches) < 4 {
+	matches := strings.Fields(fen)
+	if len(matches) < 4 || checkFEN(strings.Join(matches[:4], ` `)) != nil {
 		return nil
 	}
 
@@ -202,32 +213,54 @@ func NewPositionFromFEN(game *Game, fen string) *Position {
 		p.color = Black
 	}
 
-	// [2] - Castle rights.
-	for _, char := range(matches[2]) {
-		switch(char) {
-		case 'K':
-			p.castles |= castleKingside[White]
-		case 'Q':
-			p.castles |= castleQueenside[White]
-		case 'k':
-			p.castles |= castleKingside[Black]
-		case 'q':
-			p.castles |= castleQueenside[Black]
-		case '-':
-			// No castling rights.
+	// [2] - Castle rights. Chess960 positions might use Shredder-FEN or X-FEN
+	// notation where castle rights are specified by rook files. Note that rook
+	// files don't turn Chess960 on: standard chess positions are validated to
+	// have the rooks on their home squares.
+	if game.castling.chess960 || strings.IndexAny(matches[2], `ABCDEFGHabcdefgh`) >= 0 {
+		p.castles960(matches[2])
+	} else {
+		for _, char := range(matches[2]) {
+			switch(char) {
+			case 'K':
+				p.castles |= castleKingside[White]
+			case 'Q':
+				p.castles |= castleQueenside[White]
+			case 'k':
+				p.castles |= castleKingside[Black]
+			case 'q':
+				p.castles |= castleQueenside[Black]
+			case '-':
+				// No castling rights.
+			}
 		}
 	}
 
 	// [3] - En-passant square.
 	if matches[3] != `-` {
 		p.enpassant = uint8(square(int(matches[3][1] - '1'), int(matches[3][0] - 'a')))
+	}
+
+	// [4] - Number of half-moves.
+	if len(matches) > 4 {
+		if halfmove, err := strconv.Atoi(matches[4]); err == nil && halfmove >= 0 {
+			p.halfmove = uint16(halfmove)
+		}
+	}
 
+	// [5] - Number of full moves.
+	p.fullmove = 1
+	if len(matches) > 5 {
+		if fullmove, err := strconv.Atoi(matches[5]); err == nil && fullmove > 0 {
+			p.fullmove = uint16(fullmove)
+		}
 	}
 
 	p.reversible = true
 	p.board = p.outposts[White] | p.outposts[Black]
 	p.hash, p.pawnHash = p.polyglot()
 	p.tally = p.valuation()
+	p.setupAccumulator()
 
 	return p
 }
@@ -257,10 +290,15 @@ func (p *Position) polyglot() (hash, pawnHash uint64) {
 	return
 }
 
+// Returns evaluation parameters of the engine the position belongs to.
+func (p *Position) params() *Params {
+	return p.worker.game.engine.params
+}
+
 // Computes positional valuation score based on PST. When making a move the
 // valuation tally gets updated incrementally.
 func (p *Position) valuation() (score Score) {
-	board := p.board
+	pst, board := &p.params().pst, p.board
 	for board != 0 {
 		square := board.pop()
 		piece := p.pieces[square]
@@ -282,7 +320,7 @@ func (p *Position) status(move Move, blendedScore int) int {
 		defer func() { p = p.undoLastMove() }()
 	}
 
-	switch ply, score := ply(), abs(blendedScore); score {
+	switch ply, score := p.worker.ply(), abs(blendedScore); score {
 	case 0:
 		if ply == 1 {
 			if p.insufficient() {
@@ -317,9 +355,6 @@ func (p *Position) status(move Move, blendedScore int) int {
 
 // Encodes position as FEN string.
 func (p *Position) fen() (fen string) {
-	fancy := engine.fancy
-	engine.fancy = false; defer func() { engine.fancy = fancy }()
-
 	// Board: start from A8->H8 going down to A1->H1.
 	empty := 0
 	for row := A8H8; row >= A1H1; row-- {
@@ -357,7 +392,9 @@ func (p *Position) fen() (fen string) {
 	}
 
 	// Castle rights for both sides, if any.
-	if p.castles & 0x0F != 0 {
+	if p.castles & 0x0F != 0 && p.worker.game.castling.chess960 {
+		fen += ` ` + p.fen960()
+	} else if p.castles & 0x0F != 0 {
 		fen += ` `
 		if p.castles & castleKingside[White] != 0 {
 			fen += `K`
@@ -383,17 +420,14 @@ func (p *Position) fen() (fen string) {
 		fen += ` -`
 	}
 
-	// TODO: Number of half-moves and number of full moves.
-	fen += ` 0 1`
+	// Number of half-moves and number of full moves.
+	fen += fmt.Sprintf(` %d %d`, p.halfmove, p.fullmove)
 
 	return
 }
 
 // Encodes position as DCF string (Donna Chess Format).
 func (p *Position) dcf() string {
-	fancy := engine.fancy
-	engine.fancy = false; defer func() { engine.fancy = fancy }()
-
 	encode := func (square int) string {
 		var buffer bytes.Buffer
 
@@ -406,8 +440,10 @@ func (p *Position) dcf() string {
 	var pieces [2][]string
 
 	for color := uint8(White); color <= uint8(Black); color++ {
-		// Right to move and (TODO) move number.
-		if color == p.color && color == Black {
+		// Right to move and move number.
+		if color == p.color && p.fullmove > 1 {
+			pieces[color] = append(pieces[color], fmt.Sprintf(`M%d`, p.fullmove))
+		} else if color == p.color && color == Black {
 			pieces[color] = append(pieces[color], `M`)
 		}
 
@@ -459,6 +495,7 @@ func (p *Position) dcf() string {
 }
 
 func (p *Position) String() string {
+	fancy := p.worker.game.engine.fancy
 	buffer := bytes.NewBufferString("  a b c d e f g h")
 	if !p.isInCheck(p.color) {
 		buffer.WriteString("\n")
@@ -471,7 +508,7 @@ func (p *Position) String() string {
 			square := square(row, col)
 			buffer.WriteByte(' ')
 			if piece := p.pieces[square]; piece != 0 {
-				buffer.WriteString(piece.String())
+				buffer.WriteString(piece.format(fancy))
 			} else {
 				buffer.WriteString("\u22C5")
 			}
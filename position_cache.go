@@ -28,28 +28,33 @@ type Cache []CacheEntry
 func NewCache(megaBytes float64) Cache {
 	if megaBytes > 0.0 {
 		cacheSize := int(1024*1024*megaBytes) / int(unsafe.Sizeof(CacheEntry{}))
-		// If cache size has changed then create a new cache; otherwise
-		// simply clear the existing one.
-		if cacheSize != len(game.cache) {
-			return make(Cache, cacheSize)
-		}
-		game.cache = Cache{}
-		return game.cache
+		return make(Cache, cacheSize)
 	}
 	return nil
 }
 
+// Wipes out all cache entries.
+func (c Cache) clear() Cache {
+	for i := range c {
+		c[i] = CacheEntry{}
+	}
+	return c
+}
+
 func (p *Position) cache(move Move, score, depth int, flags uint8) *Position {
+	game := p.worker.game
 	if cacheSize := len(game.cache); cacheSize > 0 {
 		index := p.hash % uint64(cacheSize)
 		// fmt.Printf("cache size %d entries, index %d\n", len(game.cache), index)
 		entry := &game.cache[index]
 
 		if depth > entry.depth || game.token != entry.token {
-			if score > Checkmate-MaxPly && score <= Checkmate {
-				entry.score = score + ply()
-			} else if score >= -Checkmate && score < -Checkmate+MaxPly {
-				entry.score = score - ply()
+			// Checkmate and tablebase scores depend on the ply they were found
+			// at, so the cache keeps them relative to the current position.
+			if score > TablebaseWin-MaxPly && score <= Checkmate {
+				entry.score = score + p.worker.ply()
+			} else if score >= -Checkmate && score < -TablebaseWin+MaxPly {
+				entry.score = score - p.worker.ply()
 			} else {
 				entry.score = score
 			}
@@ -65,6 +70,7 @@ func (p *Position) cache(move Move, score, depth int, flags uint8) *Position {
 }
 
 func (p *Position) probeCache() *CacheEntry {
+	game := p.worker.game
 	if cacheSize := len(game.cache); cacheSize > 0 {
 		index := p.hash % uint64(cacheSize)
 		if entry := &game.cache[index]; entry.hash == p.hash {
@@ -7,8 +7,7 @@ package donna
 import(`github.com/michaeldv/donna/expect`; `testing`)
 
 func TestCache000(t *testing.T) {
-	engine.cacheSize = 0.5
-	p := NewGame().start()
+	p := NewEngine(`cache`, 0.5).NewGame().start()
 	move := NewMove(p, E2, E4)
 	p = p.makeMove(move).cache(move, 42, 1, cacheExact)
 
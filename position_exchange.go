@@ -6,28 +6,19 @@ package donna
 
 import ()
 
-var exchangeScores = []int{
-	0, 0, 						// Zero score for non-capture moves.
-	valuePawn.midgame, valuePawn.midgame, 		// Pawn/BlackPawn captures.
-	valueKnight.midgame, valueKnight.midgame, 	// Knight/BlackKinght captures.
-	valueBishop.midgame, valueBishop.midgame, 	// Bishop/BlackBishop captures.
-	valueRook.midgame, valueRook.midgame, 		// Rook/BlackRook captures.
-	valueQueen.midgame, valueQueen.midgame, 	// Queen/BlackQueen captures.
-	valueQueen.midgame * 8, valueQueen.midgame * 8, // King/BlackKing specials.
-}
-
 // Static exchange evaluation.
 func (p *Position) exchange(move Move) int {
 	from, to, piece, capture := move.split()
+	scores := &p.params().exchangeScores
 
-	score := exchangeScores[capture]
+	score := scores[capture]
 	if promo := move.promo(); promo != 0 {
-		score += exchangeScores[promo] - exchangeScores[Pawn]
+		score += scores[promo] - scores[Pawn]
 		piece = promo
 	}
 
 	board := p.board ^ bit[from]
-	return -p.exchangeScore(piece.color()^1, to, -score, exchangeScores[piece], board)
+	return -p.exchangeScore(piece.color()^1, to, -score, scores[piece], board)
 }
 
 // Recursive helper method for the static exchange evaluation.
@@ -37,12 +28,12 @@ func (p *Position) exchangeScore(color uint8, to, score, extra int, board Bitmas
 		return score
 	}
 
-	from, best := 0, Checkmate
+	from, best, scores := 0, Checkmate, &p.params().exchangeScores
 	for attackers != 0 {
 		square := attackers.pop()
-		if index := p.pieces[square]; exchangeScores[index] < best {
+		if index := p.pieces[square]; scores[index] < best {
 			from = square
-			best = exchangeScores[index]
+			best = scores[index]
 		}
 	}
 
@@ -16,8 +16,12 @@ func (p *Position) movePiece(piece Piece, from, to int) *Position {
 		p.pawnHash ^= random
 	}
 
-	// Update positional score.
+	// Update positional score and network accumulator.
+	pst := &p.params().pst
 	p.tally.subtract(pst[piece][from]).add(pst[piece][to])
+	if p.accumulator != nil {
+		p.worker.network.move(p.accumulator, piece, from, piece, to)
+	}
 
 	return p
 }
@@ -34,8 +38,12 @@ func (p *Position) promotePawn(pawn Piece, from, to int, promo Piece) *Position
 	p.pawnHash ^= random
 	p.balance += materialBalance[promo] - materialBalance[pawn]
 
-	// Update positional score.
+	// Update positional score and network accumulator.
+	pst := &p.params().pst
 	p.tally.subtract(pst[pawn][from]).add(pst[promo][to])
+	if p.accumulator != nil {
+		p.worker.network.move(p.accumulator, pawn, from, promo, to)
+	}
 
 	return p
 }
@@ -52,8 +60,11 @@ func (p *Position) capturePiece(capture Piece, from, to int) *Position {
 	}
 	p.balance -= materialBalance[capture]
 
-	// Update positional score.
-	p.tally.subtract(pst[capture][to])
+	// Update positional score and network accumulator.
+	p.tally.subtract(p.params().pst[capture][to])
+	if p.accumulator != nil {
+		p.worker.network.remove(p.accumulator, capture, to)
+	}
 
 	return p
 }
@@ -71,8 +82,11 @@ func (p *Position) captureEnpassant(capture Piece, from, to int) *Position {
 	p.pawnHash ^= random
 	p.balance -= materialBalance[capture]
 
-	// Update positional score.
-	p.tally.subtract(pst[capture][enpassant])
+	// Update positional score and network accumulator.
+	p.tally.subtract(p.params().pst[capture][enpassant])
+	if p.accumulator != nil {
+		p.worker.network.remove(p.accumulator, capture, enpassant)
+	}
 
 	return p
 }
@@ -82,13 +96,22 @@ func (p *Position) makeMove(move Move) *Position {
 	from, to, piece, capture := move.split()
 
 	// Copy over the contents of previous tree node to the current one.
-	node++
-	tree[node] = *p // => tree[node] = tree[node - 1]
-	pp := &tree[node]
+	w := p.worker
+	w.node++
+	w.tree[w.node] = *p // => tree[node] = tree[node - 1]
+	pp := &w.tree[w.node]
+	if p.accumulator != nil {
+		pp.accumulator = w.accumulators[w.node].copy(p.accumulator)
+	}
 
 	pp.enpassant, pp.reversible = 0, true
+	pp.halfmove++
+	if color == Black {
+		pp.fullmove++
+	}
 
 	if capture != 0 {
+		pp.halfmove = 0
 		pp.reversible = false
 		if to != 0 && to == int(p.enpassant) {
 			pp.captureEnpassant(pawn(color^1), from, to)
@@ -99,70 +122,68 @@ func (p *Position) makeMove(move Move) *Position {
 	}
 
 	if promo := move.promo(); promo == 0 {
-		pp.movePiece(piece, from, to)
+		if move.isCastle() {
+			pp.reversible = false
+			pp.castle(move)
+		} else {
+			pp.movePiece(piece, from, to)
+		}
 
 		if piece.isKing() {
 			pp.king[color] = uint8(to)
-			if move.isCastle() {
-				pp.reversible = false
-				switch to {
-				case G1:
-					pp.movePiece(Rook, H1, F1)
-				case C1:
-					pp.movePiece(Rook, A1, D1)
-				case G8:
-					pp.movePiece(BlackRook, H8, F8)
-				case C8:
-					pp.movePiece(BlackRook, A8, D8)
-				}
-			}
 		} else if piece.isPawn() {
-			pp.reversible = false
+			pp.reversible, pp.halfmove = false, 0
 			if move.isEnpassant() {
 				pp.enpassant = uint8(from + eight[color]) // Save the en-passant square.
 				pp.hash ^= hashEnpassant[pp.enpassant & 7]
 			}
 		}
 	} else {
-		pp.reversible = false
+		pp.reversible, pp.halfmove = false, 0
 		pp.promotePawn(piece, from, to, promo)
 	}
 
 	// Set up the board bitmask, update castle rights, finish off incremental
 	// hash value, and flip the color.
 	pp.board = pp.outposts[White] | pp.outposts[Black]
-	pp.castles &= castleRights[from] & castleRights[to]
+	pp.castles &= w.game.castling.rights[from] & w.game.castling.rights[to]
 	pp.hash ^= hashCastle[p.castles] ^ hashCastle[pp.castles]
 	pp.hash ^= polyglotRandomWhite
 	pp.color ^= 1 // <-- Flip side to move.
 
-	return &tree[node] // pp
+	return pp
 }
 
 // Makes "null" move by copying over previous node position (i.e. preserving all pieces
 // intact) and flipping the color.
 func (p *Position) makeNullMove() *Position {
-	node++
-	tree[node] = *p // => tree[node] = tree[node - 1]
-	pp := &tree[node]
+	w := p.worker
+	w.node++
+	w.tree[w.node] = *p // => tree[node] = tree[node - 1]
+	pp := &w.tree[w.node]
 
 	// Flipping side to move obviously invalidates the enpassant square.
 	if pp.enpassant != 0 {
 		pp.hash ^= hashEnpassant[pp.enpassant & 7]
 		pp.enpassant = 0
 	}
+	pp.halfmove++
+	if pp.color == Black {
+		pp.fullmove++
+	}
 	pp.hash ^= polyglotRandomWhite
 	pp.color ^= 1 // <-- Flip side to move.
 
-	return &tree[node] // pp
+	return pp
 }
 
 // Restores previous position effectively taking back the last move made.
 func (p *Position) undoLastMove() *Position {
-	if node > 0 {
-		node--
+	w := p.worker
+	if w.node > 0 {
+		w.node--
 	}
-	return &tree[node]
+	return &w.tree[w.node]
 }
 
 func (p *Position) undoNullMove() *Position {
@@ -177,24 +198,17 @@ func (p *Position) isInCheck(color uint8) bool {
 }
 
 func (p *Position) isNull() bool {
+	tree, node := &p.worker.tree, p.worker.node
 	return node > 0 && tree[node].board == tree[node-1].board
 }
 
+// Returns true if fifty moves have been made since last capture or pawn move.
 func (p *Position) fifty() bool {
-	if node < 100 {
-		return false
-	}
-	count := 0
-	for previous := node - 1; previous >= 0 && count < 100; previous-- {
-		if !tree[previous].reversible {
-			break
-		}
-		count++
-	}
-	return count >= 100
+	return p.halfmove >= 100
 }
 
 func (p *Position) repetition() bool {
+	tree, node := &p.worker.tree, p.worker.node
 	if !p.reversible || node < 1 {
 		return false
 	}
@@ -210,6 +224,7 @@ func (p *Position) repetition() bool {
 }
 
 func (p *Position) thirdRepetition() bool {
+	tree, node := &p.worker.tree, p.worker.node
 	if !p.reversible || node < 4 {
 		return false
 	}
@@ -229,6 +244,9 @@ func (p *Position) thirdRepetition() bool {
 }
 
 func (p *Position) canCastle(color uint8) (kingside, queenside bool) {
+	if p.worker.game.castling.chess960 {
+		return p.canCastle960(color)
+	}
 
 	// Start off with simple checks.
 	kingside = (p.castles & castleKingside[color] != 0) && (gapKing[color] & p.board == 0)
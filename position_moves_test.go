@@ -268,6 +268,21 @@ func TestPositionMoves130(t *testing.T) {
 	}
 }
 
+// Fifty moves draw using half-move clock from FEN.
+func TestPositionMoves140(t *testing.T) {
+	p := NewGame(`7k/8/8/8/8/8/8/R6K w - - 98 80`).start()
+	expect.False(t, p.fifty())
+	p = p.makeMove(NewMove(p, A1, A2))
+	expect.False(t, p.fifty())
+	p = p.makeMove(NewMove(p, H8, G8))
+	expect.True(t, p.fifty())
+	expect.Eq(t, p.fullmove, uint16(81))
+
+	// Null move counts as a half-move too.
+	p = p.undoLastMove().makeNullMove()
+	expect.True(t, p.fifty())
+}
+
 // Incremental hash recalculation tests (see book_test.go).
 func TestPositionMoves200(t *testing.T) { // 1. e4
 	p := NewGame().start()
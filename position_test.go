@@ -82,13 +82,34 @@ func TestPosition100(t *testing.T) {
 // Castles, no en-passant.
 func TestPosition110(t *testing.T) {
 	p := NewGame(`2r1kb1r/pp3ppp/2n1b3/1q1N2B1/1P2Q3/8/P4PPP/3RK1NR w Kk - 42 42`).start()
-	expect.Eq(t, p.fen(), `2r1kb1r/pp3ppp/2n1b3/1q1N2B1/1P2Q3/8/P4PPP/3RK1NR w Kk - 0 1`)
+	expect.Eq(t, p.fen(), `2r1kb1r/pp3ppp/2n1b3/1q1N2B1/1P2Q3/8/P4PPP/3RK1NR w Kk - 42 42`)
 }
 
 // No castles, en-passant.
 func TestPosition120(t *testing.T) {
 	p := NewGame(`1rr2k2/p1q5/3p2Q1/3Pp2p/8/1P3P2/1KPRN3/8 w - e6 42 42`).start()
-	expect.Eq(t, p.fen(), `1rr2k2/p1q5/3p2Q1/3Pp2p/8/1P3P2/1KPRN3/8 w - e6 0 1`)
+	expect.Eq(t, p.fen(), `1rr2k2/p1q5/3p2Q1/3Pp2p/8/1P3P2/1KPRN3/8 w - e6 42 42`)
+}
+
+// Half-move clock and full move number.
+func TestPosition121(t *testing.T) {
+	p := NewGame(`4k3/8/8/8/8/8/4P3/R3K3 b Q - 7 31`).start()
+	expect.Eq(t, p.halfmove, uint16(7))
+	expect.Eq(t, p.fullmove, uint16(31))
+
+	p = p.makeMove(NewMove(p, E8, D8))
+	expect.Eq(t, p.fen(), `3k4/8/8/8/8/8/4P3/R3K3 w Q - 8 32`)
+	p = p.makeMove(NewCastle(p, E1, C1))
+	expect.Eq(t, p.fen(), `3k4/8/8/8/8/8/4P3/2KR4 b - - 9 32`)
+	p = p.makeMove(NewMove(p, D8, E8))
+	p = p.makeMove(NewPawnMove(p, E2, E4))
+	expect.Eq(t, p.fen(), `4k3/8/8/8/4P3/8/8/2KR4 b - - 0 33`)
+}
+
+// Missing counters default to 0 and 1.
+func TestPosition122(t *testing.T) {
+	p := NewGame(`4k3/8/8/8/8/8/4P3/4K3 w - -`).start()
+	expect.Eq(t, p.fen(), `4k3/8/8/8/8/8/4P3/4K3 w - - 0 1`)
 }
 
 //\\ Donna Chess Format (DCF) tests.
@@ -101,18 +122,28 @@ func TestPosition130(t *testing.T) {
 // Castles, no en-passant.
 func TestPosition140(t *testing.T) {
 	p := NewGame(`2r1kb1r/pp3ppp/2n1b3/1q1N2B1/1P2Q3/8/P4PPP/3RK1NR w Kk - 42 42`).start()
-	expect.Eq(t, p.dcf(), `Ke1,Qe4,Rd1,Rh1,Bg5,Ng1,Nd5,Cg1,a2,f2,g2,h2,b4 : Ke8,Qb5,Rc8,Rh8,Be6,Bf8,Nc6,Cg8,a7,b7,f7,g7,h7`)
+	expect.Eq(t, p.dcf(), `M42,Ke1,Qe4,Rd1,Rh1,Bg5,Ng1,Nd5,Cg1,a2,f2,g2,h2,b4 : Ke8,Qb5,Rc8,Rh8,Be6,Bf8,Nc6,Cg8,a7,b7,f7,g7,h7`)
 }
 
 // No castles, en-passant.
 func TestPosition150(t *testing.T) {
 	p := NewGame(`1rr2k2/p1q5/3p2Q1/3Pp2p/8/1P3P2/1KPRN3/8 w - e6 42 42`).start()
-	expect.Eq(t, p.dcf(), `Kb2,Qg6,Rd2,Ne2,Ee6,c2,b3,f3,d5 : Kf8,Qc7,Rb8,Rc8,e5,h5,d6,a7`)
+	expect.Eq(t, p.dcf(), `M42,Kb2,Qg6,Rd2,Ne2,Ee6,c2,b3,f3,d5 : Kf8,Qc7,Rb8,Rc8,e5,h5,d6,a7`)
 
 	pp := NewGame(`M,Kb2,Qg6,Rd2,Ne2,Ee6,c2,b3,f3,d5`, `Kf8,Qc7,Rb8,Rc8,e5,h5,d6,a7`).start()
 	expect.Eq(t, pp.fen(), `1rr2k2/p1q5/3p2Q1/3Pp2p/8/1P3P2/1KPRN3/8 w - e6 0 1`)
 }
 
+// Move number.
+func TestPosition151(t *testing.T) {
+	p := NewGame(`Ke1,e4`, `M42,Ke8,e5`).start()
+	expect.Eq(t, p.fen(), `4k3/8/8/4p3/4P3/8/8/4K3 b - - 0 42`)
+	expect.Eq(t, p.dcf(), `Ke1,e4 : M42,Ke8,e5`)
+
+	p = NewGame(`M7,Ke1,e4`, `Ke8,e5`).start()
+	expect.Eq(t, p.dcf(), `M7,Ke1,e4 : Ke8,e5`)
+}
+
 // Position status.
 func TestPosition200(t *testing.T) {
 	p := NewGame().start()
@@ -122,8 +153,8 @@ func TestPosition200(t *testing.T) {
 // Mate in 1 move.
 func TestPosition210(t *testing.T) {
 	p := NewGame(`Kf8,Rh1,g6`, `Kh8,Bg8,g7,h7`).start()
-	rootNode = node // Reset ply().
-	expect.Eq(t, p.status(NewMove(p, H1, H6), Checkmate - ply()), WhiteWinning)
+	p.worker.rootNode = p.worker.node // Reset ply().
+	expect.Eq(t, p.status(NewMove(p, H1, H6), Checkmate - p.worker.ply()), WhiteWinning)
 }
 
 // Forced stalemate.
@@ -153,7 +184,7 @@ func TestPosition240(t *testing.T) {
 	p = p.makeMove(NewMove(p, A1, A2))
 	p = p.makeMove(NewMove(p, H6, H5)) // -- No NewMove(p, A2, A1) here --
 
-	rootNode = node // Reset ply().
+	p.worker.rootNode = p.worker.node // Reset ply().
 	expect.Eq(t, p.status(NewMove(p, A2, A1), 0), Repetition) // <-- Ka2-a1 causes rep #3.
 }
 
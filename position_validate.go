@@ -0,0 +1,183 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`fmt`
+	`regexp`
+	`strings`
+)
+
+// Position validation error codes.
+const (
+	InvalidFormat = iota + 1 // Malformed FEN or DCF string.
+	InvalidPieces            // Impossible number of pieces.
+	InvalidKings             // Missing or extra kings.
+	InvalidPawns             // Pawns on the first or last rank.
+	InvalidCheck             // The side that has just moved is in check.
+	InvalidCastles           // Castle rights that do not match the position.
+	InvalidEnpassant         // Impossible en-passant square.
+)
+
+// Describes why the position is not valid.
+type PositionError struct {
+	Code        int 	// One of the Invalid... error codes.
+	Reason      string 	// Human readable explanation.
+}
+
+func (e *PositionError) Error() string {
+	return `position: ` + e.Reason
+}
+
+func positionError(code int, format string, args ...interface{}) *PositionError {
+	return &PositionError{Code: code, Reason: fmt.Sprintf(format, args...)}
+}
+
+var reFenBoard = regexp.MustCompile(`^[1-8KQRBNPkqrbnp]+(/[1-8KQRBNPkqrbnp]+){7}$`)
+var reFenCastles = regexp.MustCompile(`^(-|[KQkqA-Ha-h]{1,4})$`)
+var reFenEnpassant = regexp.MustCompile(`^(-|[a-h][36])$`)
+var reFenNumber = regexp.MustCompile(`^[0-9]+$`)
+
+// Checks FEN string syntax without looking at the position it describes.
+func checkFEN(fen string) error {
+	fields := strings.Fields(fen)
+	if len(fields) < 4 || len(fields) > 6 {
+		return positionError(InvalidFormat, `expected 4 to 6 FEN fields, got %d`, len(fields))
+	}
+
+	if !reFenBoard.MatchString(fields[0]) {
+		return positionError(InvalidFormat, `invalid FEN board "%s"`, fields[0])
+	}
+	for i, rank := range strings.Split(fields[0], `/`) {
+		squares := 0
+		for _, char := range rank {
+			if char >= '1' && char <= '8' {
+				squares += int(char - '0')
+			} else {
+				squares++
+			}
+		}
+		if squares != 8 {
+			return positionError(InvalidFormat, `rank %d has %d squares`, 8 - i, squares)
+		}
+	}
+
+	if fields[1] != `w` && fields[1] != `b` {
+		return positionError(InvalidFormat, `invalid side to move "%s"`, fields[1])
+	}
+	if !reFenCastles.MatchString(fields[2]) {
+		return positionError(InvalidFormat, `invalid castle rights "%s"`, fields[2])
+	}
+	if !reFenEnpassant.MatchString(fields[3]) {
+		return positionError(InvalidFormat, `invalid en-passant square "%s"`, fields[3])
+	}
+	for _, number := range fields[4:] {
+		if !reFenNumber.MatchString(number) {
+			return positionError(InvalidFormat, `invalid move number "%s"`, number)
+		}
+	}
+
+	return nil
+}
+
+// Returns an error if the position could not possibly arise in a legal game.
+func (p *Position) validate() error {
+	for color := uint8(White); color <= uint8(Black); color++ {
+		if err := p.validatePieces(color); err != nil {
+			return err
+		}
+	}
+
+	if pawns := p.outposts[Pawn] | p.outposts[BlackPawn]; pawns & (maskRank[0] | maskRank[7]) != 0 {
+		return positionError(InvalidPawns, `pawn on %s`, squareName(pawns.first()))
+	}
+
+	if p.isInCheck(p.color ^ 1) {
+		return positionError(InvalidCheck, `%s king is in check while %s is to move`, C(p.color ^ 1), C(p.color))
+	}
+
+	if err := p.validateCastles(); err != nil {
+		return err
+	}
+
+	return p.validateEnpassant()
+}
+
+// Checks the number of kings, pawns, and pieces of the given color. Extra
+// queens, rooks, bishops, and knights must be promoted pawns.
+func (p *Position) validatePieces(color uint8) error {
+	switch kings := p.outposts[king(color)].count(); {
+	case kings == 0:
+		return positionError(InvalidKings, `%s king is missing`, C(color))
+	case kings > 1:
+		return positionError(InvalidKings, `%s has %d kings`, C(color), kings)
+	}
+
+	if pieces := p.outposts[color].count(); pieces > 16 {
+		return positionError(InvalidPieces, `%s has %d pieces`, C(color), pieces)
+	}
+
+	pawns := p.outposts[pawn(color)].count()
+	if pawns > 8 {
+		return positionError(InvalidPieces, `%s has %d pawns`, C(color), pawns)
+	}
+
+	promoted := max(0, p.outposts[queen(color)].count() - 1) +
+	            max(0, p.outposts[rook(color)].count() - 2) +
+	            max(0, p.outposts[bishop(color)].count() - 2) +
+	            max(0, p.outposts[knight(color)].count() - 2)
+	if promoted > 8 - pawns {
+		return positionError(InvalidPieces, `%s has %d promoted pieces and %d pawns`, C(color), promoted, pawns)
+	}
+
+	return nil
+}
+
+// Castle rights require the king and the castling rook to be on their home
+// squares, which in standard chess are E1 and A1 or H1 (E8 and A8 or H8 for
+// Black).
+func (p *Position) validateCastles() error {
+	castling := &p.worker.game.castling
+	for color := uint8(White); color <= uint8(Black); color++ {
+		for side := 0; side <= 1; side++ {
+			if p.castles & castleSide(color, side) == 0 {
+				continue
+			}
+			if home := castling.king[color]; p.pieces[home] != king(color) {
+				return positionError(InvalidCastles, `%s can't castle without king on %s`, C(color), squareName(home))
+			}
+			if home := castling.rook[color][side]; p.pieces[home] != rook(color) {
+				return positionError(InvalidCastles, `%s can't castle without rook on %s`, C(color), squareName(home))
+			}
+			if castling.chess960 {
+				continue
+			}
+			if home := castling.king[color]; home != E1 + 56 * int(color) {
+				return positionError(InvalidCastles, `%s can't castle with king on %s in standard chess`, C(color), squareName(home))
+			}
+			if home := castling.rook[color][side]; home != square(int(color) * 7, 7 * (1 - side)) {
+				return positionError(InvalidCastles, `%s can't castle with rook on %s in standard chess`, C(color), squareName(home))
+			}
+		}
+	}
+
+	return nil
+}
+
+// En-passant square must be right behind the pawn that has just made double
+// step move.
+func (p *Position) validateEnpassant() error {
+	if p.enpassant == 0 {
+		return nil
+	}
+
+	square := int(p.enpassant)
+	from, to := square + eight[p.color], square - eight[p.color]
+	if row(square) != 5 - 3 * int(p.color) || p.pieces[square] != 0 || p.pieces[from] != 0 || p.pieces[to] != pawn(p.color ^ 1) {
+		return positionError(InvalidEnpassant, `impossible en-passant square %s`, squareName(square))
+	}
+
+	return nil
+}
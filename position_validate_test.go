@@ -0,0 +1,137 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `testing`)
+
+func invalidPosition(args ...string) *PositionError {
+	if _, err := NewGame(args...).setup(); err != nil {
+		return err.(*PositionError)
+	}
+	return &PositionError{}
+}
+
+func TestPositionValidate000(t *testing.T) {
+	p, err := NewGame().setup()
+	expect.True(t, err == nil)
+	expect.Eq(t, p.fen(), `rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1`)
+
+	_, err = NewGame(`Ke1,Ra1,Rh1`, `M,Ke8,e5`).setup()
+	expect.True(t, err == nil)
+}
+
+// Malformed FEN and DCF.
+func TestPositionValidate010(t *testing.T) {
+	err := invalidPosition(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w`)
+	expect.Eq(t, err.Code, InvalidFormat)
+	expect.Eq(t, err.Error(), `position: expected 4 to 6 FEN fields, got 2`)
+	expect.True(t, NewGame(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w`).start() == nil)
+}
+
+func TestPositionValidate020(t *testing.T) {
+	err := invalidPosition(`rnbqkbnr/ppppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1`)
+	expect.Eq(t, err.Code, InvalidFormat)
+	expect.Eq(t, err.Error(), `position: rank 7 has 9 squares`)
+
+	err = invalidPosition(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR x KQkq - 0 1`)
+	expect.Eq(t, err.Code, InvalidFormat)
+	err = invalidPosition(`rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq e4 0 1`)
+	expect.Eq(t, err.Code, InvalidFormat)
+}
+
+func TestPositionValidate030(t *testing.T) {
+	err := invalidPosition(`Ke1,Xz9`, `Ke8`)
+	expect.Eq(t, err.Code, InvalidFormat)
+}
+
+// Kings.
+func TestPositionValidate100(t *testing.T) {
+	err := invalidPosition(`4k3/8/8/8/8/8/8/8 w - - 0 1`)
+	expect.Eq(t, err.Code, InvalidKings)
+	expect.Eq(t, err.Error(), `position: white king is missing`)
+}
+
+func TestPositionValidate110(t *testing.T) {
+	err := invalidPosition(`Ke1`, `Ke8,Ka8`)
+	expect.Eq(t, err.Code, InvalidKings)
+	expect.Eq(t, err.Error(), `position: black has 2 kings`)
+}
+
+// Piece count.
+func TestPositionValidate120(t *testing.T) {
+	err := invalidPosition(`QQQQQQQQ/QQ6/8/8/8/8/8/K6k w - - 0 1`)
+	expect.Eq(t, err.Code, InvalidPieces)
+	expect.Eq(t, err.Error(), `position: white has 9 promoted pieces and 0 pawns`)
+}
+
+func TestPositionValidate130(t *testing.T) {
+	err := invalidPosition(`Ke1,a2,b2,c2,d2,e2,f2,g2,h2,a3`, `Ke8`)
+	expect.Eq(t, err.Code, InvalidPieces)
+	expect.Eq(t, err.Error(), `position: white has 9 pawns`)
+}
+
+// Four queens and five pawns is still fine.
+func TestPositionValidate140(t *testing.T) {
+	_, err := NewGame(`Ke1,Qa1,Qb1,Qc1,Qd1,a2,b2,c2,d2,e2`, `Ke8`).setup()
+	expect.True(t, err == nil)
+
+	expect.Eq(t, invalidPosition(`Ke1,Qa1,Qb1,Qc1,Qd1,a2,b2,c2,d2,e2,f2`, `Ke8`).Code, InvalidPieces)
+}
+
+// Pawns on first or last rank.
+func TestPositionValidate200(t *testing.T) {
+	err := invalidPosition(`4k3/8/8/8/8/8/8/P3K3 w - - 0 1`)
+	expect.Eq(t, err.Code, InvalidPawns)
+	expect.Eq(t, err.Error(), `position: pawn on a1`)
+}
+
+// Side that is not to move is in check.
+func TestPositionValidate300(t *testing.T) {
+	err := invalidPosition(`4k3/8/8/8/8/8/4R3/4K3 w - - 0 1`)
+	expect.Eq(t, err.Code, InvalidCheck)
+	expect.Eq(t, err.Error(), `position: black king is in check while white is to move`)
+}
+
+// Castle rights.
+func TestPositionValidate400(t *testing.T) {
+	err := invalidPosition(`r3k3/8/8/8/8/8/8/4K2R w KQq - 0 1`)
+	expect.Eq(t, err.Code, InvalidCastles)
+	expect.Eq(t, err.Error(), `position: white can't castle without rook on a1`)
+}
+
+func TestPositionValidate410(t *testing.T) {
+	err := invalidPosition(`r3k3/8/8/8/8/8/8/3K3R w K - 0 1`)
+	expect.Eq(t, err.Code, InvalidCastles)
+	expect.Eq(t, err.Error(), `position: white can't castle without king on e1`)
+}
+
+// Castle rights without matching rook.
+func TestPositionValidate420(t *testing.T) {
+	_, err := NewEngine(`chess960`, true).NewGame(`1r3k2/8/8/8/8/8/8/1R3K2 w HAkq - 0 1`).setup()
+	expect.Eq(t, err.(*PositionError).Code, InvalidCastles)
+	expect.Eq(t, err.Error(), `position: white can't castle without rook on h1`)
+}
+
+// Castling rook on the file other than A or H in standard chess.
+func TestPositionValidate430(t *testing.T) {
+	err := invalidPosition(`1r2k3/8/8/8/8/8/8/1R2K3 w Bb - 0 1`)
+	expect.Eq(t, err.Code, InvalidCastles)
+	expect.Eq(t, err.Error(), `position: white can't castle with rook on b1 in standard chess`)
+}
+
+// En-passant square.
+func TestPositionValidate500(t *testing.T) {
+	_, err := NewGame(`4k3/8/8/4pP2/8/8/8/4K3 w - e6 0 1`).setup()
+	expect.True(t, err == nil)
+}
+
+func TestPositionValidate510(t *testing.T) {
+	err := invalidPosition(`4k3/8/8/8/8/8/8/4K3 w - e6 0 1`)
+	expect.Eq(t, err.Code, InvalidEnpassant)
+	expect.Eq(t, err.Error(), `position: impossible en-passant square e6`)
+
+	err = invalidPosition(`4k3/8/8/4pP2/8/8/8/4K3 b - e6 0 1`)
+	expect.Eq(t, err.Code, InvalidEnpassant) // Wrong side to move.
+}
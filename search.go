@@ -4,29 +4,39 @@
 
 package donna
 
+import (`sort`)
+
 // Root node search.
 func (p *Position) search(alpha, beta, depth int) (score int) {
+	w, engine := p.worker, p.worker.game.engine
 	inCheck := p.isInCheck(p.color)
 	cacheFlags := uint8(cacheAlpha)
 
+	// Only the main worker reports search progress. Secondary MultiPV variations
+	// get reported once they have been searched.
+	report := engine.uci && w.isMain() && len(w.skip) == 0
+
 	// Root move generator makes sure all generated moves are valid. The
 	// best move found so far is always the first one we search.
 	gen := NewRootGen(p, depth)
 	if depth == 1 {
-		gen.generateRootMoves()
+		gen.generateRootMoves(w.game.rootMoves...)
 	} else {
 		gen.rearrangeRootMoves()
-		if depth == 9 { // Skip moves that failed all iterations so far.
+		if depth == 9 && engine.multiPV < 2 { // Skip moves that failed all iterations so far.
 			gen.cleanupRootMoves(depth)
 		}
 	}
 
 	moveCount, bestMove := 0, Move(0)
 	for move := gen.NextMove(); move != 0; move = gen.NextMove() {
+		if w.skipped(move) { // Already covered by preceding MultiPV variation.
+			continue
+		}
 		position := p.makeMove(move)
 		moveCount++
-		if engine.uci {
-			engine.uciMove(move, moveCount, depth)
+		if report {
+			engine.uciMove(w.game, move, moveCount, depth)
 		}
 
 		// Search depth extension.
@@ -36,10 +46,10 @@ func (p *Position) search(alpha, beta, depth int) (score int) {
 		}
 
 		if moveCount == 1 {
-			game.deepening = true
+			w.deepening = true
 			score = -position.searchTree(-beta, -alpha, newDepth)
 		} else {
-			game.deepening = false
+			w.deepening = false
 			score = -position.searchTree(-alpha - 1, -alpha, newDepth)
 			if score > alpha { // && score < beta {
 				score = -position.searchTree(-beta, -alpha, newDepth)
@@ -47,10 +57,12 @@ func (p *Position) search(alpha, beta, depth int) (score int) {
 		}
 		position.undoLastMove()
 
-		if engine.clock.halt {
-			//Log("searchRoot: bestMove %s pv[0][0] %s alpha %d\n", bestMove, game.pv[0][0], alpha)
-			game.nodes += moveCount
-			if engine.uci { // Report alpha as score since we're returning alpha.
+		if w.halted() {
+			//Log("searchRoot: bestMove %s pv[0][0] %s alpha %d\n", bestMove, w.pv[0][0], alpha)
+			if depth == 1 && moveCount == 1 { // Make sure we've got a move to play.
+				w.saveBest(0, move)
+			}
+			if report { // Report alpha as score since we're returning alpha.
 				engine.uciScore(depth, alpha, alpha, beta)
 			}
 			return alpha
@@ -59,12 +71,14 @@ func (p *Position) search(alpha, beta, depth int) (score int) {
 		if moveCount == 1 || score > alpha {
 			bestMove = move
 			cacheFlags = cacheExact
-			game.saveBest(0, move)
+			w.saveBest(0, move)
 			gen.scoreMove(depth, score)
 
-			if moveCount > 1 {
-				game.volatility++
-				engine.debug("# New move %s Depth %d Volatility %.2f\n", move, depth, game.volatility)
+			if moveCount > 1 && len(w.skip) == 0 {
+				w.volatility++
+				if w.isMain() {
+					engine.debug("# New move %s Depth %d Volatility %.2f\n", move, depth, w.volatility)
+				}
 			}
 
 			alpha = max(score, alpha)
@@ -84,20 +98,22 @@ func (p *Position) search(alpha, beta, depth int) (score int) {
 		} else {
 			score = 0
 		}
-		if engine.uci {
+		if report {
 			engine.uciScore(depth, score, alpha, beta)
 		}
 		return
 	}
 
-	game.nodes += moveCount
 	if score >= beta && !inCheck {
-		game.saveGood(depth, bestMove)
+		w.saveGood(depth, bestMove)
 	}
 
+	// Secondary MultiPV variations are not the best so don't cache them.
 	score = alpha
-	p.cache(bestMove, score, depth, cacheFlags)
-	if engine.uci {
+	if len(w.skip) == 0 {
+		p.cache(bestMove, score, depth, cacheFlags)
+	}
+	if report {
 		engine.uciScore(depth, score, alpha, beta)
 	}
 
@@ -110,9 +126,11 @@ func (p *Position) solve(depth int) Move {
 		NewRootGen(p, 1).generateRootMoves()
 	}
 	p.search(-Checkmate, Checkmate, depth)
-	return game.pv[0][0]
+	return p.worker.pv[0][0]
 }
 
+// Counts leaf nodes of the move generation tree of the given depth. At depth 1
+// we do bulk counting: valid moves get counted without actually making them.
 func (p *Position) Perft(depth int) (total int64) {
 	if depth == 0 {
 		return 1
@@ -123,9 +141,40 @@ func (p *Position) Perft(depth int) (total int64) {
 		if !gen.isValid(move) {
 			continue
 		}
+		if depth == 1 {
+			total++
+			continue
+		}
 		position := p.makeMove(move)
 		total += position.Perft(depth - 1)
 		position.undoLastMove()
 	}
 	return
 }
+
+// Perft divide: returns valid root moves along with the number of leaf nodes
+// for each of them. The moves are sorted in UCI notation order, which makes
+// it easy to compare the counts with other engines.
+func (p *Position) Divide(depth int) (moves []Move, counts []int64) {
+	for _, move := range NewGen(p, MaxPly).generateAllMoves().validOnly().allMoves() {
+		moves = append(moves, move)
+	}
+	sort.Sort(byNotation{moves, p.worker.game})
+
+	for _, move := range moves {
+		position := p.makeMove(move)
+		counts = append(counts, position.Perft(depth - 1))
+		position.undoLastMove()
+	}
+	return
+}
+
+// Sorting moves by their UCI notation.
+type byNotation struct {
+	list []Move
+	game *Game
+}
+
+func (her byNotation) Len() int           { return len(her.list) }
+func (her byNotation) Swap(i, j int)      { her.list[i], her.list[j] = her.list[j], her.list[i] }
+func (her byNotation) Less(i, j int) bool { return her.list[i].uci(her.game) < her.list[j].uci(her.game) }
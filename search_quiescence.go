@@ -10,15 +10,17 @@ func (p *Position) searchQuiescence(alpha, beta, depth int) int {
 }
 
 func (p *Position) searchQuiescenceWithFlag(alpha, beta, depth int, capturesOnly bool) (score int) {
-	ply := ply()
+	w := p.worker
+	ply := w.ply()
 
 	// Reset principal variation.
-	game.pv[ply] = game.pv[ply][:0]
+	w.pv[ply] = w.pv[ply][:0]
 
 	// Return if it's time to stop search.
-	if ply >= MaxPly || engine.clock.halt {
+	if ply >= MaxPly || w.halted() {
 		return p.Evaluate()
 	}
+	w.countQnode()
 
 	// Insufficient material and repetition/perpetual check pruning.
 	if p.insufficient() || p.repetition() || p.fifty() {
@@ -32,9 +34,9 @@ func (p *Position) searchQuiescenceWithFlag(alpha, beta, depth int, capturesOnly
 	if cached := p.probeCache(); cached != nil {
 		if cached.depth >= depth {
 			score := cached.score
-			if score > Checkmate - MaxPly && score <= Checkmate {
+			if score > TablebaseWin - MaxPly && score <= Checkmate {
 				score -= ply
-			} else if score >= -Checkmate && score < -Checkmate + MaxPly {
+			} else if score >= -Checkmate && score < -TablebaseWin + MaxPly {
 				score += ply
 			}
 			if (cached.flags == cacheExact && isPrincipal) ||
@@ -71,7 +73,7 @@ func (p *Position) searchQuiescenceWithFlag(alpha, beta, depth int, capturesOnly
 		}
 
 		// Check if the move is an useless capture.
-		useless := !inCheck && !isPrincipal && !move.isPromo() && staticScore + pieceValue[move.capture()] + 72 < alpha
+		useless := !inCheck && !isPrincipal && !move.isPromo() && staticScore + p.params().pieceValue[move.capture()] + 72 < alpha
 
 		position := p.makeMove(move)
 		moveCount++
@@ -86,6 +88,10 @@ func (p *Position) searchQuiescenceWithFlag(alpha, beta, depth int, capturesOnly
 		score = -position.searchQuiescenceWithFlag(-beta, -alpha, depth, true)
 		position.undoLastMove()
 
+		if w.halted() {
+			return alpha
+		}
+
 		if score > alpha {
 			alpha = score
 			bestMove = move
@@ -95,7 +101,7 @@ func (p *Position) searchQuiescenceWithFlag(alpha, beta, depth int, capturesOnly
 				cacheFlags = cacheBeta
 				break
 			}
-			game.saveBest(ply, move)
+			w.saveBest(ply, move)
 		}
 	}
 
@@ -111,8 +117,7 @@ func (p *Position) searchQuiescenceWithFlag(alpha, beta, depth int, capturesOnly
 			score = -position.searchQuiescenceWithFlag(-beta, -alpha, depth, false)
 			position.undoLastMove()
 
-			if engine.clock.halt {
-				game.qnodes += moveCount
+			if w.halted() {
 				//Log("searchQui at %d (%s): move %s (%d) score %d alpha %d\n", depth, C(p.color), move, moveCount, score, alpha)
 				return alpha
 			}
@@ -126,13 +131,11 @@ func (p *Position) searchQuiescenceWithFlag(alpha, beta, depth int, capturesOnly
 					cacheFlags = cacheBeta
 					break
 				}
-				game.saveBest(ply, move)
+				w.saveBest(ply, move)
 			}
 		}
 	}
 
-	game.qnodes += moveCount
-
 	score = alpha
 	if inCheck && moveCount == 0 {
 		score = -Checkmate + ply
@@ -118,8 +118,8 @@ func TestSearch196(t *testing.T) { // Queen-side castle
 
 // Mate in 3.
 
-func TestSearch200(t *testing.T) {
-	move := NewGame(`Kf8,Re7,Nd5`, `Kh8,Bh5`).start().solve(5)
+func TestSearch200(t *testing.T) { // Needs transposition table to find shortest mate.
+	move := NewEngine(`cache`, 0.5).NewGame(`Kf8,Re7,Nd5`, `Kh8,Bh5`).start().solve(5)
 	expect.Eq(t, move, `Re7-g7`)
 }
 
@@ -205,3 +205,178 @@ func TestSearch450(t *testing.T) {
 	position := NewGame().start()
 	expect.Eq(t, position.Perft(5), int64(4865609))
 }
+
+// Perft suite: Kiwipete.
+func TestSearch460(t *testing.T) {
+	position := NewGame(`r3k2r/p1ppqpb1/bn2pnp1/3PN3/1p2P3/2N2Q1p/PPPBBPPP/R3K2R w KQkq - 0 1`).start()
+	expect.Eq(t, position.Perft(1), int64(48))
+	expect.Eq(t, position.Perft(2), int64(2039))
+	expect.Eq(t, position.Perft(3), int64(97862))
+	expect.Eq(t, position.Perft(4), int64(4085603))
+}
+
+// Perft suite: position 3 (en-passant and discovered checks).
+func TestSearch470(t *testing.T) {
+	position := NewGame(`8/2p5/3p4/KP5r/1R3p1k/8/4P1P1/8 w - - 0 1`).start()
+	expect.Eq(t, position.Perft(1), int64(14))
+	expect.Eq(t, position.Perft(3), int64(2812))
+	expect.Eq(t, position.Perft(5), int64(674624))
+}
+
+// Perft suite: position 4 and its mirror (promotions and castles).
+func TestSearch480(t *testing.T) {
+	position := NewGame(`r3k2r/Pppp1ppp/1b3nbN/nP6/BBP1P3/q4N2/Pp1P2PP/R2Q1RK1 w kq - 0 1`).start()
+	expect.Eq(t, position.Perft(1), int64(6))
+	expect.Eq(t, position.Perft(3), int64(9467))
+	expect.Eq(t, position.Perft(4), int64(422333))
+
+	mirror := NewGame(`r2q1rk1/pP1p2pp/Q4n2/bbp1p3/Np6/1B3NBn/pPPP1PPP/R3K2R b KQ - 0 1`).start()
+	expect.Eq(t, mirror.Perft(4), int64(422333))
+}
+
+// Perft suite: position 5.
+func TestSearch490(t *testing.T) {
+	position := NewGame(`rnbq1k1r/pp1Pbppp/2p5/8/2B5/8/PPP1NnPP/RNBQK2R w KQ - 1 8`).start()
+	expect.Eq(t, position.Perft(1), int64(44))
+	expect.Eq(t, position.Perft(3), int64(62379))
+	expect.Eq(t, position.Perft(4), int64(2103487))
+}
+
+// Perft suite: position 6.
+func TestSearch500(t *testing.T) {
+	position := NewGame(`r4rk1/1pp1qppp/p1np1n2/2b1p1B1/2B1P1b1/P1NP1N2/1PP1QPPP/R4RK1 w - - 0 10`).start()
+	expect.Eq(t, position.Perft(1), int64(46))
+	expect.Eq(t, position.Perft(3), int64(89890))
+	expect.Eq(t, position.Perft(4), int64(3894594))
+}
+
+// Perft suite: en-passant edge cases.
+func TestSearch510(t *testing.T) {
+	expect.Eq(t, NewGame(`3k4/3p4/8/K1P4r/8/8/8/8 b - - 0 1`).start().Perft(6), int64(1134888))
+	expect.Eq(t, NewGame(`8/8/4k3/8/2p5/8/B2P2K1/8 w - - 0 1`).start().Perft(6), int64(1015133))
+	expect.Eq(t, NewGame(`8/8/1k6/2b5/2pP4/8/5K2/8 b - d3 0 1`).start().Perft(6), int64(1440467))
+}
+
+// Perft suite: castle edge cases.
+func TestSearch520(t *testing.T) {
+	expect.Eq(t, NewGame(`5k2/8/8/8/8/8/8/4K2R w K - 0 1`).start().Perft(6), int64(661072))
+	expect.Eq(t, NewGame(`3k4/8/8/8/8/8/8/R3K3 w Q - 0 1`).start().Perft(6), int64(803711))
+	expect.Eq(t, NewGame(`r3k2r/1b4bq/8/8/8/8/7B/R3K2R w KQkq - 0 1`).start().Perft(4), int64(1274206))
+	expect.Eq(t, NewGame(`r3k2r/8/3Q4/8/8/5q2/8/R3K2R b KQkq - 0 1`).start().Perft(4), int64(1720476))
+}
+
+// Perft suite: promotion edge cases.
+func TestSearch530(t *testing.T) {
+	expect.Eq(t, NewGame(`2K2r2/4P3/8/8/8/8/8/3k4 w - - 0 1`).start().Perft(6), int64(3821001))
+	expect.Eq(t, NewGame(`8/8/1P2K3/8/2n5/1q6/8/5k2 b - - 0 1`).start().Perft(5), int64(1004658))
+	expect.Eq(t, NewGame(`4k3/1P6/8/8/8/8/K7/8 w - - 0 1`).start().Perft(6), int64(217342))
+	expect.Eq(t, NewGame(`8/P1k5/K7/8/8/8/8/8 w - - 0 1`).start().Perft(6), int64(92683))
+	expect.Eq(t, NewGame(`8/k1P5/8/1K6/8/8/8/8 w - - 0 1`).start().Perft(7), int64(567584))
+}
+
+// Perft suite: self stalemate and checkmate.
+func TestSearch540(t *testing.T) {
+	expect.Eq(t, NewGame(`K1k5/8/P7/8/8/8/8/8 w - - 0 1`).start().Perft(6), int64(2217))
+	expect.Eq(t, NewGame(`8/8/2k5/5q2/5n2/8/5K2/8 b - - 0 1`).start().Perft(4), int64(23527))
+}
+
+// Perft divide: node counts per root move sorted by notation.
+func TestSearch550(t *testing.T) {
+	position := NewGame(`2K2r2/4P3/8/8/8/8/8/3k4 w - - 0 1`).start()
+	moves, counts := position.Divide(1)
+	expect.Eq(t, moves, `[Kc8-b7 Kc8-c7 Kc8-d7 e7-e8B e7-e8N e7-e8Q e7-e8R e7xf8B e7xf8N e7xf8Q e7xf8R]`)
+	expect.Eq(t, counts, []int64{ 1, 1, 1, 1, 1, 1, 1, 1, 1, 1, 1 })
+}
+
+func TestSearch560(t *testing.T) {
+	position := NewGame().start()
+	moves, counts := position.Divide(3)
+	total := int64(0)
+	for _, count := range counts {
+		total += count
+	}
+	expect.Eq(t, len(moves), 20)
+	expect.Eq(t, moves[0].uci(position.worker.game), `a2a3`)
+	expect.Eq(t, counts[0], int64(380))
+	expect.Eq(t, total, position.Perft(3))
+}
+
+// Independent games searching concurrently.
+func TestSearch900(t *testing.T) {
+	moves := make(chan Move)
+	go func() {
+		moves <- NewEngine(`cache`, 0.5).NewGame(`Kf8,Rh1,g6`, `Kh8,Bg8,g7,h7`).start().solve(3)
+	}()
+	move := NewEngine(`cache`, 0.5).NewGame(`Kf4,Qc2,Nc5`, `Kd4`).start().solve(3)
+	expect.Eq(t, move, `Nc5-b7`)
+	expect.Eq(t, <-moves, `Rh1-h6`)
+}
+
+// Fixed node budget: stop as soon as the budget is used up.
+func TestSearch910(t *testing.T) {
+	game := NewEngine(`nodes`, 5000).NewGame()
+	game.start()
+	move := game.Think()
+	nodes, qnodes := game.nodeCount()
+	expect.Eq(t, nodes + qnodes, 5000)
+	expect.True(t, move != Move(0))
+}
+
+// Node budget runs out before the first root move gets searched.
+func TestSearch920(t *testing.T) {
+	game := NewEngine(`nodes`, 1).NewGame()
+	game.start()
+	move := game.Think()
+	nodes, qnodes := game.nodeCount()
+	expect.Eq(t, nodes + qnodes, 1)
+	expect.True(t, move != Move(0))
+}
+
+// MultiPV: best root moves get searched with their own variations.
+func TestSearch930(t *testing.T) {
+	game := NewEngine(`multipv`, 3, `depth`, 4).NewGame(`Kf8,Rh1,g6`, `Kh8,Bg8,g7,h7`)
+	game.start()
+	move := game.Think()
+	variations := game.workers[0].variations
+	expect.Eq(t, move.String(), `Rh1-h6`)
+	expect.Eq(t, len(variations), 3)
+	expect.Eq(t, variations[0].moves[0], move)
+	expect.True(t, variations[1].moves[0] != move && variations[2].moves[0] != move)
+	expect.True(t, variations[1].moves[0] != variations[2].moves[0])
+	expect.True(t, variations[0].score > variations[1].score)
+}
+
+// MultiPV can't exceed the number of valid root moves.
+func TestSearch940(t *testing.T) {
+	game := NewEngine(`multipv`, 5, `depth`, 3).NewGame(`Ka1,h2`, `Kh8,Qc2`)
+	game.start()
+	game.Think()
+	expect.Eq(t, len(game.workers[0].variations), 2)
+}
+
+// Search moves: mate in 1 is not among the moves we're allowed to consider.
+func TestSearch950(t *testing.T) {
+	engine := NewEngine(`depth`, 4)
+	game := engine.NewGame(`Kf8,Rh1,g6`, `Kh8,Bg8,g7,h7`)
+	p := game.start()
+	engine.options.searchMoves = []Move{ NewMove(p, H1, H5), NewMove(p, F8, E7) }
+	move := game.Think()
+	expect.True(t, move == engine.options.searchMoves[0] || move == engine.options.searchMoves[1])
+	expect.True(t, game.workers[0].rootpv[0] == move)
+}
+
+// MultiPV variations are ranked by their score, the best one being the move.
+func TestSearch960(t *testing.T) {
+	for _, depth := range []int{ 4, 5, 6 } {
+		game := NewEngine(`multipv`, 4, `depth`, depth).NewGame(`r1bqkb1r/pppp1ppp/2n2n2/4p3/2B1P3/5N2/PPPP1PPP/RNBQK2R w KQkq - 4 4`)
+		game.start()
+		move := game.Think()
+		variations := game.workers[0].variations
+		expect.Eq(t, len(variations), 4)
+		expect.Eq(t, variations[0].moves[0], move)
+		expect.Eq(t, game.workers[0].rootpv[0], move)
+		for i := 1; i < len(variations); i++ {
+			expect.True(t, variations[i-1].score >= variations[i].score)
+		}
+	}
+}
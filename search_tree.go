@@ -7,15 +7,17 @@ package donna
 import ()
 
 func (p *Position) searchTree(alpha, beta, depth int) (score int) {
-	ply := ply()
+	w := p.worker
+	ply := w.ply()
 
 	// Reset principal variation.
-	game.pv[ply] = game.pv[ply][:0]
+	w.pv[ply] = w.pv[ply][:0]
 
 	// Return if it's time to stop search.
-	if ply >= MaxPly || engine.clock.halt {
+	if ply >= MaxPly || w.halted() {
 		return p.Evaluate()
 	}
+	w.countNode()
 
 	// Insufficient material and repetition/perpetual check pruning.
 	if p.insufficient() || p.repetition() || p.fifty() {
@@ -42,22 +44,42 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 		cachedMove = cached.move
 		if cached.depth >= depth {
 			score := cached.score
-			if score > Checkmate - MaxPly && score <= Checkmate {
+			if score > TablebaseWin - MaxPly && score <= Checkmate {
 				score -= ply
-			} else if score >= -Checkmate && score < -Checkmate + MaxPly {
+			} else if score >= -Checkmate && score < -TablebaseWin + MaxPly {
 				score += ply
 			}
 			if (cached.flags == cacheExact && isPrincipal) ||
 			   (cached.flags == cacheBeta  && score >= beta) ||
 			   (cached.flags == cacheAlpha && score <= alpha) {
 				if score >= beta && !inCheck && cachedMove != 0 && cachedMove.isQuiet() {
-					game.saveGood(depth, cachedMove)
+					w.saveGood(depth, cachedMove)
 				}
 				return score
 			}
 		}
 	}
 
+	// Probe endgame tablebases right after captures and pawn moves. Wins and
+	// losses are only bounds since the search might still find a checkmate.
+	if engine := w.game.engine; engine.tablebase != nil && !p.reversible && ply < MaxPly {
+		pieces := p.board.count()
+		if pieces < engine.tablebase.pieces || (pieces == engine.tablebase.pieces && depth >= engine.syzygyDepth) {
+			if wdl, ok := engine.tablebase.probeWdl(p); ok {
+				score, flags := engine.tablebase.score(wdl, ply), uint8(cacheExact)
+				if score > 0 {
+					flags = cacheBeta
+				} else if score < 0 {
+					flags = cacheAlpha
+				}
+				if flags == cacheExact || (flags == cacheBeta && score >= beta) || (flags == cacheAlpha && score <= alpha) {
+					p.cache(Move(0), score, min(depth + 6, MaxDepth), flags)
+					return score
+				}
+			}
+		}
+	}
+
 	// Quiescence search.
 	if !inCheck && depth < 1 {
 		return p.searchQuiescence(alpha, beta, depth)
@@ -102,7 +124,6 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 	// Null move pruning.
 	if !inCheck && !isNull && depth > 1 && p.outposts[p.color].count() > 5 {
 		position := p.makeNullMove()
-		game.nodes++
 		nullScore := -position.searchTree(-beta, -beta + 1, depth - 1 - 3)
 		position.undoNullMove()
 
@@ -117,8 +138,8 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 	// Internal iterative deepening.
 	if cachedMove == 0 && depth > 4 {
 		p.searchTree(alpha, beta, depth - 4)
-		if len(game.pv[ply]) > 0 {
-			cachedMove = game.pv[ply][0]
+		if len(w.pv[ply]) > 0 {
+			cachedMove = w.pv[ply][0]
 		}
 	}
 
@@ -186,8 +207,7 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 		}
 		position.undoLastMove()
 
-		if engine.clock.halt {
-			game.nodes += moveCount
+		if w.halted() {
 			//Log("searchTree at %d (%s): move %s (%d) score %d alpha %d\n", depth, C(p.color), move, moveCount, score, alpha)
 			return alpha
 		}
@@ -196,7 +216,7 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 			alpha = score
 			bestMove = move
 			cacheFlags = cacheExact
-			game.saveBest(ply, move)
+			w.saveBest(ply, move)
 
 			if alpha >= beta {
 				cacheFlags = cacheBeta
@@ -205,8 +225,6 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 		}
 	}
 
-	game.nodes += moveCount
-
 	if moveCount == 0 {
 		if inCheck {
 			alpha = -Checkmate + ply
@@ -214,7 +232,7 @@ func (p *Position) searchTree(alpha, beta, depth int) (score int) {
 			alpha = 0
 		}
 	} else if score >= beta && !inCheck {
-		game.saveGood(depth, bestMove)
+		w.saveGood(depth, bestMove)
 	}
 
 	score = alpha
@@ -0,0 +1,184 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bytes`
+	`fmt`
+	`io`
+	`math/rand`
+	`sync`
+)
+
+// Self-play training data generator: plays fast fixed depth or fixed nodes
+// games starting off random openings, and records quiet positions along with
+// search scores and game results. Each game is seeded with the seed plus game
+// number so that the games stay reproducible regardless of the number of
+// threads (though with multiple threads they might get written out of order).
+type SelfPlay struct {
+	depth       int 	// Search depth per move.
+	nodes       int 	// Search nodes per move.
+	games       int 	// Number of games to play.
+	threads     int 	// Number of games to play in parallel.
+	cache       int 	// Cache size (MB) of each thread's engine.
+	seed        int64 	// Random seed.
+	randomPlies int 	// Number of random moves to start each game with.
+	evalFile    string 	// Neural network weights file, if any.
+	referee     *Match 	// Adjudicates the games: resign, draw, and maximum number of moves.
+}
+
+// Creates self-play generator. Besides its own settings it takes adjudication
+// settings of NewMatch(), ex. "resignscore", 500 or "maxmoves", 200.
+func NewSelfPlay(args ...interface{}) *SelfPlay {
+	sp := &SelfPlay{games: 100, threads: 1, cache: 4, randomPlies: 8}
+	for i := 0; i < len(args); i += 2 {
+		switch value := args[i+1]; args[i] {
+		case `depth`:
+			sp.depth = value.(int)
+		case `nodes`:
+			sp.nodes = value.(int)
+		case `games`:
+			sp.games = value.(int)
+		case `threads`:
+			sp.threads = max(1, min(value.(int), MaxThreads))
+		case `cache`:
+			sp.cache = value.(int)
+		case `seed`:
+			sp.seed = value.(int64)
+		case `randomplies`:
+			sp.randomPlies = value.(int)
+		case `evalfile`:
+			sp.evalFile = value.(string)
+		}
+	}
+	if sp.depth == 0 && sp.nodes == 0 {
+		sp.depth = 6
+	}
+	sp.referee = NewMatch(nil, nil, args...)
+
+	return sp
+}
+
+// Plays the games and writes quiet positions to the writer, one per line: FEN,
+// search score in centipawns from White's point of view, and game result, ex:
+//
+//   r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - 2 3 32 1-0
+//
+// The callback, if any, gets invoked after each game with the number of games
+// played and positions written so far. Returns the number of positions written.
+func (sp *SelfPlay) Run(w io.Writer, callback func(games, positions int)) (positions int, err error) {
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+
+	played, jobs := 0, make(chan int)
+	for thread := 0; thread < sp.threads; thread++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			engine := NewEngine(`quiet`, true, `ownbook`, false, `cache`, sp.cache, `depth`, sp.depth, `nodes`, sp.nodes,
+				`evalfile`, sp.evalFile, `usenetwork`, sp.evalFile != ``)
+			for index := range jobs {
+				lines := sp.play(engine, index)
+
+				mutex.Lock()
+				if err == nil {
+					_, err = w.Write(lines.Bytes())
+				}
+				played, positions = played + 1, positions + bytes.Count(lines.Bytes(), []byte{'\n'})
+				if callback != nil {
+					callback(played, positions)
+				}
+				mutex.Unlock()
+			}
+		}()
+	}
+
+	for index := 0; index < sp.games; index++ {
+		jobs <- index
+	}
+	close(jobs)
+	wg.Wait()
+
+	return
+}
+
+// Plays one game and returns its quiet positions.
+func (sp *SelfPlay) play(engine *Engine, index int) *bytes.Buffer {
+	random := rand.New(rand.NewSource(sp.seed + int64(index)))
+	game := engine.NewGame()
+	position := game.start()
+
+	// Note that the game tree keeps growing so we can't use move generator
+	// of the current ply.
+	for ply := 0; ply < sp.randomPlies; ply++ {
+		moves := NewGen(position, MaxPly).generateAllMoves().validOnly().allMoves()
+		if len(moves) == 0 {
+			break
+		}
+		position = position.makeMove(moves[random.Intn(len(moves))])
+	}
+
+	fens, scores := []string{}, []int{}
+	result := position.result()
+	for result == `*` {
+		score := 0
+		game.progress = func(depth, best int, move Move, duration int64) {
+			score = best * 100 / onePawn
+		}
+		move := game.Think()
+		if move == Move(0) {
+			break
+		}
+
+		if pv := game.workers[0].rootpv; len(pv) > 0 && pv[0] == move && sp.quiet(position, pv, score) {
+			white := score
+			if position.color == Black {
+				white = -score
+			}
+			fens = append(fens, fmt.Sprintf(`%s %d`, position.fen(), white))
+		}
+		position = position.makeMove(move)
+		scores = append(scores, score)
+
+		if result = position.result(); result == `*` {
+			result, _ = sp.referee.adjudicate(position, scores)
+		}
+	}
+
+	lines := &bytes.Buffer{}
+	if result != `*` {
+		for _, fen := range fens {
+			fmt.Fprintf(lines, "%s %s\n", fen, result)
+		}
+	}
+
+	return lines
+}
+
+// Returns true if the position is quiet: the side to move is not in check, the
+// score is not decisive, and principal variation has no captures, promotions,
+// or checks.
+func (sp *SelfPlay) quiet(p *Position, pv []Move, score int) bool {
+	if abs(score) >= WhiteWinning || p.isInCheck(p.color) {
+		return false
+	}
+
+	quiet, position := true, p
+	for _, move := range pv {
+		if move.capture() != 0 || move.isPromo() {
+			quiet = false
+			break
+		}
+		if position = position.makeMove(move); position.isInCheck(position.color) {
+			quiet = false
+			break
+		}
+	}
+	for position != p {
+		position = position.undoLastMove()
+	}
+
+	return quiet
+}
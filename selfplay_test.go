@@ -0,0 +1,101 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `bytes`; `strconv`; `strings`; `testing`)
+
+func selfPlay(args ...interface{}) (string, int) {
+	buffer := &bytes.Buffer{}
+	positions, _ := NewSelfPlay(append([]interface{}{ `depth`, 1, `games`, 2, `maxmoves`, 20 }, args...)...).Run(buffer, nil)
+	return buffer.String(), positions
+}
+
+func TestSelfPlay000(t *testing.T) {
+	sp := NewSelfPlay()
+	expect.Eq(t, sp.depth, 6)
+	expect.Eq(t, sp.games, 100)
+	expect.Eq(t, sp.threads, 1)
+	expect.Eq(t, sp.randomPlies, 8)
+
+	sp = NewSelfPlay(`nodes`, 5000, `games`, 10, `threads`, 100, `seed`, int64(42), `randomplies`, 4, `resignscore`, 500)
+	expect.Eq(t, sp.depth, 0)
+	expect.Eq(t, sp.nodes, 5000)
+	expect.Eq(t, sp.threads, MaxThreads)
+	expect.Eq(t, sp.seed, int64(42))
+	expect.Eq(t, sp.randomPlies, 4)
+	expect.Eq(t, sp.referee.resignScore, 500)
+}
+
+// Each line has FEN, White's score, and game result.
+func TestSelfPlay010(t *testing.T) {
+	data, positions := selfPlay(`seed`, int64(1))
+	lines := strings.Split(strings.TrimSpace(data), "\n")
+	expect.True(t, positions > 0)
+	expect.Eq(t, len(lines), positions)
+
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		expect.Eq(t, len(fields), 8)
+		expect.Eq(t, checkFEN(strings.Join(fields[:6], ` `)), nil)
+		_, err := strconv.Atoi(fields[6])
+		expect.Eq(t, err, nil)
+		expect.Contain(t, `1-0 0-1 1/2-1/2`, fields[7])
+	}
+}
+
+// Same seed plays the same games, different seed plays different openings.
+func TestSelfPlay020(t *testing.T) {
+	first, _ := selfPlay(`seed`, int64(7))
+	second, _ := selfPlay(`seed`, int64(7))
+	third, _ := selfPlay(`seed`, int64(8))
+	expect.Eq(t, first, second)
+	expect.Ne(t, first, third)
+}
+
+// Multiple threads play the same games.
+func TestSelfPlay030(t *testing.T) {
+	single, _ := selfPlay(`seed`, int64(3), `games`, 3)
+	multiple, _ := selfPlay(`seed`, int64(3), `games`, 3, `threads`, 2)
+
+	lines := func(data string) map[string]bool {
+		set := map[string]bool{}
+		for _, line := range strings.Split(data, "\n") {
+			set[line] = true
+		}
+		return set
+	}
+	expect.Eq(t, lines(single), lines(multiple))
+}
+
+// Generated positions are accepted by the tuner.
+func TestSelfPlay040(t *testing.T) {
+	data, positions := selfPlay(`seed`, int64(5))
+	tuner, err := NewEngine().NewTuner(strings.NewReader(data))
+	expect.Eq(t, err, nil)
+	expect.Eq(t, tuner.Size(), positions)
+}
+
+// Positions with captures or checks in principal variation are not quiet.
+func TestSelfPlay050(t *testing.T) {
+	sp := NewSelfPlay()
+	p := NewGame(`Ke1,Qd1,e4`, `Ke8,d5`).start()
+	node := p.worker.node
+	expect.True(t, sp.quiet(p, []Move{ NewMove(p, E4, E5) }, 0))
+	expect.False(t, sp.quiet(p, []Move{ NewMove(p, E4, D5) }, 0))
+	expect.False(t, sp.quiet(p, []Move{ NewMove(p, D1, H5) }, 0))
+	expect.False(t, sp.quiet(p, []Move{ NewMove(p, E4, E5) }, Checkmate - 10))
+	expect.Eq(t, p.worker.node, node)
+}
+
+// Self-play with neural network evaluation.
+func TestSelfPlay060(t *testing.T) {
+	sp := NewSelfPlay(`evalfile`, `testdata/material.nnue`)
+	expect.Eq(t, sp.evalFile, `testdata/material.nnue`)
+
+	data, positions := selfPlay(`seed`, int64(1), `evalfile`, `testdata/material.nnue`)
+	classical, _ := selfPlay(`seed`, int64(1))
+	expect.True(t, positions > 0)
+	expect.Ne(t, data, classical)
+}
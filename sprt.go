@@ -0,0 +1,133 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`fmt`
+	`math`
+)
+
+// SPRT status.
+const (
+	SprtRunning  = iota // Keep playing: neither hypothesis could be accepted yet.
+	SprtAcceptH0        // The patch is not stronger by elo0 or more.
+	SprtRejectH0        // The patch is stronger by elo1 or more.
+)
+
+// Sequential probability ratio test: H0 is that the Elo difference between
+// the players is elo0, and H1 that it's elo1. The games are played in pairs
+// with reversed colors, and the pair results are tallied using pentanomial
+// model, i.e. 0, 1/2, 1, 3/2, or 2 points scored by the first player.
+type Sprt struct {
+	elo0        float64 	// Elo difference for H0.
+	elo1        float64 	// Elo difference for H1.
+	alpha       float64 	// Probability of false positive (accepting H1 when H0 is true).
+	beta        float64 	// Probability of false negative (accepting H0 when H1 is true).
+	pairs       [5]int 	// Number of game pairs by the points scored in half-points.
+}
+
+func NewSprt(elo0, elo1, alpha, beta float64) *Sprt {
+	return &Sprt{elo0: elo0, elo1: elo1, alpha: alpha, beta: beta}
+}
+
+// Records the result of the game pair: the number of half-points (0 to 4)
+// scored by the first player.
+func (s *Sprt) add(halfPoints int) *Sprt {
+	s.pairs[max(0, min(halfPoints, 4))]++
+	return s
+}
+
+// Returns the lower and upper LLR bounds: H0 is accepted when LLR falls below
+// the lower bound, and rejected when LLR reaches the upper one.
+func (s *Sprt) Bounds() (lower, upper float64) {
+	return math.Log(s.beta / (1.0 - s.alpha)), math.Log((1.0 - s.beta) / s.alpha)
+}
+
+// Returns log-likelihood ratio of H1 vs. H0 using normal approximation of the
+// pair score distribution. When estimating the variance empty pentanomial
+// buckets are counted as quarter of a pair so that it doesn't collapse to zero
+// early in the test when all the pairs end up the same. The mean and the
+// number of pairs are not affected.
+func (s *Sprt) LLR() float64 {
+	pairs, mean := 0.0, 0.0
+	for i, n := range s.pairs {
+		pairs += float64(n)
+		mean += float64(n) * float64(i) / 4.0
+	}
+	if pairs == 0.0 {
+		return 0.0
+	}
+	mean /= pairs
+
+	counts, total, average, variance := [5]float64{}, 0.0, 0.0, 0.0
+	for i, n := range s.pairs {
+		counts[i] = math.Max(float64(n), 0.25)
+		total += counts[i]
+		average += counts[i] * float64(i) / 4.0
+	}
+	average /= total
+	for i, n := range counts {
+		variance += n * math.Pow(float64(i) / 4.0 - average, 2)
+	}
+	variance /= total
+
+	score0, score1 := eloScore(s.elo0), eloScore(s.elo1)
+	return pairs * (score1 - score0) * (2.0 * mean - score0 - score1) / (2.0 * variance)
+}
+
+// Returns SPRT status based on current LLR.
+func (s *Sprt) Status() int {
+	lower, upper := s.Bounds()
+	switch llr := s.LLR(); {
+	case llr <= lower:
+		return SprtAcceptH0
+	case llr >= upper:
+		return SprtRejectH0
+	}
+	return SprtRunning
+}
+
+// Converts Elo difference to expected score.
+func eloScore(elo float64) float64 {
+	return 1.0 / (1.0 + math.Pow(10.0, -elo / 400.0))
+}
+
+func (s *Sprt) String() string {
+	lower, upper := s.Bounds()
+	status := ``
+	switch s.Status() {
+	case SprtAcceptH0:
+		status = ` H0 accepted`
+	case SprtRejectH0:
+		status = ` H0 rejected`
+	}
+	return fmt.Sprintf(`LLR %.2f (%.2f, %.2f) [%.1f, %.1f] %v%s`, s.LLR(), lower, upper, s.elo0, s.elo1, s.pairs, status)
+}
+
+// Plays game pairs with reversed colors until SPRT accepts or rejects H0, or
+// the maximum number of games (if set) has been played. The callback, if any,
+// gets invoked after each game; the test gets updated after each pair.
+func (m *Match) Sprt(test *Sprt, callback func(round int, pgn *Pgn, score MatchScore)) (score MatchScore, err error) {
+	for round := 0; m.games == 0 || round + 1 < m.games; round += 2 {
+		before := score
+		for i := round; i <= round + 1; i++ {
+			pgn, err := m.round(i, &score)
+			if err != nil {
+				return score, err
+			}
+			if i == round + 1 {
+				test.add((score.Wins - before.Wins) * 2 + score.Draws - before.Draws)
+			}
+			if callback != nil {
+				callback(i + 1, pgn, score)
+			}
+		}
+		if test.Status() != SprtRunning {
+			break
+		}
+	}
+
+	return
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `fmt`; `math`; `testing`)
+
+// Weak player that always makes the first valid move.
+type firstMovePlayer struct{}
+
+func (fp firstMovePlayer) Name() string  { return `First Move` }
+func (fp firstMovePlayer) NewGame() error { return nil }
+func (fp firstMovePlayer) Close() error   { return nil }
+
+func (fp firstMovePlayer) Go(fen string, moves []string, clock *MatchClock) (string, int, error) {
+	game := NewGame()
+	if fen != `` {
+		game = NewGame(fen)
+	}
+	p := game.start()
+	for _, notation := range moves {
+		p = p.makeMove(NewMoveFromNotation(p, notation))
+	}
+	return NewGen(p, MaxPly).generateAllMoves().validOnly().allMoves()[0].notation(), 0, nil
+}
+
+func TestSprt000(t *testing.T) {
+	lower, upper := NewSprt(0.0, 5.0, 0.05, 0.05).Bounds()
+	expect.Eq(t, fmt.Sprintf(`%.3f %.3f`, lower, upper), `-2.944 2.944`)
+
+	lower, upper = NewSprt(0.0, 5.0, 0.05, 0.10).Bounds()
+	expect.Eq(t, fmt.Sprintf(`%.3f %.3f`, lower, upper), `-2.251 2.890`)
+}
+
+func TestSprt010(t *testing.T) {
+	test := NewSprt(0.0, 10.0, 0.05, 0.05)
+	expect.Eq(t, test.LLR(), 0.0)
+	expect.Eq(t, test.Status(), SprtRunning)
+}
+
+// Pentanomial LLR.
+func TestSprt020(t *testing.T) {
+	test := &Sprt{elo0: 0.0, elo1: 10.0, alpha: 0.05, beta: 0.05, pairs: [5]int{ 1, 3, 10, 6, 2 }}
+	expect.Eq(t, fmt.Sprintf(`%.4f`, test.LLR()), `0.2783`)
+	expect.Eq(t, test.Status(), SprtRunning)
+	expect.Eq(t, test.String(), `LLR 0.28 (-2.94, 2.94) [0.0, 10.0] [1 3 10 6 2]`)
+}
+
+func TestSprt030(t *testing.T) {
+	test := NewSprt(0.0, 10.0, 0.05, 0.05)
+	for i := 0; i < 20; i++ {
+		test.add(4).add(3)
+	}
+	expect.True(t, test.LLR() > 2.944)
+	expect.Eq(t, test.Status(), SprtRejectH0)
+	expect.Contain(t, test.String(), `H0 rejected`)
+}
+
+func TestSprt040(t *testing.T) {
+	test := NewSprt(0.0, 10.0, 0.05, 0.05)
+	for i := 0; i < 20; i++ {
+		test.add(0).add(1)
+	}
+	expect.True(t, test.LLR() < -2.944)
+	expect.Eq(t, test.Status(), SprtAcceptH0)
+}
+
+// Draws only: the variance must not collapse to zero.
+func TestSprt050(t *testing.T) {
+	test := NewSprt(0.0, 10.0, 0.05, 0.05)
+	for i := 0; i < 100; i++ {
+		test.add(2)
+	}
+	expect.False(t, math.IsNaN(test.LLR()) || math.IsInf(test.LLR(), 0))
+	expect.True(t, test.LLR() < 0.0)
+}
+
+// Single pair is not enough to accept or reject H0.
+func TestSprt060(t *testing.T) {
+	test := NewSprt(0.0, 200.0, 0.05, 0.05).add(2)
+	expect.Eq(t, test.Status(), SprtRunning)
+	test = NewSprt(0.0, 200.0, 0.05, 0.05).add(4)
+	expect.Eq(t, test.Status(), SprtRunning)
+}
+
+// Empty buckets only affect the variance: the mean and the number of pairs
+// come from the pairs actually played.
+func TestSprt070(t *testing.T) {
+	test := &Sprt{elo0: 0.0, elo1: 10.0, alpha: 0.05, beta: 0.05, pairs: [5]int{ 0, 0, 10, 10, 0 }}
+	expect.Eq(t, fmt.Sprintf(`%.4f`, test.LLR()), `1.4675`)
+}
+
+// SPRT match stops as soon as H0 gets rejected.
+func TestSprt100(t *testing.T) {
+	tc, _ := NewTimeControl(`depth=1`)
+	match := NewMatch(NewEnginePlayer(`Donna`, NewEngine()), firstMovePlayer{}, `games`, 0, `tc`, tc, `resigncount`, 1, `resignscore`, 500)
+	test := NewSprt(0.0, 100.0, 0.05, 0.05)
+
+	rounds := 0
+	score, err := match.Sprt(test, func(round int, pgn *Pgn, score MatchScore) {
+		rounds = round
+	})
+	expect.Eq(t, err, nil)
+	expect.Eq(t, test.Status(), SprtRejectH0)
+	expect.Eq(t, rounds, score.Games())
+	expect.Eq(t, rounds % 2, 0)
+	expect.Eq(t, score.Losses, 0)
+	expect.Eq(t, test.pairs[4], rounds / 2)
+}
+
+// SPRT match stops after maximum number of games.
+func TestSprt110(t *testing.T) {
+	tc, _ := NewTimeControl(`depth=1`)
+	match := NewMatch(NewEnginePlayer(`Donna`, NewEngine()), firstMovePlayer{}, `games`, 4, `tc`, tc, `resigncount`, 1, `resignscore`, 500)
+	test := NewSprt(0.0, 1.0, 0.05, 0.05)
+
+	score, _ := match.Sprt(test, nil)
+	expect.Eq(t, score.Games(), 4)
+	expect.Eq(t, test.Status(), SprtRunning)
+}
@@ -0,0 +1,623 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bytes`
+	`io`
+	`os`
+	`strings`
+	`sync`
+	`time`
+)
+
+// Syzygy endgame tablebases. WDL tables store win/draw/loss outcome of the
+// position, and DTZ tables store the distance (in moves or plies) to the next
+// zeroing move, i.e. capture or pawn move. The table files are compressed with
+// canonical Huffman codes on top of "recursive pairing" scheme; the format is
+// described in Ronald de Man's original probing code. Only table headers are
+// kept in memory; compressed data blocks are read from the files on demand.
+
+const (
+	tbLoss        = -2 // Loss.
+	tbBlessedLoss = -1 // Loss but draw under fifty moves rule.
+	tbDraw        =  0 // Draw.
+	tbCursedWin   =  1 // Win but draw under fifty moves rule.
+	tbWin         =  2 // Win.
+)
+
+// Table flags.
+const (
+	tbStm         = 1   // DTZ table stores positions with black to move.
+	tbMapped      = 2   // DTZ values are mapped.
+	tbWinPlies    = 4   // DTZ wins are stored in plies rather than moves.
+	tbLossPlies   = 8   // DTZ losses are stored in plies rather than moves.
+	tbWide        = 16  // DTZ map is made of 16-bit values.
+	tbSingleValue = 128 // All table entries store the same value.
+)
+
+var (
+	tbMagicWdl = []byte{ 0x71, 0xE8, 0x23, 0x5D }
+	tbMagicDtz = []byte{ 0xD7, 0x66, 0x0C, 0xA5 }
+
+	// Maps squares below A1-H8 diagonal to 0..27.
+	tbMapB1H1H7 [64]int
+
+	// Maps squares of A1-D1-D4 triangle to 0..9.
+	tbMapA1D1D4 [64]int
+
+	// Maps 462 legal positions of two kings where the first one resides in
+	// A1-D1-D4 triangle.
+	tbMapKK [10][64]int
+
+	// Binomial coefficients: tbBinomial[k][n] ways to choose k out of n.
+	tbBinomial [6][64]uint64
+
+	// Number of available squares for the pawns when the leading pawn is on
+	// the given square. Leading pawn is the one with highest value.
+	tbMapPawns [64]int
+
+	// Leading pawns group index and total size per file.
+	tbLeadPawnIdx [6][64]uint64
+	tbLeadPawnsSize [6][4]uint64
+
+	// Tables are shared by all the engines so that each table file is only
+	// opened and set up once. The table gets replaced if its file changes.
+	tbShared = struct {
+		sync.Mutex
+		tables map[string]tbSharedTable
+	}{ tables: map[string]tbSharedTable{} }
+)
+
+// Piece group decoding data for one side and one file of the table.
+type tbPairs struct {
+	flags       uint8 	// Table flags.
+	pieces      [7]int 	// Syzygy piece codes in the order they are encoded.
+	groupLen    [8]int 	// Number of pieces in each group, zero terminated.
+	groupIdx    [8]uint64 	// Index multiplier for each group.
+	blockSize   uint64 	// Size of compressed data block in bytes.
+	span        uint64 	// Number of values between sparse index entries.
+	sparseSize  int 	// Number of sparse index entries.
+	blocks      int 	// Number of compressed data blocks.
+	lengthSize  int 	// Number of block length entries.
+	minSymLen   int 	// Shortest symbol length (or the value itself).
+	lowestSym   int 	// Offset of the lowest symbols of each length.
+	base64      []uint64 	// Canonical Huffman symbol bases.
+	symlen      []int 	// Number of values each symbol expands to, less one.
+	btree       int 	// Offset of recursive pairing tree.
+	sparseIndex int 	// Offset of sparse index.
+	blockLength int 	// Offset of block lengths.
+	data        int 	// Offset of compressed data.
+	mapIdx      [4]int 	// DTZ value map indices.
+}
+
+type tbTable struct {
+	name            string 		// Material signature, ex. "KRvK".
+	file            string 		// Full table file name.
+	dtz             bool 		// DTZ rather than WDL table.
+	once            sync.Once 	// Tables get loaded on the first probe.
+	ready           bool 		// Table has been loaded successfully.
+	reader          *os.File 	// Open table file to read compressed data blocks from.
+	size            int 		// Table file size.
+	header          []byte 		// Table file contents preceding compressed data.
+	pieceCount      int 		// Total number of pieces.
+	hasPawns        bool 		// Are there any pawns?
+	hasUniquePieces bool 		// Is there a single piece of some kind other than king?
+	pawnCount       [2]int 		// Pawns of leading and other color.
+	symmetric       bool 		// Are both sides the same, ex. "KRvKR"?
+	pairs           [2][4]tbPairs 	// Decoding data per side and per file.
+	dtzMap          int 		// Offset of DTZ value map.
+}
+
+type tbSharedTable struct {
+	table           *tbTable
+	size            int64
+	modified        time.Time
+}
+
+func initTablebase() {
+	code := 0
+	for sq := A1; sq <= H8; sq++ {
+		if row(sq) < col(sq) {
+			tbMapB1H1H7[sq] = code
+			code++
+		}
+	}
+
+	code, diagonal := 0, []int{}
+	for sq := A1; sq <= D4; sq++ {
+		if row(sq) < col(sq) && col(sq) <= 3 {
+			tbMapA1D1D4[sq] = code
+			code++
+		} else if row(sq) == col(sq) && col(sq) <= 3 {
+			diagonal = append(diagonal, sq)
+		}
+	}
+	for _, sq := range diagonal { // Diagonal squares go last.
+		tbMapA1D1D4[sq] = code
+		code++
+	}
+
+	code, bothOnDiagonal := 0, [][2]int{}
+	for idx := 0; idx < 10; idx++ {
+		for s1 := A1; s1 <= D4; s1++ {
+			if tbMapA1D1D4[s1] != idx || (idx == 0 && s1 != B1) {
+				continue
+			}
+			for s2 := A1; s2 <= H8; s2++ {
+				if s1 == s2 || kingMoves[s1].on(s2) { // Illegal position.
+					continue
+				} else if row(s1) == col(s1) && row(s2) > col(s2) { // First on diagonal, second above.
+					continue
+				} else if row(s1) == col(s1) && row(s2) == col(s2) {
+					bothOnDiagonal = append(bothOnDiagonal, [2]int{ idx, s2 })
+				} else {
+					tbMapKK[idx][s2] = code
+					code++
+				}
+			}
+		}
+	}
+	for _, pair := range bothOnDiagonal { // Both kings on diagonal go last.
+		tbMapKK[pair[0]][pair[1]] = code
+		code++
+	}
+
+	tbBinomial[0][0] = 1
+	for n := 1; n < 64; n++ {
+		for k := 0; k < 6 && k <= n; k++ {
+			if k > 0 {
+				tbBinomial[k][n] += tbBinomial[k - 1][n - 1]
+			}
+			if k < n {
+				tbBinomial[k][n] += tbBinomial[k][n - 1]
+			}
+		}
+	}
+
+	available := 47 // Available squares when the leading pawn is on A2.
+	for count := 1; count <= 5; count++ {
+		for file := 0; file <= 3; file++ {
+			idx := uint64(0)
+			for rank := 1; rank <= 6; rank++ {
+				sq := square(rank, file)
+				if count == 1 {
+					tbMapPawns[sq] = available
+					tbMapPawns[sq ^ 7] = available - 1
+					available -= 2
+				}
+				tbLeadPawnIdx[count][sq] = idx
+				idx += tbBinomial[count - 1][tbMapPawns[sq]]
+			}
+			tbLeadPawnsSize[count][file] = idx
+		}
+	}
+}
+
+// Creates the table for given material signature, ex. "KRPvKR". The table
+// file itself gets loaded on the first probe.
+func newTbTable(name, file string, dtz bool) *tbTable {
+	t := &tbTable{ name: name, file: file, dtz: dtz }
+	sides := strings.Split(name, `v`)
+	t.symmetric = (sides[0] == sides[1])
+	t.pieceCount = len(sides[0]) + len(sides[1])
+
+	var pawns [2]int
+	for color, side := range sides {
+		pawns[color] = strings.Count(side, `P`)
+		for _, kind := range `QRBNP` {
+			if strings.Count(side, string(kind)) == 1 {
+				t.hasUniquePieces = true
+			}
+		}
+	}
+	t.hasPawns = (pawns[White] + pawns[Black] > 0)
+
+	// Leading color is the one with fewer pawns for better compression.
+	if pawns[Black] == 0 || (pawns[White] > 0 && pawns[Black] >= pawns[White]) {
+		t.pawnCount = pawns
+	} else {
+		t.pawnCount = [2]int{ pawns[Black], pawns[White] }
+	}
+
+	return t
+}
+
+// Returns the table for given file, reusing the one that has already been
+// created unless the file has changed since then.
+func sharedTbTable(name, file string, dtz bool, info os.FileInfo) *tbTable {
+	tbShared.Lock()
+	defer tbShared.Unlock()
+
+	shared, ok := tbShared.tables[file]
+	if !ok || shared.size != info.Size() || !shared.modified.Equal(info.ModTime()) {
+		shared = tbSharedTable{ newTbTable(name, file, dtz), info.Size(), info.ModTime() }
+		tbShared.tables[file] = shared
+	}
+	return shared.table
+}
+
+// Opens the table file and reads its header once; returns true if the table
+// is ready for probing. The file stays open for reading data blocks.
+func (t *tbTable) load() bool {
+	t.once.Do(func() {
+		defer func() {
+			if err := recover(); err != nil { // Corrupted or truncated file.
+				t.reader.Close()
+				t.reader, t.header, t.ready = nil, nil, false
+			}
+		}()
+
+		magic := tbMagicWdl
+		if t.dtz {
+			magic = tbMagicDtz
+		}
+		if reader, err := os.Open(t.file); err == nil {
+			t.reader = reader
+			if info, err := reader.Stat(); err == nil {
+				t.size = int(info.Size())
+			}
+			if t.readHeader(4); !bytes.Equal(t.header[:4], magic) {
+				panic(`invalid table`)
+			}
+			t.setup()
+			t.ready = true
+		}
+	})
+
+	return t.ready
+}
+
+// Reads table file header up to the given size. Header size is not known up
+// front, so it keeps growing while the table is being set up.
+func (t *tbTable) readHeader(size int) {
+	if size <= len(t.header) {
+		return
+	}
+	if size > t.size {
+		panic(`truncated table`)
+	}
+
+	header := make([]byte, min(max(size, max(2 * len(t.header), 4096)), t.size))
+	if _, err := t.reader.ReadAt(header, 0); err != nil {
+		panic(err)
+	}
+	t.header = header
+}
+
+// Returns decoding data for given side to move and leading file. DTZ tables
+// and symmetric WDL tables are one-sided.
+func (t *tbTable) get(stm, file int) *tbPairs {
+	if t.dtz || t.symmetric {
+		stm = 0
+	}
+	if !t.hasPawns {
+		file = 0
+	}
+	return &t.pairs[stm][file]
+}
+
+// Parses table header and sets up decoding data. Offsets are relative to the
+// beginning of the file.
+func (t *tbTable) setup() {
+	pos := 4 // Skip the magic.
+	if (t.u8(pos) & 2 != 0) != t.hasPawns {
+		panic(`pawns mismatch`)
+	}
+	pos++
+
+	sides, files := 2, 1
+	if t.dtz || t.symmetric {
+		sides = 1
+	}
+	if t.hasPawns {
+		files = 4
+	}
+	pp := t.hasPawns && t.pawnCount[1] > 0 // Pawns on both sides.
+
+	for f := 0; f < files; f++ {
+		order := [2][2]int{ { t.u8(pos) & 0xF, 0xF }, { t.u8(pos) >> 4, 0xF } }
+		if pp {
+			order[0][1], order[1][1] = t.u8(pos + 1) & 0xF, t.u8(pos + 1) >> 4
+			pos++
+		}
+		pos++
+
+		for k := 0; k < t.pieceCount; k, pos = k + 1, pos + 1 {
+			t.pairs[0][f].pieces[k] = t.u8(pos) & 0xF
+			t.pairs[1][f].pieces[k] = t.u8(pos) >> 4
+		}
+		for i := 0; i < sides; i++ {
+			t.setGroups(&t.pairs[i][f], order[i], f)
+		}
+	}
+	pos += pos & 1
+
+	for f := 0; f < files; f++ {
+		for i := 0; i < sides; i++ {
+			pos = t.setSizes(&t.pairs[i][f], pos)
+		}
+	}
+
+	if t.dtz {
+		pos = t.setDtzMap(pos, files)
+	}
+
+	for f := 0; f < files; f++ {
+		for i := 0; i < sides; i++ {
+			t.pairs[i][f].sparseIndex = pos
+			pos += t.pairs[i][f].sparseSize * 6
+		}
+	}
+	for f := 0; f < files; f++ {
+		for i := 0; i < sides; i++ {
+			t.pairs[i][f].blockLength = pos
+			pos += t.pairs[i][f].lengthSize * 2
+		}
+	}
+	t.readHeader(pos) // Everything but the data blocks.
+
+	for f := 0; f < files; f++ {
+		for i := 0; i < sides; i++ {
+			pos = (pos + 0x3F) &^ 0x3F // 64-byte alignment.
+			t.pairs[i][f].data = pos
+			pos += t.pairs[i][f].blocks * int(t.pairs[i][f].blockSize)
+		}
+	}
+
+	if pos > t.size {
+		panic(`truncated table`)
+	}
+}
+
+// Splits the pieces into groups and computes index multiplier for each group.
+// The first group holds either leading pawns or the kings (with a unique piece
+// if available), then go remaining pawns followed by the rest of the pieces.
+func (t *tbTable) setGroups(d *tbPairs, order [2]int, file int) {
+	n, firstLen := 0, 2
+	if t.hasPawns {
+		firstLen = 0
+	} else if t.hasUniquePieces {
+		firstLen = 3
+	}
+
+	d.groupLen[n] = 1
+	for i := 1; i < t.pieceCount; i++ {
+		firstLen--
+		if firstLen > 0 || d.pieces[i] == d.pieces[i - 1] {
+			d.groupLen[n]++
+		} else {
+			n++
+			d.groupLen[n] = 1
+		}
+	}
+	n++
+	d.groupLen[n] = 0
+
+	pp := t.hasPawns && t.pawnCount[1] > 0
+	next, free := 1, 64 - d.groupLen[0]
+	if pp {
+		next, free = 2, free - d.groupLen[1]
+	}
+
+	idx := uint64(1)
+	for k := 0; next < n || k == order[0] || k == order[1]; k++ {
+		if k == order[0] { // Leading pawns or pieces.
+			d.groupIdx[0] = idx
+			if t.hasPawns {
+				idx *= tbLeadPawnsSize[d.groupLen[0]][file]
+			} else if t.hasUniquePieces {
+				idx *= 31332
+			} else {
+				idx *= 462
+			}
+		} else if k == order[1] { // Remaining pawns.
+			d.groupIdx[1] = idx
+			idx *= tbBinomial[d.groupLen[1]][48 - d.groupLen[0]]
+		} else { // Remaining pieces.
+			d.groupIdx[next] = idx
+			idx *= tbBinomial[d.groupLen[next]][free]
+			free -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// Reads block sizes and Huffman code tables.
+func (t *tbTable) setSizes(d *tbPairs, pos int) int {
+	d.flags = uint8(t.u8(pos))
+	pos++
+
+	if d.flags & tbSingleValue != 0 {
+		d.minSymLen = t.u8(pos) // The value itself.
+		return pos + 1
+	}
+
+	n := 0
+	for d.groupLen[n] != 0 {
+		n++
+	}
+	size := d.groupIdx[n]
+
+	d.blockSize = uint64(1) << uint(t.u8(pos))
+	d.span = uint64(1) << uint(t.u8(pos + 1))
+	d.sparseSize = int((size + d.span - 1) / d.span)
+	d.blocks = int(t.u32(pos + 3))
+	d.lengthSize = d.blocks + t.u8(pos + 2)
+	maxSymLen, minSymLen := t.u8(pos + 7), t.u8(pos + 8)
+	d.minSymLen = minSymLen
+	pos += 9
+
+	// Lowest symbols are ordered so that longer codes have lower values.
+	d.lowestSym = pos
+	d.base64 = make([]uint64, maxSymLen - minSymLen + 1)
+	for i := len(d.base64) - 2; i >= 0; i-- {
+		d.base64[i] = (d.base64[i + 1] + uint64(t.u16(pos + 2 * i)) - uint64(t.u16(pos + 2 * i + 2))) / 2
+	}
+	for i := range d.base64 {
+		d.base64[i] <<= uint(64 - i - minSymLen)
+	}
+	pos += len(d.base64) * 2
+
+	d.symlen = make([]int, t.u16(pos))
+	pos += 2
+	d.btree = pos
+
+	visited := make([]bool, len(d.symlen))
+	for sym := range d.symlen {
+		if !visited[sym] {
+			d.symlen[sym] = t.setSymlen(d, sym, visited)
+		}
+	}
+
+	return pos + len(d.symlen) * 3 + (len(d.symlen) & 1)
+}
+
+// Computes the number of values the symbol expands to (less one).
+func (t *tbTable) setSymlen(d *tbPairs, sym int, visited []bool) int {
+	visited[sym] = true
+	right := t.right(d, sym)
+	if right == 0xFFF {
+		return 0
+	}
+	left := t.left(d, sym)
+	if !visited[left] {
+		d.symlen[left] = t.setSymlen(d, left, visited)
+	}
+	if !visited[right] {
+		d.symlen[right] = t.setSymlen(d, right, visited)
+	}
+	return d.symlen[left] + d.symlen[right] + 1
+}
+
+// Sets up the map of DTZ values.
+func (t *tbTable) setDtzMap(pos, files int) int {
+	t.dtzMap = pos
+	for f := 0; f < files; f++ {
+		d := &t.pairs[0][f]
+		if d.flags & tbMapped != 0 {
+			if d.flags & tbWide != 0 {
+				pos += pos & 1
+				for i := 0; i < 4; i++ {
+					d.mapIdx[i] = (pos - t.dtzMap) / 2 + 1
+					pos += 2 * t.u16(pos) + 2
+				}
+			} else {
+				for i := 0; i < 4; i++ {
+					d.mapIdx[i] = pos - t.dtzMap + 1
+					pos += t.u8(pos) + 1
+				}
+			}
+		}
+	}
+
+	return pos + (pos & 1)
+}
+
+// Returns the value stored at given index, or false if the data block could
+// not be read.
+func (t *tbTable) decompress(d *tbPairs, idx uint64) (int, bool) {
+	if d.flags & tbSingleValue != 0 {
+		return d.minSymLen, true
+	}
+
+	// Find the block that contains the value using sparse index entry
+	// nearest to the index.
+	entry := d.sparseIndex + 6 * int(idx / d.span)
+	block := int(t.u32(entry))
+	offset := t.u16(entry + 4) + int(idx % d.span) - int(d.span / 2)
+
+	for offset < 0 {
+		block--
+		offset += t.u16(d.blockLength + 2 * block) + 1
+	}
+	for offset > t.u16(d.blockLength + 2 * block) {
+		offset -= t.u16(d.blockLength + 2 * block) + 1
+		block++
+	}
+
+	// Read the block; the decoder might look a few bytes past its end so
+	// there is some zero padding.
+	data := make([]byte, d.blockSize + 16)
+	if _, err := t.reader.ReadAt(data[:d.blockSize], int64(d.data) + int64(block) * int64(d.blockSize)); err != nil && err != io.EOF {
+		return 0, false
+	}
+
+	// Decode Huffman symbols until we get to the one that covers the offset.
+	ptr := 0
+	buffer, bits := uint64(b32(data[ptr:])) << 32 | uint64(b32(data[ptr + 4:])), 64
+	ptr += 8
+
+	sym := 0
+	for {
+		length := 0
+		for buffer < d.base64[length] {
+			length++
+		}
+		sym = int((buffer - d.base64[length]) >> uint(64 - length - d.minSymLen))
+		sym += t.u16(d.lowestSym + 2 * length)
+
+		if offset < d.symlen[sym] + 1 {
+			break
+		}
+		offset -= d.symlen[sym] + 1
+		length += d.minSymLen
+		buffer <<= uint(length)
+		bits -= length
+
+		if bits <= 32 { // Refill the buffer.
+			bits += 32
+			buffer |= uint64(b32(data[ptr:])) << uint(64 - bits)
+			ptr += 4
+		}
+	}
+
+	// Expand the symbol to get to the value.
+	for d.symlen[sym] != 0 {
+		left := t.left(d, sym)
+		if offset < d.symlen[left] + 1 {
+			sym = left
+		} else {
+			offset -= d.symlen[left] + 1
+			sym = t.right(d, sym)
+		}
+	}
+
+	return t.left(d, sym), true
+}
+
+// Left and right symbols of the recursive pairing tree node, 12 bits each.
+func (t *tbTable) left(d *tbPairs, sym int) int {
+	at := d.btree + 3 * sym
+	return (t.u8(at + 1) & 0xF) << 8 | t.u8(at)
+}
+
+func (t *tbTable) right(d *tbPairs, sym int) int {
+	at := d.btree + 3 * sym
+	return t.u8(at + 2) << 4 | t.u8(at + 1) >> 4
+}
+
+// Header byte, and little endian 16 and 32 bit values. Once the table is set
+// up the header is never read past its end.
+func (t *tbTable) u8(at int) int {
+	if at >= len(t.header) {
+		t.readHeader(at + 1)
+	}
+	return int(t.header[at])
+}
+
+func (t *tbTable) u16(at int) int {
+	return t.u8(at) | t.u8(at + 1) << 8
+}
+
+func (t *tbTable) u32(at int) uint32 {
+	return uint32(t.u8(at)) | uint32(t.u8(at + 1)) << 8 | uint32(t.u8(at + 2)) << 16 | uint32(t.u8(at + 3)) << 24
+}
+
+// Big endian 32 bit value of the data block.
+func b32(data []byte) uint32 {
+	return uint32(data[0]) << 24 | uint32(data[1]) << 16 | uint32(data[2]) << 8 | uint32(data[3])
+}
@@ -0,0 +1,525 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`io/ioutil`
+	`path/filepath`
+	`regexp`
+	`strings`
+)
+
+// Probe results.
+const (
+	tbFail      = iota // Table is missing or the position can't be probed.
+	tbOk               // Probe succeeded.
+	tbChangeStm        // DTZ table stores the other side to move.
+	tbZeroing          // Best move is a zeroing one (capture or pawn move).
+)
+
+// Tablebase scores are below checkmate ones so that the search still prefers
+// actual checkmate when it can see one.
+const TablebaseWin = Checkmate - 2 * MaxPly
+
+type Tablebase struct {
+	pieces      int                  // Largest number of pieces covered by the tables.
+	wdl         map[string]*tbTable  // Win/draw/loss tables by material, ex. "KRvK".
+	dtz         map[string]*tbTable  // Distance to zeroing move tables.
+}
+
+// Scans given directories separated by path list separator (: or ;) for
+// Syzygy table files. Tables that have already been opened, ex. by another
+// engine or before the path got changed, are reused. Returns nil if no tables
+// were found.
+func NewTablebase(path string) *Tablebase {
+	tb := &Tablebase{ wdl: map[string]*tbTable{}, dtz: map[string]*tbTable{} }
+	re := regexp.MustCompile(`^(K[QRBNP]*vK[QRBNP]*)\.rtb([wz])$`)
+
+	for _, dir := range filepath.SplitList(path) {
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			match := re.FindStringSubmatch(file.Name())
+			if match == nil || len(match[1]) > 8 { // 7 pieces max.
+				continue
+			}
+			name, fileName := match[1], filepath.Join(dir, file.Name())
+			if match[2] == `w` {
+				tb.wdl[name] = sharedTbTable(name, fileName, false, file)
+				tb.pieces = max(tb.pieces, len(name) - 1)
+			} else {
+				tb.dtz[name] = sharedTbTable(name, fileName, true, file)
+			}
+		}
+	}
+
+	if len(tb.wdl) == 0 {
+		return nil
+	}
+	return tb
+}
+
+// Returns material signature of the given side, ex. "KRP".
+func (p *Position) material(color uint8) string {
+	signature := `K`
+	for i, kind := range []Piece{ Queen, Rook, Bishop, Knight, Pawn } {
+		signature += strings.Repeat(`QRBNP`[i : i + 1], p.outposts[kind | Piece(color)].count())
+	}
+	return signature
+}
+
+// Returns true if the position is covered by the tables and can be probed.
+func (tb *Tablebase) covers(p *Position) bool {
+	return p.castles == 0 && p.board.count() <= tb.pieces
+}
+
+// Probes win/draw/loss table. The score is from the side to move point of view.
+func (tb *Tablebase) probeWdl(p *Position) (int, bool) {
+	if !tb.covers(p) {
+		return 0, false
+	}
+	score, state := tb.search(p, false)
+	return score, state != tbFail
+}
+
+// Probes distance to zeroing move table. Returns the number of plies to the
+// next capture or pawn move when winning (positive), or losing (negative), and
+// zero for a draw. Cursed wins and blessed losses are over 100 plies.
+func (tb *Tablebase) probeDtz(p *Position) (int, bool) {
+	if !tb.covers(p) {
+		return 0, false
+	}
+
+	wdl, state := tb.search(p, true)
+	if state == tbFail || wdl == tbDraw { // DTZ tables don't store draws.
+		return 0, state != tbFail
+	}
+
+	// DTZ stores "don't care" value if the best move is zeroing one.
+	if state == tbZeroing {
+		return dtzBeforeZeroing(wdl), true
+	}
+
+	dtz, state := tb.probeTable(p, true, wdl)
+	if state == tbFail {
+		return 0, false
+	}
+	if state != tbChangeStm {
+		if wdl == tbBlessedLoss || wdl == tbCursedWin {
+			dtz += 100
+		}
+		if wdl < 0 {
+			dtz = -dtz
+		}
+		return dtz, true
+	}
+
+	// DTZ table stores the other side to move so do 1-ply search to find the
+	// move that minimizes DTZ.
+	best := 0xFFFF
+	gen := NewMoveGen(p).generateAllMoves()
+	for move := gen.NextMove(); move != 0; move = gen.NextMove() {
+		if !gen.isValid(move) {
+			continue
+		}
+		zeroing := move.capture() != 0 || move.piece().isPawn()
+		position := p.makeMove(move)
+
+		ok := true
+		if zeroing { // Get the sign of the score after the move.
+			score, state := tb.search(position, false)
+			dtz, ok = -dtzBeforeZeroing(score), state != tbFail
+		} else {
+			dtz, ok = tb.probeDtz(position)
+			dtz = -dtz
+		}
+
+		if dtz == 1 && position.isInCheck(position.color) && !NewGen(position, MaxPly).generateAllMoves().anyValid() {
+			best = 1 // The move mates.
+		}
+		if !zeroing {
+			dtz += sign(dtz)
+		}
+		if dtz < best && sign(dtz) == sign(wdl) {
+			best = dtz
+		}
+		position.undoLastMove()
+
+		if !ok {
+			return 0, false
+		}
+	}
+
+	if best == 0xFFFF { // No legal moves: checkmate.
+		return -1, true
+	}
+	return best, true
+}
+
+// Looks up the tables after searching all the captures (and pawn moves when
+// checking zeroing moves) since the tables might not store positions where
+// capture is the best move.
+func (tb *Tablebase) search(p *Position, checkZeroing bool) (int, int) {
+	best, total, count := tbLoss, 0, 0
+
+	gen := NewMoveGen(p).generateAllMoves()
+	for move := gen.NextMove(); move != 0; move = gen.NextMove() {
+		if !gen.isValid(move) {
+			continue
+		}
+		total++
+		if move.capture() == 0 && (!checkZeroing || !move.piece().isPawn()) {
+			continue
+		}
+		count++
+
+		position := p.makeMove(move)
+		score, state := tb.search(position, false)
+		position.undoLastMove()
+
+		if state == tbFail {
+			return tbDraw, tbFail
+		}
+		if -score > best {
+			best = -score
+			if best >= tbWin {
+				return best, tbZeroing
+			}
+		}
+	}
+
+	// No need to probe the table if we have already seen all the moves. For
+	// example, tables don't store positions with en-passant rights.
+	score, noMoreMoves := best, count > 0 && count == total
+	if !noMoreMoves {
+		var state int
+		if score, state = tb.probeTable(p, false, tbDraw); state == tbFail {
+			return tbDraw, tbFail
+		}
+	}
+
+	if best >= score {
+		if best > tbDraw || noMoreMoves {
+			return best, tbZeroing
+		}
+		return best, tbOk
+	}
+	return score, tbOk
+}
+
+// Encodes the position into table index and returns decoded table value.
+func (tb *Tablebase) probeTable(p *Position, dtz bool, wdl int) (int, int) {
+	if p.board.count() == 2 { // Bare kings.
+		return tbDraw, tbOk
+	}
+
+	tables := tb.wdl
+	if dtz {
+		tables = tb.dtz
+	}
+
+	// Tables are stored with the stronger side being white so we might need
+	// to flip colors and squares.
+	white, black := p.material(White), p.material(Black)
+	t, blackStronger := tables[white + `v` + black], false
+	if t == nil {
+		t, blackStronger = tables[black + `v` + white], true
+	}
+	if t == nil || !t.load() {
+		return 0, tbFail
+	}
+
+	// Symmetric tables store white to move only.
+	flip := blackStronger || (t.symmetric && p.color == Black)
+	flipColor, flipSquares, stm := 0, 0, int(p.color)
+	if flip {
+		flipColor, flipSquares, stm = 8, 56, stm ^ 1
+	}
+
+	var squares, pieces [7]int
+	size, leadPawnsCount, file := 0, 0, 0
+	leadPawns := Bitmask(0)
+
+	// Leading pawn is the one with highest tbMapPawns[] value, i.e. the one
+	// closest to the edge and with lowest rank.
+	if t.hasPawns {
+		color := uint8((t.get(0, 0).pieces[0] ^ flipColor) >> 3)
+		leadPawns = p.outposts[pawn(color)]
+		for bm := leadPawns; bm.any(); size++ {
+			squares[size] = bm.pop() ^ flipSquares
+		}
+		leadPawnsCount = size
+
+		lead := 0
+		for i := 1; i < leadPawnsCount; i++ {
+			if tbMapPawns[squares[i]] > tbMapPawns[squares[lead]] {
+				lead = i
+			}
+		}
+		squares[0], squares[lead] = squares[lead], squares[0]
+		file = min(col(squares[0]), 7 - col(squares[0]))
+	}
+
+	// DTZ tables are one-sided.
+	if dtz && int(t.get(stm, file).flags & tbStm) != stm && (!t.symmetric || t.hasPawns) {
+		return 0, tbChangeStm
+	}
+
+	for bm := p.board ^ leadPawns; bm.any(); size++ {
+		sq := bm.pop()
+		squares[size] = sq ^ flipSquares
+		pieces[size] = tbPiece(p.pieces[sq]) ^ flipColor
+	}
+
+	// Reorder the pieces to match the sequence stored in the table.
+	d := t.get(stm, file)
+	for i := leadPawnsCount; i < size - 1; i++ {
+		for j := i + 1; j < size; j++ {
+			if d.pieces[i] == pieces[j] {
+				pieces[i], pieces[j] = pieces[j], pieces[i]
+				squares[i], squares[j] = squares[j], squares[i]
+				break
+			}
+		}
+	}
+
+	// Map the leading piece onto A1-D1-D4 triangle.
+	if col(squares[0]) > 3 {
+		for i := 0; i < size; i++ {
+			squares[i] ^= 7
+		}
+	}
+
+	idx := uint64(0)
+	if t.hasPawns {
+		idx = tbLeadPawnIdx[leadPawnsCount][squares[0]]
+		for i := 2; i < leadPawnsCount; i++ { // Sort the rest of leading pawns.
+			for j := i; j > 1 && tbMapPawns[squares[j]] < tbMapPawns[squares[j - 1]]; j-- {
+				squares[j], squares[j - 1] = squares[j - 1], squares[j]
+			}
+		}
+		for i := 1; i < leadPawnsCount; i++ {
+			idx += tbBinomial[i][tbMapPawns[squares[i]]]
+		}
+	} else {
+		if row(squares[0]) > 3 {
+			for i := 0; i < size; i++ {
+				squares[i] ^= 56
+			}
+		}
+
+		// Make sure the first piece of the leading group that is not on A1-H8
+		// diagonal gets mapped below the diagonal.
+		for i := 0; i < d.groupLen[0]; i++ {
+			if row(squares[i]) == col(squares[i]) {
+				continue
+			}
+			if row(squares[i]) > col(squares[i]) {
+				for j := i; j < size; j++ {
+					squares[j] = ((squares[j] >> 3) | (squares[j] << 3)) & 63
+				}
+			}
+			break
+		}
+
+		if t.hasUniquePieces {
+			idx = tbEncodeUnique(squares)
+		} else {
+			idx = uint64(tbMapKK[tbMapA1D1D4[squares[0]]][squares[1]])
+		}
+	}
+
+	// Encode remaining groups: pawns and then pieces in ascending square order.
+	idx *= d.groupIdx[0]
+	start, remainingPawns := d.groupLen[0], t.hasPawns && t.pawnCount[1] > 0
+	for next := 1; d.groupLen[next] != 0; next++ {
+		group := squares[start : start + d.groupLen[next]]
+		for i := 1; i < len(group); i++ {
+			for j := i; j > 0 && group[j] < group[j - 1]; j-- {
+				group[j], group[j - 1] = group[j - 1], group[j]
+			}
+		}
+
+		n := uint64(0)
+		for i, sq := range group {
+			adjust := 0
+			for _, previous := range squares[:start] {
+				if sq > previous {
+					adjust++
+				}
+			}
+			if remainingPawns {
+				adjust += 8
+			}
+			n += tbBinomial[i + 1][sq - adjust]
+		}
+
+		remainingPawns = false
+		idx += n * d.groupIdx[next]
+		start += d.groupLen[next]
+	}
+
+	value, ok := t.decompress(d, idx)
+	if !ok {
+		return 0, tbFail
+	}
+	if !dtz {
+		return value - 2, tbOk
+	}
+	return t.mapDtz(file, value, wdl), tbOk
+}
+
+// Encodes the kings along with a unique piece.
+func tbEncodeUnique(squares [7]int) uint64 {
+	adjust1 := 0
+	if squares[1] > squares[0] {
+		adjust1++
+	}
+	adjust2 := 0
+	if squares[2] > squares[0] {
+		adjust2++
+	}
+	if squares[2] > squares[1] {
+		adjust2++
+	}
+
+	offA1H8 := func(sq int) int {
+		return row(sq) - col(sq)
+	}
+
+	var idx int
+	if offA1H8(squares[0]) != 0 {
+		idx = (tbMapA1D1D4[squares[0]] * 63 + squares[1] - adjust1) * 62 + squares[2] - adjust2
+	} else if offA1H8(squares[1]) != 0 {
+		idx = (6 * 63 + row(squares[0]) * 28 + tbMapB1H1H7[squares[1]]) * 62 + squares[2] - adjust2
+	} else if offA1H8(squares[2]) != 0 {
+		idx = 6 * 63 * 62 + 4 * 28 * 62 + row(squares[0]) * 7 * 28 + (row(squares[1]) - adjust1) * 28 + tbMapB1H1H7[squares[2]]
+	} else {
+		idx = 6 * 63 * 62 + 4 * 28 * 62 + 4 * 7 * 28 + row(squares[0]) * 7 * 6 + (row(squares[1]) - adjust1) * 6 + row(squares[2]) - adjust2
+	}
+
+	return uint64(idx)
+}
+
+// Converts DTZ table value to plies.
+func (t *tbTable) mapDtz(file, value, wdl int) int {
+	d := t.get(0, file)
+	if d.flags & tbMapped != 0 {
+		index := d.mapIdx[[]int{ 1, 3, 0, 2, 0 }[wdl + 2]] + value
+		if d.flags & tbWide != 0 {
+			value = t.u16(t.dtzMap + 2 * index)
+		} else {
+			value = t.u8(t.dtzMap + index)
+		}
+	}
+
+	if (wdl == tbWin && d.flags & tbWinPlies == 0) || (wdl == tbLoss && d.flags & tbLossPlies == 0) ||
+	   wdl == tbCursedWin || wdl == tbBlessedLoss {
+		value *= 2
+	}
+
+	return value + 1
+}
+
+// Probes the root position and returns the moves that preserve the best
+// tablebase outcome, picking the ones that zero the fifty moves counter the
+// soonest when winning, and the latest when losing. Wins that can't zero the
+// counter before fifty moves rule kicks in (given the moves already played)
+// are only better than draws, and such losses are only worse than draws.
+// Returns nil if the position can't be probed.
+func (tb *Tablebase) rootMoves(p *Position, searchMoves ...Move) (moves []Move) {
+	if !tb.covers(p) {
+		return nil
+	}
+
+	best := -0xFFFF
+	gen := NewRootGen(p, 1).generateRootMoves(searchMoves...)
+	for move := gen.NextMove(); move != 0; move = gen.NextMove() {
+		position := p.makeMove(move)
+
+		dtz, ok := 0, true
+		if move.capture() != 0 || move.piece().isPawn() {
+			var wdl int
+			wdl, ok = tb.probeWdl(position)
+			dtz = dtzBeforeZeroing(-wdl)
+		} else {
+			dtz, ok = tb.probeDtz(position)
+			dtz = -dtz
+			dtz += sign(dtz)
+		}
+		if dtz == 2 && position.isInCheck(position.color) && !NewGen(position, MaxPly).generateAllMoves().anyValid() {
+			dtz = 1 // The move mates.
+		}
+		position.undoLastMove()
+
+		if !ok {
+			return nil
+		}
+
+		// Rank the move: the shorter the win the better, and the longer the
+		// loss the better.
+		rank, halfmove := 0, int(p.halfmove)
+		if dtz > 0 && dtz + halfmove <= 100 {
+			rank = 1000 - dtz
+		} else if dtz > 0 { // Cursed win.
+			rank = 1
+		} else if dtz < 0 && halfmove - dtz <= 100 {
+			rank = -1000 - dtz
+		} else if dtz < 0 { // Blessed loss.
+			rank = -1
+		}
+
+		if rank > best {
+			best, moves = rank, append(moves[:0], move)
+		} else if rank == best {
+			moves = append(moves, move)
+		}
+	}
+
+	return moves
+}
+
+// Returns tablebase score for the side to move found at the given ply. Like
+// checkmate scores, the wins that are closer to the root score higher.
+func (tb *Tablebase) score(wdl, ply int) int {
+	switch wdl {
+	case tbWin:
+		return TablebaseWin - ply
+	case tbLoss:
+		return -TablebaseWin + ply
+	}
+	return 0 // Draws, cursed wins, and blessed losses.
+}
+
+// Returns Syzygy piece code: 1..6 for white pawn to king, 9..14 for black.
+func tbPiece(piece Piece) int {
+	return int(piece >> 1) | int(piece.color()) << 3
+}
+
+// Returns DTZ of the move that zeroes fifty moves counter.
+func dtzBeforeZeroing(wdl int) int {
+	switch wdl {
+	case tbWin:
+		return 1
+	case tbCursedWin:
+		return 101
+	case tbBlessedLoss:
+		return -101
+	case tbLoss:
+		return -1
+	}
+	return 0
+}
+
+func sign(n int) int {
+	if n > 0 {
+		return 1
+	} else if n < 0 {
+		return -1
+	}
+	return 0
+}
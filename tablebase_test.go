@@ -0,0 +1,375 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `io/ioutil`; `os`; `path/filepath`; `strings`; `testing`)
+
+// KQvK, KRvK, KBvK, KNvK, and KPvK tables in testdata/syzygy are made by
+// testdata/syzygy/generate.go. The generator writes the same encoding the
+// reader expects, so these tables only check that the two agree. Known results
+// are checked against the published tables when DONNA_SYZYGY environment
+// variable points to them (see TestTablebase300 and on). The tests that need
+// tables get skipped if they are missing.
+const syzygyTestPath = `testdata/syzygy`
+
+func syzygy(t *testing.T) *Tablebase {
+	tb := NewTablebase(syzygyTestPath)
+	if tb == nil {
+		t.Skip(`no Syzygy tables in ` + syzygyTestPath)
+	}
+	return tb
+}
+
+// Returns the published tables from DONNA_SYZYGY path, or skips the test if
+// any of the required ones are missing.
+func syzygyPublished(t *testing.T, tables ...string) *Tablebase {
+	path := os.Getenv(`DONNA_SYZYGY`)
+	if tb := NewTablebase(path); tb != nil {
+		for _, name := range tables {
+			if tb.wdl[name] == nil || tb.dtz[name] == nil {
+				t.Skip(`no ` + name + ` tables in ` + path)
+			}
+		}
+		return tb
+	}
+	t.Skip(`DONNA_SYZYGY doesn't point to Syzygy tables`)
+	return nil
+}
+
+// Probes position given in FEN notation and returns its WDL and DTZ scores.
+func probe(t *testing.T, tb *Tablebase, fen string) (wdl, dtz int) {
+	p := NewGame(fen).start()
+	wdl, ok := tb.probeWdl(p)
+	expect.True(t, ok)
+	dtz, ok = tb.probeDtz(p)
+	expect.True(t, ok)
+	return
+}
+
+// Index encoding tables.
+func TestTablebase000(t *testing.T) {
+	codes := map[int]bool{}
+	for idx := 0; idx < 10; idx++ {
+		for sq := A1; sq <= H8; sq++ {
+			codes[tbMapKK[idx][sq]] = true
+		}
+	}
+	expect.Eq(t, len(codes), 462)
+	expect.True(t, codes[461])
+}
+
+func TestTablebase010(t *testing.T) {
+	expect.Eq(t, tbMapA1D1D4[B1], 0)
+	expect.Eq(t, tbMapA1D1D4[D3], 5)
+	expect.Eq(t, tbMapA1D1D4[A1], 6)
+	expect.Eq(t, tbMapA1D1D4[D4], 9)
+	expect.Eq(t, tbMapB1H1H7[B1], 0)
+	expect.Eq(t, tbMapB1H1H7[H7], 27)
+}
+
+func TestTablebase020(t *testing.T) {
+	expect.Eq(t, tbMapPawns[A2], 47)
+	expect.Eq(t, tbMapPawns[H2], 46)
+	expect.Eq(t, tbMapPawns[A3], 45)
+	expect.Eq(t, tbLeadPawnsSize[1][0], uint64(6))
+	expect.Eq(t, tbBinomial[2][48], uint64(1128))
+}
+
+// Material signatures.
+func TestTablebase030(t *testing.T) {
+	p := NewGame(`Ke1,Rh1,a2,b2`, `Kd8,Bc8`).start()
+	expect.Eq(t, p.material(White), `KRPP`)
+	expect.Eq(t, p.material(Black), `KB`)
+}
+
+func TestTablebase040(t *testing.T) {
+	table := newTbTable(`KRPvKR`, ``, false)
+	expect.Eq(t, table.pieceCount, 5)
+	expect.True(t, table.hasPawns)
+	expect.True(t, table.hasUniquePieces)
+	expect.False(t, table.symmetric)
+	expect.Eq(t, table.pawnCount, [2]int{ 1, 0 })
+}
+
+func TestTablebase050(t *testing.T) {
+	table := newTbTable(`KPPvKP`, ``, false)
+	expect.Eq(t, table.pawnCount, [2]int{ 1, 2 }) // Leading color has fewer pawns.
+	table = newTbTable(`KRvKR`, ``, true)
+	expect.True(t, table.symmetric)
+	table = newTbTable(`KNNvK`, ``, true)
+	expect.False(t, table.hasUniquePieces)
+}
+
+// No tables found.
+func TestTablebase060(t *testing.T) {
+	expect.True(t, NewTablebase(``) == nil)
+	expect.True(t, NewTablebase(`testdata/none`) == nil)
+
+	engine := NewEngine(`syzygypath`, `testdata/none`, `depth`, 3)
+	expect.True(t, engine.tablebase == nil)
+	game := engine.NewGame(`Ka1,Rh1`, `Kh8`)
+	game.start()
+	move := game.Think()
+	expect.True(t, move != Move(0))
+}
+
+// Hand made KRvK table that stores win for white to move and loss for black
+// to move as single values.
+func TestTablebase070(t *testing.T) {
+	dir, _ := ioutil.TempDir(``, `syzygy`)
+	defer os.RemoveAll(dir)
+
+	data := make([]byte, 64)
+	copy(data, []byte{
+		0x71, 0xE8, 0x23, 0x5D, // Magic.
+		0x01,                   // Two-sided table without pawns.
+		0x00,                   // Group order.
+		0x66, 0x44, 0xEE,       // White king, white rook, and black king.
+		0x00,                   // Alignment.
+		0x80, 4,                // White to move: single value (win).
+		0x80, 0,                // Black to move: single value (loss).
+	})
+	ioutil.WriteFile(filepath.Join(dir, `KRvK.rtbw`), data, 0644)
+
+	tb := NewTablebase(dir)
+	expect.Eq(t, tb.pieces, 3)
+
+	score, ok := tb.probeWdl(NewGame(`Ka1,Rh1`, `Ke5`).start())
+	expect.True(t, ok)
+	expect.Eq(t, score, tbWin)
+
+	score, ok = tb.probeWdl(NewGame(`Ka1,Rh1`, `M,Ke5`).start())
+	expect.True(t, ok)
+	expect.Eq(t, score, tbLoss)
+
+	score, ok = tb.probeWdl(NewGame(`Ke5`, `Ka1,Rh1`).start()) // Colors flipped.
+	expect.True(t, ok)
+	expect.Eq(t, score, tbLoss)
+
+	_, ok = tb.probeWdl(NewGame(`Ka1,Qh1`, `Ke5`).start()) // No KQvK table.
+	expect.False(t, ok)
+
+	// Changed table file is not shared with the existing table.
+	expect.True(t, NewTablebase(dir).wdl[`KRvK`] == tb.wdl[`KRvK`])
+	ioutil.WriteFile(filepath.Join(dir, `KRvK.rtbw`), append(data, make([]byte, 64)...), 0644)
+	expect.True(t, NewTablebase(dir).wdl[`KRvK`] != tb.wdl[`KRvK`])
+}
+
+// Probing with actual tables.
+func TestTablebase100(t *testing.T) {
+	tb := syzygy(t)
+	p := NewGame(`Ka1,Rh1`, `Ke5`).start()
+	wdl, ok := tb.probeWdl(p)
+	expect.True(t, ok)
+	expect.Eq(t, wdl, tbWin)
+}
+
+func TestTablebase110(t *testing.T) {
+	tb := syzygy(t)
+	p := NewGame(`Ka1,Rh1`, `M,Ke5`).start()
+	wdl, ok := tb.probeWdl(p)
+	expect.True(t, ok)
+	expect.Eq(t, wdl, tbLoss)
+}
+
+// Black can capture hanging rook.
+func TestTablebase120(t *testing.T) {
+	tb := syzygy(t)
+	p := NewGame(`Ka1,Rd4`, `M,Ke5`).start()
+	wdl, ok := tb.probeWdl(p)
+	expect.True(t, ok)
+	expect.Eq(t, wdl, tbDraw)
+}
+
+func TestTablebase130(t *testing.T) {
+	tb := syzygy(t)
+	p := NewGame(`Ka1,Qh1`, `Ke5`).start()
+	dtz, ok := tb.probeDtz(p)
+	expect.True(t, ok)
+	expect.True(t, dtz > 0 && dtz < 100)
+}
+
+// Root moves preserve the win, and pick the fastest one: Rh1-a1 Kb8-c8 Ra1-a8
+// mate. Rh1-h8 is not a mate because of Kb8-a7.
+func TestTablebase140(t *testing.T) {
+	tb := syzygy(t)
+	p := NewGame(`Kc6,Rh1`, `M,Ka8`).start()
+	p = p.makeMove(NewMove(p, A8, B8))
+	moves := tb.rootMoves(p)
+	expect.Eq(t, moves, []Move{ NewMove(p, H1, A1) })
+}
+
+func TestTablebase150(t *testing.T) {
+	engine := NewEngine(`syzygypath`, syzygyTestPath, `depth`, 4)
+	if engine.tablebase == nil {
+		t.Skip(`no Syzygy tables in ` + syzygyTestPath)
+	}
+	game := engine.NewGame(`Kc6,Rh1`, `Kb8`)
+	game.start()
+	move := game.Think()
+	expect.Eq(t, move, `Rh1-a1`)
+}
+
+// Tables agree with king and pawn vs. king bitbase.
+func TestTablebase160(t *testing.T) {
+	tb, game := syzygy(t), NewGame()
+	for _, pawn := range []int{ A2, C4, E7, H5 } {
+		for wk := A1; wk <= H8; wk++ {
+			for bk := A1; bk <= H8; bk++ {
+				if wk == pawn || bk == pawn || distance[wk][bk] < 2 {
+					continue
+				}
+				for _, color := range []string{ ``, `M,` } {
+					game.initial = `K` + squareName(wk) + `,` + squareName(pawn) + ` : ` + color + `K` + squareName(bk)
+					p := game.start()
+					if p.isInCheck(p.color ^ 1) {
+						continue
+					}
+					wdl, ok := tb.probeWdl(p)
+					if p.color == Black {
+						wdl = -wdl
+					}
+					if wins := (&Evaluation{ position: p }).kingAndPawnWins(White, pawn); !ok || (wdl == tbWin) != wins {
+						t.Errorf(`%s: wdl %d, bitbase %v`, p.fen(), wdl, wins)
+					}
+				}
+			}
+		}
+	}
+}
+
+// Pawn moves zero the counter, so the pawn gets pushed right away unless the
+// king has to escort it first.
+func TestTablebase170(t *testing.T) {
+	tb := syzygy(t)
+	dtz, ok := tb.probeDtz(NewGame(`Kb6,c7`, `Ka8`).start())
+	expect.True(t, ok)
+	expect.Eq(t, dtz, 1)
+
+	dtz, ok = tb.probeDtz(NewGame(`Ke1,e2`, `M,Ke3`).start()) // Draw.
+	expect.True(t, ok)
+	expect.Eq(t, dtz, 0)
+
+	moves := tb.rootMoves(NewGame(`Kd6,d5`, `Kd8`).start()) // Kd6-c6 or Kd6-e6 gain the opposition.
+	expect.Eq(t, len(moves), 2)
+}
+
+// Tables are shared by all the engines.
+func TestTablebase180(t *testing.T) {
+	tb := syzygy(t)
+	expect.True(t, NewTablebase(syzygyTestPath).wdl[`KRvK`] == tb.wdl[`KRvK`])
+	expect.True(t, NewTablebase(syzygyTestPath).dtz[`KRvK`] == tb.dtz[`KRvK`])
+	expect.True(t, NewEngine(`syzygypath`, syzygyTestPath).tablebase.wdl[`KQvK`] == tb.wdl[`KQvK`])
+}
+
+// Fifty moves rule: after 97 half-moves only the fastest win is still a win,
+// and after 98 all the wins are cursed, so any of them would do.
+func TestTablebase190(t *testing.T) {
+	tb := syzygy(t)
+	p := NewGame(`1k6/8/2K5/8/8/8/8/7R w - - 97 60`).start()
+	expect.Eq(t, tb.rootMoves(p), []Move{ NewMove(p, H1, A1) })
+
+	p = NewGame(`1k6/8/2K5/8/8/8/8/7R w - - 98 60`).start()
+	moves := tb.rootMoves(p)
+	expect.Eq(t, len(moves), len(NewGen(p, 0).generateAllMoves().validOnly().allMoves()))
+}
+
+// Blessed losses: the longest loss is no better than any other one that lasts
+// past fifty moves rule.
+func TestTablebase200(t *testing.T) {
+	tb := syzygy(t)
+	p := NewGame(`8/8/8/4k3/8/8/8/K6R b - - 0 60`).start()
+	longest := tb.rootMoves(p)
+
+	p = NewGame(`8/8/8/4k3/8/8/8/K6R b - - 90 60`).start()
+	moves := tb.rootMoves(p)
+	expect.True(t, len(moves) > len(longest))
+	expect.Eq(t, len(moves), len(NewGen(p, 0).generateAllMoves().validOnly().allMoves()))
+}
+
+// Known results from the published tables. King on the sixth rank in front of
+// the pawn wins no matter who moves, and the king in the corner holds the rook
+// pawn.
+func TestTablebase300(t *testing.T) {
+	tb := syzygyPublished(t, `KPvK`)
+	for _, fen := range []string{ `4k3/8/4K3/8/4P3/8/8/8 w - - 0 1`, `4k3/8/4K3/8/4P3/8/8/8 b - - 0 1` } {
+		wdl, dtz := probe(t, tb, fen)
+		if strings.Contains(fen, ` w `) {
+			expect.Eq(t, wdl, tbWin)
+			expect.True(t, dtz > 0)
+		} else {
+			expect.Eq(t, wdl, tbLoss)
+			expect.True(t, dtz < 0)
+		}
+	}
+	for _, fen := range []string{ `k7/8/8/PK6/8/8/8/8 w - - 0 1`, `k7/8/8/PK6/8/8/8/8 b - - 0 1` } {
+		wdl, dtz := probe(t, tb, fen)
+		expect.Eq(t, wdl, tbDraw)
+		expect.Eq(t, dtz, 0)
+	}
+}
+
+// Rh1-h8 is the only mate in one.
+func TestTablebase310(t *testing.T) {
+	tb := syzygyPublished(t, `KRvK`)
+	wdl, _ := probe(t, tb, `k7/8/1K6/8/8/8/8/7R w - - 0 1`)
+	expect.Eq(t, wdl, tbWin)
+
+	p := NewGame(`k7/8/1K6/8/8/8/8/7R w - - 0 1`).start()
+	expect.Eq(t, tb.rootMoves(p), []Move{ NewMove(p, H1, H8) })
+}
+
+// Queen takes hanging rook, and king takes hanging queen: both captures win
+// right away.
+func TestTablebase320(t *testing.T) {
+	tb := syzygyPublished(t, `KQvKR`, `KQvK`)
+	wdl, dtz := probe(t, tb, `8/7k/8/3r4/8/8/8/1K1Q4 w - - 0 1`)
+	expect.Eq(t, wdl, tbWin)
+	expect.Eq(t, dtz, 1)
+
+	wdl, dtz = probe(t, tb, `4k3/4Q3/8/7r/8/8/8/4K3 b - - 0 1`)
+	expect.Eq(t, wdl, tbWin)
+	expect.Eq(t, dtz, 1)
+}
+
+// Black is mated.
+func TestTablebase330(t *testing.T) {
+	tb := syzygyPublished(t, `KQvKR`)
+	p := NewGame(`k7/1Q6/2K5/8/8/8/8/7r b - - 0 1`).start()
+	wdl, ok := tb.probeWdl(p)
+	expect.True(t, ok)
+	expect.Eq(t, wdl, tbLoss)
+}
+
+// Rook takes the pawn before it promotes, and king takes the rook leaving
+// drawn rook pawn ending.
+func TestTablebase340(t *testing.T) {
+	tb := syzygyPublished(t, `KRvKP`, `KRvK`, `KPvK`)
+	wdl, dtz := probe(t, tb, `R7/8/8/4k3/8/8/p7/7K w - - 0 1`)
+	expect.Eq(t, wdl, tbWin)
+	expect.Eq(t, dtz, 1)
+
+	wdl, dtz = probe(t, tb, `8/8/8/8/p2kR3/8/8/K7 b - - 0 1`)
+	expect.Eq(t, wdl, tbDraw)
+	expect.Eq(t, dtz, 0)
+}
+
+// Tablebase scores get closer to zero further from the root, and wins and
+// losses are cached as bounds.
+func TestTablebase210(t *testing.T) {
+	tb := syzygy(t)
+	expect.Eq(t, tb.score(tbWin, 3), TablebaseWin - 3)
+	expect.Eq(t, tb.score(tbLoss, 3), -TablebaseWin + 3)
+	expect.Eq(t, tb.score(tbDraw, 3), 0)
+
+	engine := NewEngine(`syzygypath`, syzygyTestPath, `cache`, 1)
+	p := engine.NewGame(`Ka1,Rd1`, `Ke6,Nd4`).start()
+	p = p.makeMove(NewMove(p, D1, D4))
+	expect.Eq(t, p.searchTree(-100, 100, 4), -TablebaseWin + 1)
+
+	cached := p.probeCache()
+	expect.Eq(t, cached.score, -TablebaseWin)
+	expect.Eq(t, cached.flags, uint8(cacheAlpha))
+}
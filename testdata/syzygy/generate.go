@@ -0,0 +1,914 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+//go:build ignore
+// +build ignore
+
+// Generates Syzygy tables used by the tests: win/draw/loss (.rtbw) and distance
+// to zeroing move (.rtbz) files for KQvK, KRvK, KBvK, KNvK, and KPvK. Run it
+// from the repository root:
+//
+//   go run testdata/syzygy/generate.go
+//
+// The endgames are solved by retrograde analysis that shares no code with the
+// engine, and the tables are compressed with recursive pairing and canonical
+// Huffman codes the way the original generator does (albeit less thoroughly).
+// Since the tables are written by the same reading of the format the engine
+// uses, they check that the reader and the writer agree rather than the format
+// itself; that is left to the tests that probe the published tables.
+package main
+
+import (
+	`fmt`
+	`io/ioutil`
+	`path/filepath`
+	`sort`
+)
+
+// Syzygy piece codes; black pieces are +8.
+const (
+	pawn = 1 + iota
+	knight
+	bishop
+	rook
+	queen
+	king
+)
+
+const (
+	white, black = 0, 1
+	unknown = 9        // Position value hasn't been determined yet.
+	unset = -1000      // Table entry doesn't correspond to any position.
+	outputDir = `testdata/syzygy`
+)
+
+var (
+	kingMoves, knightMoves [64][]int
+	directions = map[int][][2]int{
+		bishop: { { 1, 1 }, { 1, -1 }, { -1, 1 }, { -1, -1 } },
+		rook: { { 1, 0 }, { -1, 0 }, { 0, 1 }, { 0, -1 } },
+		queen: { { 1, 1 }, { 1, -1 }, { -1, 1 }, { -1, -1 }, { 1, 0 }, { -1, 0 }, { 0, 1 }, { 0, -1 } },
+	}
+
+	// Index encoding tables, see initTablebase() in tablebase.go.
+	mapA1D1D4 [64]int
+	mapB1H1H7 [64]int
+)
+
+// King with one extra white piece vs. lone king. Position values are from the
+// side to move point of view: 2 win, 0 draw, and -2 loss.
+type endgame struct {
+	name   string
+	piece  int 		// Extra white piece.
+	wdl    []int 		// Win/draw/loss by position index.
+	dtz    []int 		// Distance to zeroing move in plies (negative when losing).
+}
+
+// Move from the position: the resulting position index in the endgame, or nil
+// endgame for bare kings.
+type child struct {
+	endgame *endgame
+	index   int
+	zeroing bool
+}
+
+// Table layout: piece encoding order and group order for each side, and for
+// DTZ tables the side to move that gets stored.
+type layout struct {
+	pieces [2][3]int
+	order  [2]int
+	stm    int
+}
+
+// Compressed values of one side and one file of the table.
+type pairs struct {
+	groupLen  []int
+	groupIdx  []int
+	values    []int
+	dtzMap    [4][]int 	// Wins, losses, cursed wins, and blessed losses.
+	single    bool
+	flags     int
+	blockSize uint 		// Log2 of block size in bytes.
+	span      uint 		// Log2 of sparse index span.
+	maxLen    int
+	minLen    int
+	lowest    []int
+	tree      [][2]int
+	sparse    [][2]int
+	lengths   []int
+	data      []byte
+}
+
+func main() {
+	initTables()
+
+	queens := &endgame{ name: `KQvK`, piece: queen }
+	rooks := &endgame{ name: `KRvK`, piece: rook }
+	bishops := &endgame{ name: `KBvK`, piece: bishop }
+	knights := &endgame{ name: `KNvK`, piece: knight }
+	pawns := &endgame{ name: `KPvK`, piece: pawn }
+	promotions := map[int]*endgame{ queen: queens, rook: rooks, bishop: bishops, knight: knights }
+
+	for _, eg := range []*endgame{ queens, rooks, bishops, knights, pawns } {
+		eg.solveWdl(promotions)
+		eg.solveDtz(promotions)
+	}
+
+	// Vary the layouts so that the tests cover different piece and group
+	// orders, and both sides to move stored in DTZ tables.
+	queens.write(false, layout{ pieces: [2][3]int{ { king, queen, king + 8 }, { queen, king + 8, king } } })
+	queens.write(true, layout{ pieces: [2][3]int{ { king, king + 8, queen } }, stm: white })
+	rooks.write(false, layout{ pieces: [2][3]int{ { king + 8, king, rook }, { king, rook, king + 8 } } })
+	rooks.write(true, layout{ pieces: [2][3]int{ { rook, king, king + 8 } }, stm: black })
+	bishops.write(false, layout{ pieces: [2][3]int{ { king, bishop, king + 8 }, { king, bishop, king + 8 } } })
+	bishops.write(true, layout{ pieces: [2][3]int{ { king, bishop, king + 8 } } })
+	knights.write(false, layout{ pieces: [2][3]int{ { king, knight, king + 8 }, { king, knight, king + 8 } } })
+	knights.write(true, layout{ pieces: [2][3]int{ { king, knight, king + 8 } } })
+	pawns.write(false, layout{ pieces: [2][3]int{ { pawn, king, king + 8 }, { pawn, king + 8, king } }, order: [2]int{ 0, 2 } })
+	pawns.write(true, layout{ pieces: [2][3]int{ { pawn, king, king + 8 } }, order: [2]int{ 1, 0 }, stm: white })
+}
+
+func initTables() {
+	for sq := 0; sq < 64; sq++ {
+		for _, delta := range [][2]int{ { 1, 0 }, { 1, 1 }, { 0, 1 }, { -1, 1 }, { -1, 0 }, { -1, -1 }, { 0, -1 }, { 1, -1 } } {
+			if to, ok := step(sq, delta); ok {
+				kingMoves[sq] = append(kingMoves[sq], to)
+			}
+		}
+		for _, delta := range [][2]int{ { 2, 1 }, { 1, 2 }, { -1, 2 }, { -2, 1 }, { -2, -1 }, { -1, -2 }, { 1, -2 }, { 2, -1 } } {
+			if to, ok := step(sq, delta); ok {
+				knightMoves[sq] = append(knightMoves[sq], to)
+			}
+		}
+	}
+
+	code := 0
+	for sq := 0; sq < 64; sq++ {
+		if row(sq) < col(sq) {
+			mapB1H1H7[sq] = code
+			code++
+		}
+	}
+	code = 0
+	for _, sq := range []int{ 1, 2, 3, 10, 11, 19, 0, 9, 18, 27 } { // B1, C1, D1, C2, D2, D3, and then A1-D4 diagonal.
+		mapA1D1D4[sq] = code
+		code++
+	}
+}
+
+func row(sq int) int { return sq >> 3 }
+func col(sq int) int { return sq & 7 }
+
+func step(sq int, delta [2]int) (int, bool) {
+	r, c := row(sq) + delta[0], col(sq) + delta[1]
+	return r * 8 + c, r >= 0 && r < 8 && c >= 0 && c < 8
+}
+
+func adjacent(a, b int) bool {
+	return a != b && abs(row(a) - row(b)) <= 1 && abs(col(a) - col(b)) <= 1
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func index(stm, wk, x, bk int) int {
+	return stm << 18 | wk << 12 | x << 6 | bk
+}
+
+// Returns true if white piece attacks the target square. The only possible
+// blocker is white king since black king is either the target or has moved.
+func attacks(piece, from, target, blocker int) bool {
+	switch piece {
+	case pawn:
+		return row(target) == row(from) + 1 && abs(col(target) - col(from)) == 1
+	case knight:
+		for _, to := range knightMoves[from] {
+			if to == target {
+				return true
+			}
+		}
+		return false
+	}
+
+	for _, delta := range directions[piece] {
+		for sq, ok := step(from, delta); ok && sq != blocker; sq, ok = step(sq, delta) {
+			if sq == target {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (eg *endgame) legal(stm, wk, x, bk int) bool {
+	if wk == x || wk == bk || x == bk || adjacent(wk, bk) {
+		return false
+	}
+	if eg.piece == pawn && (row(x) == 0 || row(x) == 7) {
+		return false
+	}
+	return stm == black || !attacks(eg.piece, x, bk, wk)
+}
+
+// Generates the moves of the side to move. White king is never in check, and
+// moving white piece can't expose it either.
+func (eg *endgame) moves(stm, wk, x, bk int, promotions map[int]*endgame) (children []child) {
+	if stm == black {
+		for _, to := range kingMoves[bk] {
+			if to == wk || adjacent(to, wk) {
+				continue
+			}
+			if to == x { // Capture leaves bare kings.
+				children = append(children, child{ zeroing: true })
+			} else if !attacks(eg.piece, x, to, wk) {
+				children = append(children, child{ eg, index(white, wk, x, to), false })
+			}
+		}
+		return
+	}
+
+	for _, to := range kingMoves[wk] {
+		if to != x && !adjacent(to, bk) {
+			children = append(children, child{ eg, index(black, to, x, bk), false })
+		}
+	}
+
+	switch eg.piece {
+	case pawn:
+		if to := x + 8; to != wk && to != bk {
+			if row(to) == 7 {
+				for _, piece := range []int{ queen, rook, bishop, knight } {
+					children = append(children, child{ promotions[piece], index(black, wk, to, bk), true })
+				}
+			} else {
+				children = append(children, child{ eg, index(black, wk, to, bk), true })
+				if row(x) == 1 && to + 8 != wk && to + 8 != bk {
+					children = append(children, child{ eg, index(black, wk, to + 8, bk), true })
+				}
+			}
+		}
+	case knight:
+		for _, to := range knightMoves[x] {
+			if to != wk && to != bk {
+				children = append(children, child{ eg, index(black, wk, to, bk), false })
+			}
+		}
+	default:
+		for _, delta := range directions[eg.piece] {
+			for to, ok := step(x, delta); ok && to != wk && to != bk; to, ok = step(to, delta) {
+				children = append(children, child{ eg, index(black, wk, to, bk), false })
+			}
+		}
+	}
+	return
+}
+
+// Calls the function for each legal position.
+func (eg *endgame) each(fn func(stm, wk, x, bk int)) {
+	for stm := white; stm <= black; stm++ {
+		for wk := 0; wk < 64; wk++ {
+			for x := 0; x < 64; x++ {
+				for bk := 0; bk < 64; bk++ {
+					if eg.legal(stm, wk, x, bk) {
+						fn(stm, wk, x, bk)
+					}
+				}
+			}
+		}
+	}
+}
+
+func (c child) wdl() int {
+	if c.endgame == nil {
+		return 0
+	}
+	return c.endgame.wdl[c.index]
+}
+
+func (c child) mated(promotions map[int]*endgame) bool {
+	stm, wk, x, bk := c.index >> 18, c.index >> 12 & 63, c.index >> 6 & 63, c.index & 63
+	return c.endgame != nil && stm == black && attacks(c.endgame.piece, x, bk, wk) &&
+		len(c.endgame.moves(stm, wk, x, bk, promotions)) == 0
+}
+
+// Iterates until no more positions can be resolved: the position is won if
+// there is a move to lost position, and lost if all the moves lead to won
+// positions. The rest are draws.
+func (eg *endgame) solveWdl(promotions map[int]*endgame) {
+	eg.wdl = make([]int, 1 << 19)
+	for i := range eg.wdl {
+		eg.wdl[i] = unknown
+	}
+
+	for changed := true; changed; {
+		changed = false
+		eg.each(func(stm, wk, x, bk int) {
+			i := index(stm, wk, x, bk)
+			if eg.wdl[i] != unknown {
+				return
+			}
+			children := eg.moves(stm, wk, x, bk, promotions)
+			if len(children) == 0 { // Checkmate or stalemate.
+				if stm == black && attacks(eg.piece, x, bk, wk) {
+					eg.wdl[i] = -2
+				} else {
+					eg.wdl[i] = 0
+				}
+				changed = true
+				return
+			}
+
+			win, loss := false, true
+			for _, c := range children {
+				win = win || c.wdl() == -2
+				loss = loss && c.wdl() == 2
+			}
+			if win {
+				eg.wdl[i], changed = 2, true
+			} else if loss {
+				eg.wdl[i], changed = -2, true
+			}
+		})
+	}
+
+	for i := range eg.wdl {
+		if eg.wdl[i] == unknown {
+			eg.wdl[i] = 0
+		}
+	}
+}
+
+// Resolves DTZ one ply at a time. Winning side picks the fastest zeroing or
+// mating move, and losing side postpones them the longest.
+func (eg *endgame) solveDtz(promotions map[int]*endgame) {
+	eg.dtz = make([]int, 1 << 19)
+
+	for ply, pending := 1, true; pending; ply++ {
+		if ply > 1000 {
+			panic(eg.name + `: unresolved DTZ`)
+		}
+		pending = false
+		eg.each(func(stm, wk, x, bk int) {
+			i := index(stm, wk, x, bk)
+			if eg.wdl[i] == 0 || eg.dtz[i] != 0 {
+				return
+			}
+			children := eg.moves(stm, wk, x, bk, promotions)
+
+			if eg.wdl[i] > 0 {
+				best := 1000
+				for _, c := range children {
+					if c.wdl() != -2 {
+						continue
+					}
+					if c.zeroing || c.mated(promotions) {
+						best = 1
+					} else if dtz := c.endgame.dtz[c.index]; dtz != 0 {
+						best = min(best, 1 - dtz)
+					}
+				}
+				if best <= ply {
+					eg.dtz[i] = best
+				} else {
+					pending = true
+				}
+				return
+			}
+
+			worst := 1 // Checkmate.
+			for _, c := range children {
+				if c.zeroing {
+					continue
+				}
+				if c.endgame.dtz[c.index] == 0 {
+					pending = true
+					return
+				}
+				worst = max(worst, 1 + c.endgame.dtz[c.index])
+			}
+			if worst <= ply {
+				eg.dtz[i] = -worst
+			} else {
+				pending = true
+			}
+		})
+	}
+}
+
+func min(x, y int) int {
+	if x < y {
+		return x
+	}
+	return y
+}
+
+func max(x, y int) int {
+	if x > y {
+		return x
+	}
+	return y
+}
+
+// Splits the pieces into groups and computes index multiplier for each group,
+// see tbTable.setGroups().
+func (eg *endgame) groups(d *pairs, pieces [3]int, order int) {
+	firstLen := 3 // Kings along with the unique piece.
+	if eg.piece == pawn {
+		firstLen = 0
+	}
+	d.groupLen = []int{ 1 }
+	for i := 1; i < len(pieces); i++ {
+		if firstLen--; firstLen > 0 {
+			d.groupLen[len(d.groupLen) - 1]++
+		} else {
+			d.groupLen = append(d.groupLen, 1)
+		}
+	}
+
+	n := len(d.groupLen)
+	d.groupIdx = make([]int, n + 1)
+	next, free, idx := 1, 64 - d.groupLen[0], 1
+	for k := 0; next < n || k == order; k++ {
+		if k == order {
+			d.groupIdx[0] = idx
+			if eg.piece == pawn {
+				idx *= 6 // Leading pawn ranks.
+			} else {
+				idx *= 31332
+			}
+		} else {
+			d.groupIdx[next] = idx
+			idx *= free // Single piece groups only.
+			free -= d.groupLen[next]
+			next++
+		}
+	}
+	d.groupIdx[n] = idx
+}
+
+// Encodes the position with given piece order into the file and the index.
+func (eg *endgame) encode(d *pairs, pieces [3]int, wk, x, bk int) (file, idx int) {
+	var squares [3]int
+	for i, piece := range pieces {
+		squares[i] = map[int]int{ king: wk, eg.piece: x, king + 8: bk }[piece]
+	}
+
+	if col(squares[0]) > 3 {
+		for i := range squares {
+			squares[i] ^= 7
+		}
+	}
+
+	if eg.piece == pawn {
+		file = col(squares[0])
+		idx = row(squares[0]) - 1
+	} else {
+		if row(squares[0]) > 3 {
+			for i := range squares {
+				squares[i] ^= 56
+			}
+		}
+		for i := range squares { // Transpose if the first piece off the diagonal is above it.
+			if row(squares[i]) == col(squares[i]) {
+				continue
+			}
+			if row(squares[i]) > col(squares[i]) {
+				for j := i; j < len(squares); j++ {
+					squares[j] = col(squares[j]) * 8 + row(squares[j])
+				}
+			}
+			break
+		}
+		idx = encodeUnique(squares)
+	}
+
+	idx *= d.groupIdx[0]
+	for g := 1; g < len(d.groupLen); g++ {
+		sq, adjust := squares[g + d.groupLen[0] - 1], 0
+		for _, previous := range squares[:g + d.groupLen[0] - 1] {
+			if sq > previous {
+				adjust++
+			}
+		}
+		idx += (sq - adjust) * d.groupIdx[g]
+	}
+
+	return
+}
+
+func encodeUnique(squares [3]int) int {
+	s0, s1, s2 := squares[0], squares[1], squares[2]
+	adjust1, adjust2 := 0, 0
+	if s1 > s0 {
+		adjust1++
+	}
+	if s2 > s0 {
+		adjust2++
+	}
+	if s2 > s1 {
+		adjust2++
+	}
+
+	switch {
+	case row(s0) != col(s0):
+		return (mapA1D1D4[s0] * 63 + s1 - adjust1) * 62 + s2 - adjust2
+	case row(s1) != col(s1):
+		return (6 * 63 + row(s0) * 28 + mapB1H1H7[s1]) * 62 + s2 - adjust2
+	case row(s2) != col(s2):
+		return 6 * 63 * 62 + 4 * 28 * 62 + row(s0) * 7 * 28 + (row(s1) - adjust1) * 28 + mapB1H1H7[s2]
+	}
+	return 6 * 63 * 62 + 4 * 28 * 62 + 4 * 7 * 28 + row(s0) * 7 * 6 + (row(s1) - adjust1) * 6 + row(s2) - adjust2
+}
+
+// Fills in the table values, compresses them, and writes the table file.
+func (eg *endgame) write(dtz bool, l layout) {
+	sides, files := 2, 1
+	if dtz {
+		sides = 1
+	}
+	if eg.piece == pawn {
+		files = 4
+	}
+
+	var tables [2][4]pairs
+	for side := 0; side < sides; side++ {
+		for file := 0; file < files; file++ {
+			d := &tables[side][file]
+			eg.groups(d, l.pieces[side], l.order[side])
+			d.values = make([]int, d.groupIdx[len(d.groupLen)])
+			for i := range d.values {
+				d.values[i] = unset
+			}
+		}
+	}
+
+	eg.each(func(stm, wk, x, bk int) {
+		i, side := index(stm, wk, x, bk), stm
+		value := eg.wdl[i] + 2
+		if dtz {
+			if stm != l.stm || eg.wdl[i] == 0 {
+				return
+			}
+			side, value = 0, eg.dtz[i]
+		}
+		file, idx := eg.encode(&tables[side][0], l.pieces[side], wk, x, bk) // Same groups for all the files.
+		d := &tables[side][file]
+		if d.values[idx] != unset && d.values[idx] != value {
+			panic(fmt.Sprintf(`%s: symmetric positions mismatch at %d`, eg.name, idx))
+		}
+		d.values[idx] = value
+	})
+
+	for side := 0; side < sides; side++ {
+		for file := 0; file < files; file++ {
+			d := &tables[side][file]
+			if dtz {
+				d.mapDtz()
+				if l.stm == black {
+					d.flags |= 1
+				}
+			}
+			d.compress()
+		}
+	}
+
+	magic, ext := []byte{ 0x71, 0xE8, 0x23, 0x5D }, `.rtbw`
+	if dtz {
+		magic, ext = []byte{ 0xD7, 0x66, 0x0C, 0xA5 }, `.rtbz`
+	}
+	data := append([]byte{}, magic...)
+	data = append(data, byte(1 | map[bool]int{ false: 0, true: 2 }[eg.piece == pawn]))
+
+	for file := 0; file < files; file++ {
+		data = append(data, byte(l.order[0] | l.order[1] << 4))
+		for k := 0; k < 3; k++ {
+			data = append(data, byte(l.pieces[0][k] | l.pieces[1][k] << 4))
+		}
+	}
+	data = pad(data, 2)
+
+	for file := 0; file < files; file++ {
+		for side := 0; side < sides; side++ {
+			data = tables[side][file].sizes(data)
+		}
+	}
+	if dtz {
+		for file := 0; file < files; file++ {
+			if d := &tables[0][file]; !d.single {
+				for _, values := range d.dtzMap {
+					data = append(data, byte(len(values)))
+					for _, value := range values {
+						data = append(data, byte(value))
+					}
+				}
+			}
+		}
+		data = pad(data, 2)
+	}
+
+	for file := 0; file < files; file++ {
+		for side := 0; side < sides; side++ {
+			for _, entry := range tables[side][file].sparse {
+				data = append(data, u32(entry[0])...)
+				data = append(data, u16(entry[1])...)
+			}
+		}
+	}
+	for file := 0; file < files; file++ {
+		for side := 0; side < sides; side++ {
+			for _, length := range tables[side][file].lengths {
+				data = append(data, u16(length)...)
+			}
+		}
+	}
+	for file := 0; file < files; file++ {
+		for side := 0; side < sides; side++ {
+			data = pad(data, 64)
+			data = append(data, tables[side][file].data...)
+		}
+	}
+	data = pad(data, 64)
+
+	name := filepath.Join(outputDir, eg.name + ext)
+	if err := ioutil.WriteFile(name, data, 0644); err != nil {
+		panic(err)
+	}
+	fmt.Printf("%s: %d bytes\n", name, len(data))
+}
+
+// Replaces DTZ values with their indices in the value map, and picks plies
+// rather than moves since odd DTZ values can't be halved.
+func (d *pairs) mapDtz() {
+	d.flags = 2 | 4 | 8 // Mapped values, win and loss plies.
+	seen := [2]map[int]bool{ {}, {} }
+	for _, value := range d.values {
+		if value > 0 {
+			seen[0][value - 1] = true
+		} else if value != unset {
+			seen[1][-value - 1] = true
+		}
+	}
+
+	for i := range seen {
+		for value := range seen[i] {
+			d.dtzMap[i] = append(d.dtzMap[i], value)
+		}
+		sort.Ints(d.dtzMap[i])
+	}
+
+	for i, value := range d.values {
+		if value == unset {
+			continue
+		}
+		m, stored := 0, value - 1
+		if value < 0 {
+			m, stored = 1, -value - 1
+		}
+		d.values[i] = sort.SearchInts(d.dtzMap[m], stored)
+	}
+}
+
+// Compresses the values: fills in "don't care" entries, replaces the most
+// frequent pairs of symbols with new symbols, and then encodes the symbols
+// with canonical Huffman codes split into fixed size blocks.
+func (d *pairs) compress() {
+	last := 0
+	for _, value := range d.values {
+		if value != unset {
+			last = value
+			break
+		}
+	}
+	for i := range d.values {
+		if d.values[i] == unset {
+			d.values[i] = last
+		}
+		last = d.values[i]
+	}
+
+	leaves := map[int]int{}
+	stream := make([]int, len(d.values))
+	for i, value := range d.values {
+		if _, ok := leaves[value]; !ok {
+			leaves[value] = len(d.tree)
+			d.tree = append(d.tree, [2]int{ value, 0xFFF })
+		}
+		stream[i] = leaves[value]
+	}
+	if len(d.tree) == 1 {
+		d.single = true
+		d.flags = d.flags & 1 | 0x80 // Keep stored side to move.
+		return
+	}
+
+	// Recursive pairing.
+	symlen := make([]int, len(d.tree)) // Values per symbol less one.
+	for len(d.tree) < 1000 {
+		counts := map[[2]int]int{}
+		for i := 0; i + 1 < len(stream); i++ {
+			counts[[2]int{ stream[i], stream[i + 1] }]++
+			if stream[i] == stream[i + 1] && i + 2 < len(stream) && stream[i + 2] == stream[i] {
+				i++ // Don't count overlapping pairs.
+			}
+		}
+		best, count := [2]int{}, 0
+		for pair, n := range counts {
+			if n > count || (n == count && (pair[0] < best[0] || (pair[0] == best[0] && pair[1] < best[1]))) {
+				best, count = pair, n
+			}
+		}
+		if count < 8 || symlen[best[0]] + symlen[best[1]] + 2 > 4096 {
+			break
+		}
+
+		sym, paired, distinct := len(d.tree), []int{}, map[int]bool{}
+		for i := 0; i < len(stream); i++ {
+			if i + 1 < len(stream) && stream[i] == best[0] && stream[i + 1] == best[1] {
+				paired = append(paired, sym)
+				i++
+			} else {
+				paired = append(paired, stream[i])
+			}
+			distinct[paired[len(paired) - 1]] = true
+		}
+		if len(distinct) < 2 {
+			break
+		}
+		d.tree = append(d.tree, best)
+		symlen = append(symlen, symlen[best[0]] + symlen[best[1]] + 1)
+		stream = paired
+	}
+
+	// Huffman code lengths, limited so that the decoder buffer always has
+	// enough bits.
+	freq := make([]int, len(d.tree))
+	for _, sym := range stream {
+		freq[sym]++
+	}
+	lengths := huffman(freq)
+	for longest(lengths) > 24 {
+		for i := range freq {
+			if freq[i] > 0 {
+				freq[i] = freq[i] / 2 + 1
+			}
+		}
+		lengths = huffman(freq)
+	}
+
+	// Canonical numbering: longer codes get lower symbol numbers, and the
+	// symbols that only appear within other symbols go last.
+	order := make([]int, len(d.tree))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := lengths[order[i]], lengths[order[j]]
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a > b
+	})
+	number := make([]int, len(d.tree))
+	for n, sym := range order {
+		number[sym] = n
+	}
+
+	d.minLen, d.maxLen = 64, 0
+	for _, length := range lengths {
+		if length > 0 {
+			d.minLen, d.maxLen = min(d.minLen, length), max(d.maxLen, length)
+		}
+	}
+	count := make([]int, d.maxLen - d.minLen + 1)
+	for _, length := range lengths {
+		if length > 0 {
+			count[length - d.minLen]++
+		}
+	}
+	d.lowest = make([]int, len(count))
+	base := make([]int, len(count))
+	for i := len(count) - 2; i >= 0; i-- {
+		d.lowest[i] = d.lowest[i + 1] + count[i + 1]
+		base[i] = (base[i + 1] + count[i + 1]) / 2
+	}
+
+	tree := make([][2]int, len(d.tree))
+	for sym, node := range d.tree {
+		if node[1] == 0xFFF {
+			tree[number[sym]] = node
+		} else {
+			tree[number[sym]] = [2]int{ number[node[0]], number[node[1]] }
+		}
+	}
+	d.tree = tree
+
+	// Pack the codes into blocks.
+	d.blockSize, d.span = 5, 6
+	blockBits, start, starts := 8 << d.blockSize, 0, []int{}
+	bits, values := blockBits, 0
+	for _, sym := range stream {
+		length := lengths[sym]
+		n := number[sym]
+		code := base[length - d.minLen] + n - d.lowest[length - d.minLen]
+		if bits + length > blockBits || values + symlen[sym] + 1 > 60000 {
+			if values > 0 {
+				d.lengths = append(d.lengths, values - 1)
+			}
+			starts = append(starts, start)
+			d.data = append(d.data, make([]byte, 1 << d.blockSize)...)
+			bits, values = 0, 0
+		}
+		block := d.data[len(d.data) - (1 << d.blockSize):]
+		for b := length - 1; b >= 0; b, bits = b - 1, bits + 1 {
+			if code >> uint(b) & 1 != 0 {
+				block[bits / 8] |= 0x80 >> uint(bits % 8)
+			}
+		}
+		values += symlen[sym] + 1
+		start += symlen[sym] + 1
+	}
+	d.lengths = append(d.lengths, values - 1)
+
+	// Sparse index points at the block and offset of the value in the middle
+	// of each span.
+	span := 1 << d.span
+	for k := 0; k * span < len(d.values); k++ {
+		middle := k * span + span / 2
+		block := sort.Search(len(starts), func(i int) bool { return starts[i] > middle }) - 1
+		d.sparse = append(d.sparse, [2]int{ block, middle - starts[block] })
+	}
+}
+
+// Returns Huffman code lengths for given symbol frequencies.
+func huffman(freq []int) []int {
+	type node struct {
+		weight  int
+		symbols []int
+	}
+	nodes := []node{}
+	for sym, n := range freq {
+		if n > 0 {
+			nodes = append(nodes, node{ n, []int{ sym } })
+		}
+	}
+
+	lengths := make([]int, len(freq))
+	for len(nodes) > 1 {
+		sort.SliceStable(nodes, func(i, j int) bool { return nodes[i].weight < nodes[j].weight })
+		merged := node{ nodes[0].weight + nodes[1].weight, append(append([]int{}, nodes[0].symbols...), nodes[1].symbols...) }
+		for _, sym := range merged.symbols {
+			lengths[sym]++
+		}
+		nodes = append([]node{ merged }, nodes[2:]...)
+	}
+	return lengths
+}
+
+func longest(lengths []int) (n int) {
+	for _, length := range lengths {
+		n = max(n, length)
+	}
+	return
+}
+
+// Appends block sizes and Huffman code tables.
+func (d *pairs) sizes(data []byte) []byte {
+	if d.single {
+		return append(data, byte(d.flags), byte(d.values[0]))
+	}
+
+	data = append(data, byte(d.flags), byte(d.blockSize), byte(d.span), 0)
+	data = append(data, u32(len(d.lengths))...)
+	data = append(data, byte(d.maxLen), byte(d.minLen))
+	for _, lowest := range d.lowest {
+		data = append(data, u16(lowest)...)
+	}
+	data = append(data, u16(len(d.tree))...)
+	for _, node := range d.tree {
+		data = append(data, byte(node[0]), byte(node[0] >> 8 | (node[1] & 0xF) << 4), byte(node[1] >> 4))
+	}
+	if len(d.tree) & 1 != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+func pad(data []byte, alignment int) []byte {
+	for len(data) % alignment != 0 {
+		data = append(data, 0)
+	}
+	return data
+}
+
+func u16(n int) []byte {
+	return []byte{ byte(n), byte(n >> 8) }
+}
+
+func u32(n int) []byte {
+	return []byte{ byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24) }
+}
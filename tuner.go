@@ -0,0 +1,230 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import (
+	`bufio`
+	`fmt`
+	`io`
+	`math`
+	`regexp`
+	`strings`
+)
+
+// Texel tuner: adjusts evaluation parameters to minimize mean squared error
+// between game results and evaluation scores mapped to expected game results
+// by sigmoid function.
+type Tuner struct {
+	game        *Game 	// Game that owns the worker used for evaluation.
+	positions   []Position 	// Quiet positions labelled with game results.
+	results     []float64 	// Game results from White's point of view: 1, 0.5, or 0.
+	params      []param 	// Parameters being tuned.
+	values      *Params 	// Engine's own copy of evaluation parameters the tuner changes.
+	k           float64 	// Sigmoid scaling constant.
+}
+
+// Game results as they appear in the tuning positions file.
+var tunerResults = map[string]float64{
+	`1-0`: 1.0, `1/2-1/2`: 0.5, `0-1`: 0.0,
+	`1.0`: 1.0, `0.5`:     0.5, `0.0`: 0.0,
+}
+
+// Creates the tuner and reads tuning positions, one per line. Each line is
+// FEN or EPD followed by the game result, ex:
+//
+//   rnbqkb1r/pp2pppp/3p1n2/8/3NP3/8/PPP2PPP/RNBQKB1R w KQkq - 1 5 [0.5]
+//   r1bqkbnr/pppp1ppp/2n5/4p3/4P3/5N2/PPPP1PPP/RNBQKB1R w KQkq - c9 "1-0";
+//
+// Blank lines and lines starting with # are ignored. The tuner changes the
+// engine's evaluation parameters: the engine gets its own copy of them so that
+// other engines are not affected.
+func (e *Engine) NewTuner(r io.Reader) (*Tuner, error) {
+	if err := e.beginParamsChange(); err != nil {
+		return nil, err
+	}
+	e.params = e.params.clone()
+	e.endParamsChange()
+
+	t := &Tuner{game: e.NewGame(), values: e.params, k: 1.0}
+	t.params = t.values.list()
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == `` || text[0] == '#' {
+			continue
+		}
+		position, result, err := t.parse(text)
+		if err != nil {
+			return nil, fmt.Errorf(`tuner: line %d: %v`, line, err)
+		}
+		t.positions = append(t.positions, *position)
+		t.results = append(t.results, result)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(t.positions) == 0 {
+		return nil, fmt.Errorf(`tuner: no positions to tune`)
+	}
+
+	return t, nil
+}
+
+// Parses tuning position along with its game result.
+func (t *Tuner) parse(line string) (*Position, float64, error) {
+	fields := strings.Fields(strings.NewReplacer(`"`, ` `, `;`, ` `, `[`, ` `, `]`, ` `).Replace(line))
+	if len(fields) < 4 {
+		return nil, 0.0, fmt.Errorf(`expected at least 4 fields in "%s"`, line)
+	}
+
+	result, found := 0.0, false
+	for _, field := range fields[4:] {
+		if value, ok := tunerResults[field]; ok {
+			result, found = value, true
+		}
+	}
+	if !found {
+		return nil, 0.0, fmt.Errorf(`missing game result in "%s"`, line)
+	}
+
+	fen := strings.Join(fields[:4], ` `)
+	if err := checkFEN(fen); err != nil {
+		return nil, 0.0, err
+	}
+	position := NewPositionFromFEN(t.game, fen)
+	if err := position.validate(); err != nil {
+		return nil, 0.0, err
+	}
+
+	return position, result, nil
+}
+
+// Returns the number of tuning positions.
+func (t *Tuner) Size() int {
+	return len(t.positions)
+}
+
+// Limits tuning to the parameters whose names match the regular expression,
+// ex. "^value" for piece values or "^mobility" for mobility bonuses.
+func (t *Tuner) Select(pattern string) error {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return err
+	}
+
+	t.params = t.params[:0]
+	for _, param := range t.values.list() {
+		if re.MatchString(param.name) {
+			t.params = append(t.params, param)
+		}
+	}
+	if len(t.params) == 0 {
+		return fmt.Errorf(`tuner: no parameters match "%s"`, pattern)
+	}
+
+	return nil
+}
+
+// Maps the score to the expected game result.
+func (t *Tuner) sigmoid(score int) float64 {
+	return 1.0 / (1.0 + math.Pow(10.0, -t.k * float64(score) / 400.0))
+}
+
+// Returns mean squared error of the evaluation for the current parameter
+// values. The pawn cache gets cleared since cached pawn scores depend on the
+// parameters.
+func (t *Tuner) Error() float64 {
+	t.game.workers[0].pawnCache = PawnCache{}
+
+	sum := 0.0
+	for i := range t.positions {
+		p := &t.positions[i]
+		score := p.Evaluate()
+		if p.color == Black {
+			score = -score // Evaluation is from the side to move point of view.
+		}
+		sum += math.Pow(t.results[i] - t.sigmoid(score), 2)
+	}
+
+	return sum / float64(len(t.positions))
+}
+
+// Finds the sigmoid scaling constant that minimizes the error for the current
+// parameter values. This should be done once before tuning.
+func (t *Tuner) FitK() float64 {
+	best := t.Error()
+	for step := 0.1; step > 0.0001; step /= 10.0 {
+		for improved := true; improved; {
+			improved = false
+			for _, delta := range []float64{ step, -step } {
+				t.k += delta
+				if err := t.Error(); err < best {
+					best, improved = err, true
+					break
+				}
+				t.k -= delta
+			}
+		}
+	}
+
+	return t.k
+}
+
+// Runs local search: each parameter gets nudged up and down, and the change is
+// kept if it reduces the error. Stops when none of the parameters could be
+// improved or after the given number of iterations (zero means no limit). The
+// callback, if any, gets invoked after each iteration. Tuning fails if any of
+// the engine's games is searching, and the searches started while tuning wait
+// for it to end.
+func (t *Tuner) Tune(iterations int, callback func(iteration int, err float64)) (float64, error) {
+	engine := t.game.engine
+	if err := engine.beginParamsChange(); err != nil {
+		return 0.0, err
+	}
+	defer engine.endParamsChange()
+
+	best := t.Error()
+	for iteration := 1; iterations == 0 || iteration <= iterations; iteration++ {
+		improved := false
+		for _, param := range t.params {
+			for _, delta := range []int{ 1, -1 } {
+				*param.value += delta
+				t.update(param)
+				if err := t.Error(); err < best {
+					best, improved = err, true
+					break
+				}
+				*param.value -= delta
+				t.update(param)
+			}
+		}
+		if callback != nil {
+			callback(iteration, best)
+		}
+		if !improved {
+			break
+		}
+	}
+
+	return best, nil
+}
+
+// Writes tuned parameters.
+func (t *Tuner) Save(w io.Writer) error {
+	return t.values.write(w)
+}
+
+// Rebuilds derived tables after parameter change and updates incremental PST
+// values of the tuning positions.
+func (t *Tuner) update(param param) {
+	t.values.initValues()
+	if strings.HasPrefix(param.name, `imbalance`) {
+		t.values.initImbalance()
+	}
+	for i := range t.positions {
+		t.positions[i].tally = t.positions[i].valuation()
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `bytes`; `strings`; `testing`)
+
+// Changing piece values and bonuses rebuilds derived tables.
+func TestTuner010(t *testing.T) {
+	params := defaultParams.clone()
+	params.valueRook.midgame += 10
+	params.bonusKnight[0][A8] += 5 // A8 is A1 for White.
+	params.initValues()
+	expect.Eq(t, params.pst[Rook][A1].midgame, params.valueRook.midgame + params.bonusRook[0][A8])
+	expect.Eq(t, params.pst[BlackRook][A1].midgame, -params.valueRook.midgame - params.bonusRook[0][A1])
+	expect.Eq(t, params.pst[Knight][A1].midgame, params.valueKnight.midgame + params.bonusKnight[0][A8])
+	expect.Eq(t, params.pieceValue[BlackRook], params.valueRook.midgame)
+	expect.Eq(t, params.exchangeScores[Rook], params.valueRook.midgame)
+	expect.Eq(t, defaultParams.pieceValue[BlackRook], 635)
+}
+
+// Tuning positions with results in various formats.
+func TestTuner020(t *testing.T) {
+	tuner, err := NewEngine().NewTuner(strings.NewReader(
+		"# Comment.\n\n" +
+		"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 [1.0]\n" +
+		"4k3/8/8/8/8/8/4P3/4K3 b - - 0 1 [0.5]\n" +
+		"4k3/4p3/8/8/8/8/8/4K3 w - - c9 \"0-1\";\n" +
+		"rnbqkbnr/pppppppp/8/8/8/8/PPPPPPPP/RNBQKBNR w KQkq - 0 1 1/2-1/2\n"))
+	expect.Eq(t, err, nil)
+	expect.Eq(t, tuner.Size(), 4)
+	expect.Eq(t, tuner.results, []float64{ 1.0, 0.5, 0.0, 0.5 })
+	expect.Eq(t, tuner.positions[1].color, uint8(Black))
+	expect.Eq(t, tuner.positions[3].tally, Score{0, 0})
+}
+
+func TestTuner030(t *testing.T) {
+	for _, data := range []string{
+		``,
+		"# Comment only.\n",
+		"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1\n",
+		"4k3/8/8/8/8/8/4P3/4K3 w - - 0 1 1\n",
+		"4k3/8/8/8/8/8/4P3/4X3 w - - 0 1 [1.0]\n",
+		"4k3/8/8/8/8/8/4P3/8 w - - 0 1 [1.0]\n",
+	} {
+		_, err := NewEngine().NewTuner(strings.NewReader(data))
+		expect.Ne(t, err, nil)
+	}
+}
+
+// Evaluation error uses White's point of view regardless of side to move.
+func TestTuner040(t *testing.T) {
+	tuner, _ := NewEngine().NewTuner(strings.NewReader(
+		"4k3/pppppppp/8/8/8/8/PPPPPPPP/3QK3 w - - 0 1 1-0\n" +
+		"4k3/pppppppp/8/8/8/8/PPPPPPPP/3QK3 b - - 0 1 1-0\n"))
+	expect.True(t, tuner.Error() < 0.01)
+
+	tuner, _ = NewEngine().NewTuner(strings.NewReader(
+		"4k3/pppppppp/8/8/8/8/PPPPPPPP/3QK3 w - - 0 1 0-1\n" +
+		"4k3/pppppppp/8/8/8/8/PPPPPPPP/3QK3 b - - 0 1 0-1\n"))
+	expect.True(t, tuner.Error() > 0.9)
+}
+
+func TestTuner050(t *testing.T) {
+	tuner, _ := NewEngine().NewTuner(strings.NewReader("4k3/pppp4/8/8/8/8/PPPP4/4K2R w - - 0 1 [1.0]\n"))
+	expect.Eq(t, tuner.Select(`^rook`), nil)
+	expect.Eq(t, len(tuner.params), 10)
+	expect.Ne(t, tuner.Select(`^nothing`), nil)
+	expect.Ne(t, tuner.Select(`[`), nil)
+}
+
+// Extra rook on open file in a drawn game: the tuner should lower the bonus.
+func TestTuner060(t *testing.T) {
+	engine := NewEngine()
+	tuner, _ := engine.NewTuner(strings.NewReader(
+		"4k3/pppp4/8/8/8/8/PPPP4/4K2R w - - 0 1 [0.5]\n" +
+		"4k3/pppp4/8/8/8/8/PPPP4/4K2R b - - 0 1 [0.5]\n"))
+	tuner.Select(`^rookOnOpen\.endgame$`)
+	before, midgame, endgame := tuner.Error(), engine.params.rookOnOpen.midgame, engine.params.rookOnOpen.endgame
+
+	iterations := 0
+	after, err := tuner.Tune(3, func(iteration int, err float64) {
+		iterations = iteration
+	})
+	expect.Eq(t, err, nil)
+	expect.Eq(t, iterations, 3)
+	expect.True(t, after < before)
+	expect.Eq(t, after, tuner.Error())
+	expect.Eq(t, engine.params.rookOnOpen.midgame, midgame)
+	expect.Eq(t, engine.params.rookOnOpen.endgame, endgame - 3)
+	expect.Eq(t, defaultParams.rookOnOpen, Score{22, 10})
+
+	buffer := &bytes.Buffer{}
+	tuner.Save(buffer)
+	expect.Contain(t, buffer.String(), "\nrookOnOpen.midgame = 22\nrookOnOpen.endgame = 7\n")
+	expect.Contain(t, buffer.String(), "\nvaluePawn.midgame = 100\n")
+}
+
+// Fitting sigmoid scaling constant.
+func TestTuner070(t *testing.T) {
+	tuner, _ := NewEngine().NewTuner(strings.NewReader(
+		"4k3/pppp4/8/8/8/8/PPPP4/4K2R w - - 0 1 [0.5]\n" +
+		"4k3/pppp4/8/8/8/8/PPPP4/4K2R w - - 0 1 [1.0]\n"))
+	before := tuner.Error()
+	k := tuner.FitK()
+	expect.True(t, k < 1.0)
+	expect.True(t, tuner.Error() < before)
+}
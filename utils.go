@@ -60,11 +60,6 @@ func between(from, to, between int) bool {
 	return (maskStraight[from][to] | maskDiagonal[from][to]).on(between)
 }
 
-// Returns distance between current and root node.
-func ply() int {
-	return node - rootNode
-}
-
 // Integer version of math/abs.
 func abs(n int) int {
 	if n < 0 {
@@ -106,9 +101,10 @@ func since(start time.Time) int64 {
 	return time.Since(start).Nanoseconds() / 1000000
 }
 
-// Returns nodes per second search speed for the given time duration.
-func nps(duration int64) int64 {
-	nodes := int64(game.nodes + game.qnodes) * 1000
+// Returns nodes per second search speed for the given number of nodes and time
+// duration.
+func nps(count int, duration int64) int64 {
+	nodes := int64(count) * 1000
 	if duration != 0 {
 		return nodes / duration
 	}
@@ -134,6 +130,11 @@ func C(color uint8) string {
 	return [2]string{`white`, `black`}[color]
 }
 
+// Returns square name, ex. `e4`.
+func squareName(square int) string {
+	return string([]byte{ byte(col(square)) + 'a', byte(row(square)) + '1' })
+}
+
 func Summary(metrics map[string]interface{}) {
 	phase := metrics[`Phase`].(int)
 	tally := metrics[`PST`].(Score)
@@ -171,28 +172,3 @@ func Summary(metrics map[string]interface{}) {
 	fmt.Printf("%-12s    -      -    %5.2f  |    -      -    %5.2f  >  %5.2f\n\n", `Final Score`,
 		float32(final.midgame)/units, float32(final.endgame)/units, float32(final.blended(phase))/units)
 }
-
-// Logging wrapper around fmt.Printf() that could be turned on as needed. Typical
-// usage is Log(); defer Log() in tests.
-func Log(args ...interface{}) {
-	switch len(args) {
-	case 0:
-		// Calling Log() with no arguments flips the logging setting.
-		engine.log = !engine.log
-		engine.fancy = !engine.fancy
-	case 1:
-		switch args[0].(type) {
-		case bool:
-			engine.log = args[0].(bool)
-			engine.fancy = args[0].(bool)
-		default:
-			if engine.log {
-				fmt.Println(args...)
-			}
-		}
-	default:
-		if engine.log {
-			fmt.Printf(args[0].(string), args[1:]...)
-		}
-	}
-}
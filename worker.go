@@ -0,0 +1,187 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import `sync/atomic`
+
+// Search worker holds everything the search modifies as it walks the tree. With
+// Lazy SMP each worker searches the same root position on its own goroutine,
+// and the workers share nothing but the transposition table. The main worker
+// (id 0) owns the game tree, manages time, and reports search progress; helper
+// workers simply fill the cache with their findings.
+type Worker struct {
+	id          int 		// Worker id, zero for the main worker.
+	game        *Game 		// The game the worker is searching.
+	nodes       int64 		// Number of regular nodes searched (atomic).
+	qnodes      int64 		// Number of quiescence nodes searched (atomic).
+	deepening   bool 		// True when searching first root move.
+	improving   bool 		// True when root search score is not falling.
+	volatility  float32 		// Root search stability count.
+	node        int 		// Current position tree node.
+	rootNode    int 		// Tree node the search has started from.
+	history     History 		// Good moves history.
+	killers     Killers 		// Killer moves.
+	rootpv      RootPv 		// Principal variation for root moves.
+	pv          Pv 			// Principal variations for each ply.
+	variations  []Variation 		// Best root moves and their variations (MultiPV).
+	skip        []Move 		// Root moves to skip when searching next variation.
+	eval        Evaluation 		// Evaluation scratch pad.
+	pawnCache   PawnCache 		// Cache of pawn structures.
+	pawnToken   int 		// Engine's pawn cache token the cached structures match.
+	network     *Network 		// Evaluation network, if any.
+	accumulators []Accumulator 	// Network accumulators for each tree node.
+	tree        [1024]Position 	// Positions from the start of the game.
+
+	// Pre-allocated move generator array (one entry per ply) to avoid garbage
+	// collection overhead. Last entry serves for utility move generation, ex.
+	// when converting string notations or determining a stalemate.
+	moveList    [MaxPly+1]MoveGen
+}
+
+func NewWorker(game *Game, id int) *Worker {
+	w := &Worker{id: id, game: game}
+
+	w.rootpv = make([]Move, 0, MaxPly)
+	for ply := 0;  ply < MaxPly; ply++ {
+		w.pv[ply] = make([]Move, 0, MaxPly)
+	}
+
+	return w
+}
+
+// Counts regular node. Node counters are updated atomically since the main
+// worker reports node counts of all the workers while they are searching.
+func (w *Worker) countNode() {
+	atomic.AddInt64(&w.nodes, 1)
+}
+
+// Counts quiescence node.
+func (w *Worker) countQnode() {
+	atomic.AddInt64(&w.qnodes, 1)
+}
+
+// Returns distance between current and root node.
+func (w *Worker) ply() int {
+	return w.node - w.rootNode
+}
+
+// Returns true if it's time to stop the search: either the search has been
+// halted, all the workers have searched maximum number of nodes, or the main
+// worker has run out of time. The main worker checks the clock (and whether
+// the GUI has sent "ponderhit") every 1024 nodes.
+func (w *Worker) halted() bool {
+	engine := w.game.engine
+	if engine.clock.stopped() {
+		return true
+	}
+
+	if engine.fixedNodes() {
+		if nodes, qnodes := w.game.nodeCount(); nodes + qnodes >= engine.options.maxNodes {
+			engine.clock.stop()
+		}
+	}
+	if w.isMain() && (w.nodes + w.qnodes) & 1023 == 0 && !engine.pondering() && engine.timeIsUp(w) {
+		engine.clock.stop()
+	}
+
+	return engine.clock.stopped()
+}
+
+// Returns true if the root move should be skipped when searching secondary
+// variations in MultiPV mode.
+func (w *Worker) skipped(move Move) bool {
+	for _, skip := range w.skip {
+		if move == skip {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if this is the main worker.
+func (w *Worker) isMain() bool {
+	return w.id == 0
+}
+
+// Returns the position at current tree node.
+func (w *Worker) position() *Position {
+	return &w.tree[w.node]
+}
+
+// Resets principal variation as well as killer moves, move history, and node
+// counters. Root node gets set to the current tree node to match the position.
+func (w *Worker) getReady() *Worker {
+	w.rootpv = w.rootpv[:0]
+	w.variations = w.variations[:0]
+	w.skip = w.skip[:0]
+	for ply := 0;  ply < MaxPly; ply++ {
+		w.pv[ply] = w.pv[ply][:0]
+	}
+
+	w.killers = Killers{}
+	w.history = History{}
+	w.nodes, w.qnodes = 0, 0
+	w.deepening = false
+	w.improving = true
+	w.volatility = 0.0
+
+	w.rootNode = w.node
+	return w
+}
+
+// Copies the main worker's position tree so that the helper worker starts from
+// the same root position while still being able to detect repetitions.
+func (w *Worker) follow(main *Worker) *Position {
+	copy(w.tree[:main.node + 1], main.tree[:main.node + 1])
+	for node := 0; node <= main.node; node++ {
+		w.tree[node].worker = w
+	}
+	w.node = main.node
+	if main.network != nil {
+		w.useNetwork(main.network)
+	}
+
+	return w.getReady().position()
+}
+
+// Helper worker's iterative deepening loop. To diversify the search half of
+// the helpers skip odd depths. The loop keeps going until the main worker is
+// done and halts the search.
+func (w *Worker) help(p *Position) {
+	NewRootGen(p, 1).generateRootMoves(w.game.rootMoves...)
+	for depth := 1 + w.id & 1; depth <= MaxDepth && !w.halted(); depth++ {
+		p.search(-Checkmate, Checkmate, depth)
+		if len(w.pv[0]) > 0 {
+			w.rootpv = append(w.rootpv[:0], w.pv[0]...)
+		}
+	}
+}
+
+func (w *Worker) saveBest(ply int, move Move) *Worker {
+	w.pv[ply] = append(w.pv[ply][0:ply], move)
+
+	next := ply + 1
+	if length := len(w.pv[next]); length > 0 {
+		w.pv[ply] = append(w.pv[ply], w.pv[next][next : length]...)
+	}
+
+	return w
+}
+
+func (w *Worker) saveGood(depth int, move Move) *Worker {
+	if ply := w.ply(); move.isQuiet() && move != w.killers[ply][0] {
+		w.killers[ply][1] = w.killers[ply][0]
+		w.killers[ply][0] = move
+		w.history[move.piece()][move.to()] += depth * depth
+	}
+
+	return w
+}
+
+// Checks whether the move is among good moves captured so far and returns its
+// history value.
+func (w *Worker) good(move Move) int {
+	return w.history[move.piece()][move.to()]
+}
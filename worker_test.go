@@ -0,0 +1,40 @@
+// Copyright (c) 2013-2014 by Michael Dvorkin. All Rights Reserved.
+// Use of this source code is governed by a MIT-style license that can
+// be found in the LICENSE file.
+
+package donna
+
+import(`github.com/michaeldv/donna/expect`; `testing`)
+
+// Helper worker starts off the main worker's position.
+func TestWorker000(t *testing.T) {
+	p := NewGame().start()
+	p = p.makeMove(NewMove(p, E2, E4))
+
+	helper := NewWorker(p.worker.game, 1)
+	position := helper.follow(p.worker)
+	expect.Eq(t, position.worker, helper)
+	expect.Eq(t, position.fen(), p.fen())
+	expect.Eq(t, helper.ply(), 0)
+}
+
+// Helper worker keeps game history to detect repetitions.
+func TestWorker010(t *testing.T) {
+	p := NewGame(`Ka1,g3,h2`, `M,Kh5,h3,g4,g5,g6,h7`).start()
+	p = p.makeMove(NewMove(p, H5, H6))
+	p = p.makeMove(NewMove(p, A1, A2))
+	p = p.makeMove(NewMove(p, H6, H5))
+
+	position := NewWorker(p.worker.game, 1).follow(p.worker)
+	position = position.makeMove(NewMove(position, A2, A1))
+	expect.True(t, position.repetition())
+}
+
+// Main worker's best move with helper workers searching in parallel.
+func TestWorker020(t *testing.T) {
+	engine := NewEngine(`threads`, 4, `depth`, 3, `uci`, true)
+	game := engine.NewGame(`Kf8,Rh1,g6`, `Kh8,Bg8,g7,h7`)
+	game.start()
+	expect.Eq(t, game.Think(), `Rh1-h6`)
+	expect.Eq(t, len(game.workers), 4)
+}